@@ -0,0 +1,40 @@
+package stow
+
+import "io"
+
+// Appender represents a Container that can append contents to its
+// Items, such as for log-style workloads, rather than replacing all
+// of the contents with Put.
+type Appender interface {
+	// Append appends the contents of r to the Item with the specified
+	// ID, creating it if it does not exist, and gets the Item.
+	Append(id string, r io.Reader) (Item, error)
+}
+
+// Truncater represents a Container that can truncate its Items.
+type Truncater interface {
+	// Truncate changes the size of the contents of the Item with the
+	// specified ID. Contents past the size are discarded, and contents
+	// that are extended read as zero bytes.
+	Truncate(id string, size int64) error
+}
+
+// Append appends the contents of r to the Item with the specified ID
+// in the Container. Containers that are not Appenders fail with an
+// error for which IsNotSupported is true.
+func Append(c Container, id string, r io.Reader) (Item, error) {
+	if a, ok := c.(Appender); ok {
+		return a.Append(id, r)
+	}
+	return nil, NotSupported("append")
+}
+
+// Truncate changes the size of the contents of the Item with the
+// specified ID in the Container. Containers that are not Truncaters
+// fail with an error for which IsNotSupported is true.
+func Truncate(c Container, id string, size int64) error {
+	if t, ok := c.(Truncater); ok {
+		return t.Truncate(id, size)
+	}
+	return NotSupported("truncate")
+}
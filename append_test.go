@@ -0,0 +1,60 @@
+package stow_test
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// appendingContainer is an Appender and a Truncater holding its
+// Items in memory.
+type appendingContainer struct {
+	*testContainer
+}
+
+func (c *appendingContainer) Append(id string, r io.Reader) (stow.Item, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	item, ok := c.items[id]
+	if !ok {
+		item = &testItem{name: id, lastMod: time.Now()}
+		c.items[id] = item
+	}
+	item.data = append(item.data, b...)
+	return item, nil
+}
+
+func (c *appendingContainer) Truncate(id string, size int64) error {
+	item, ok := c.items[id]
+	if !ok {
+		return stow.ErrNotFound
+	}
+	data := make([]byte, size)
+	copy(data, item.data)
+	item.data = data
+	return nil
+}
+
+func TestAppend(t *testing.T) {
+	is := is.New(t)
+	c := &appendingContainer{testContainer: newTestContainer("c")}
+	_, err := stow.Append(c, "log", strings.NewReader("one\n"))
+	is.NoErr(err)
+	item, err := stow.Append(c, "log", strings.NewReader("two\n"))
+	is.NoErr(err)
+	is.Equal(string(item.(*testItem).data), "one\ntwo\n")
+
+	is.NoErr(stow.Truncate(c, "log", 3))
+	is.Equal(string(c.items["log"].data), "one")
+
+	_, err = stow.Append(newTestContainer("plain"), "log", strings.NewReader("one\n"))
+	is.True(stow.IsNotSupported(err))
+	is.True(stow.IsNotSupported(stow.Truncate(newTestContainer("plain"), "log", 0)))
+}
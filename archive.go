@@ -0,0 +1,136 @@
+package stow
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// archivePageSize is the number of Items TarContainer and
+// ZipContainer list per request.
+const archivePageSize = 100
+
+// defaultArchiveMode is the mode of the files in archives made by
+// TarContainer and ZipContainer, unless the metadata of their Items
+// holds one.
+const defaultArchiveMode os.FileMode = 0644
+
+// TarContainer writes a tar archive of the Items in the Container whose
+// names start with the prefix to w, streaming the contents of each Item
+// into it in turn. The files in the archive are named like the Items,
+// and have their sizes and modification times. Their permission bits
+// are taken from the octal "mode" metadata of the Items, as the local
+// backend has, or else are 0644.
+// Items that are removed while the archive is being written are skipped
+// with a logged warning.
+// The end of the archive is written, but w is not closed.
+func TarContainer(c Container, prefix string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	err := Walk(c, prefix, archivePageSize, func(item Item, err error) error {
+		if err != nil {
+			return err
+		}
+		return archiveItem(item, func(size int64, mode os.FileMode, modTime time.Time) (io.Writer, error) {
+			hdr := &tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     item.Name(),
+				Size:     size,
+				Mode:     int64(mode),
+				ModTime:  modTime,
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return nil, err
+			}
+			return tw, nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// ZipContainer writes a zip archive of the Items in the Container whose
+// names start with the prefix to w, like TarContainer. The contents are
+// compressed with the Deflate method.
+// The end of the archive is written, but w is not closed.
+func ZipContainer(c Container, prefix string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	err := Walk(c, prefix, archivePageSize, func(item Item, err error) error {
+		if err != nil {
+			return err
+		}
+		return archiveItem(item, func(size int64, mode os.FileMode, modTime time.Time) (io.Writer, error) {
+			hdr := &zip.FileHeader{
+				Name:     item.Name(),
+				Method:   zip.Deflate,
+				Modified: modTime,
+			}
+			hdr.SetMode(mode)
+			return zw.CreateHeader(hdr)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// archiveItem streams the contents of the Item to the writer got from
+// create for the file of the Item in an archive. Items that no longer
+// exist are skipped with a logged warning.
+func archiveItem(item Item, create func(size int64, mode os.FileMode, modTime time.Time) (io.Writer, error)) error {
+	rc, err := item.Open()
+	if errors.Is(err, ErrNotFound) {
+		log.Printf("stow: skipping %s in archive: %v", item.Name(), err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	size, err := item.Size()
+	if err != nil {
+		return err
+	}
+	modTime, err := item.LastMod()
+	if err != nil {
+		return err
+	}
+	md, err := item.Metadata()
+	if err != nil {
+		return err
+	}
+	w, err := create(size, archiveMode(md), modTime)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(w, rc)
+	if err != nil {
+		return fmt.Errorf("archiving %s: %w", item.Name(), err)
+	}
+	if n != size {
+		return fmt.Errorf("archiving %s: read %d of %d bytes", item.Name(), n, size)
+	}
+	return nil
+}
+
+// archiveMode gets the permission bits of the file of an Item in an
+// archive from the octal "mode" metadata of the Item, if it has any.
+func archiveMode(md map[string]interface{}) os.FileMode {
+	s, ok := md["mode"].(string)
+	if !ok {
+		return defaultArchiveMode
+	}
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return defaultArchiveMode
+	}
+	return os.FileMode(m) & os.ModePerm
+}
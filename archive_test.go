@@ -0,0 +1,103 @@
+package stow_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// vanishingItem is a testItem that was removed after being listed.
+type vanishingItem struct {
+	*testItem
+}
+
+func (i *vanishingItem) Open() (io.ReadCloser, error) {
+	return nil, stow.ErrNotFound
+}
+
+// vanishingContainer is a testContainer that lists an Item
+// that no longer exists.
+type vanishingContainer struct {
+	*testContainer
+}
+
+func (c *vanishingContainer) Items(prefix, cursor string, count int) ([]stow.Item, string, error) {
+	items, cursor, err := c.testContainer.Items(prefix, cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+	for i, item := range items {
+		if item.Name() == "a/gone" {
+			items[i] = &vanishingItem{item.(*testItem)}
+		}
+	}
+	return items, cursor, nil
+}
+
+func archiveContainer(t *testing.T) stow.Container {
+	is := is.New(t)
+	c := &vanishingContainer{newTestContainer("c")}
+	_, err := c.Put("a/private", strings.NewReader("private"), 7, map[string]interface{}{"mode": "600"})
+	is.NoErr(err)
+	_, err = c.Put("a/public", strings.NewReader("public"), 6, nil)
+	is.NoErr(err)
+	_, err = c.Put("a/gone", strings.NewReader("gone"), 4, nil)
+	is.NoErr(err)
+	_, err = c.Put("b/other", strings.NewReader("other"), 5, nil)
+	is.NoErr(err)
+	return c
+}
+
+func TestTarContainer(t *testing.T) {
+	is := is.New(t)
+	var buf bytes.Buffer
+	is.NoErr(stow.TarContainer(archiveContainer(t), "a/", &buf))
+
+	tr := tar.NewReader(&buf)
+	files := map[string]string{}
+	modes := map[string]os.FileMode{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		is.NoErr(err)
+		b, err := ioutil.ReadAll(tr)
+		is.NoErr(err)
+		files[hdr.Name] = string(b)
+		modes[hdr.Name] = os.FileMode(hdr.Mode)
+	}
+	is.Equal(files, map[string]string{"a/private": "private", "a/public": "public"})
+	is.Equal(modes["a/private"], os.FileMode(0600))
+	is.Equal(modes["a/public"], os.FileMode(0644))
+}
+
+func TestZipContainer(t *testing.T) {
+	is := is.New(t)
+	var buf bytes.Buffer
+	is.NoErr(stow.ZipContainer(archiveContainer(t), "a/", &buf))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	is.NoErr(err)
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		is.NoErr(err)
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		is.NoErr(err)
+		files[f.Name] = string(b)
+		if f.Name == "a/private" {
+			is.Equal(f.Mode(), os.FileMode(0600))
+		}
+	}
+	is.Equal(files, map[string]string{"a/private": "private", "a/public": "public"})
+}
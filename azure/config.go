@@ -58,6 +58,7 @@ func init() {
 		return u.Scheme == Kind
 	}
 	stow.Register(Kind, makefn, kindfn, validatefn)
+	stow.RegisterConfigKeys(Kind, []string{ConfigAccount, ConfigKey}, nil)
 }
 
 func newBlobStorageClient(cfg stow.Config) (*az.BlobStorageClient, error) {
@@ -37,7 +37,7 @@ func (c *container) Item(id string) (stow.Item, error) {
 	err := blob.GetProperties(nil)
 	if err != nil {
 		if strings.Contains(err.Error(), "404") {
-			return nil, stow.ErrNotFound
+			return nil, stow.NotFound(err)
 		}
 		return nil, err
 	}
@@ -83,6 +83,9 @@ func (c *container) Items(prefix, cursor string, count int) ([]stow.Item, string
 }
 
 func (c *container) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+	if size < 0 {
+		return nil, stow.NotSupported("put with unknown size")
+	}
 	mdParsed, err := prepMetadata(metadata)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to create or update Item, preparing metadata")
@@ -148,7 +151,11 @@ func prepMetadata(md map[string]interface{}) (map[string]string, error) {
 }
 
 func (c *container) RemoveItem(id string) error {
-	return c.client.GetContainerReference(c.id).GetBlobReference(id).Delete(nil)
+	err := c.client.GetContainerReference(c.id).GetBlobReference(id).Delete(nil)
+	if err != nil && strings.Contains(err.Error(), "404") {
+		return stow.NotFound(err)
+	}
+	return err
 }
 
 // Remove quotation marks from beginning and end. This includes quotations that
@@ -23,8 +23,10 @@ type item struct {
 }
 
 var (
-	_ stow.Item       = (*item)(nil)
-	_ stow.ItemRanger = (*item)(nil)
+	_ stow.Item           = (*item)(nil)
+	_ stow.ItemRanger     = (*item)(nil)
+	_ stow.MetadataSetter = (*item)(nil)
+	_ stow.ContentTyper   = (*item)(nil)
 )
 
 func (i *item) ID() string {
@@ -50,6 +52,10 @@ func (i *item) Open() (io.ReadCloser, error) {
 	return i.client.GetContainerReference(i.container.id).GetBlobReference(i.id).Get(nil)
 }
 
+func (i *item) ContentType() (string, error) {
+	return i.properties.ContentType, nil
+}
+
 func (i *item) ETag() (string, error) {
 	return i.properties.Etag, nil
 }
@@ -67,6 +73,23 @@ func (i *item) Metadata() (map[string]interface{}, error) {
 	return i.metadata, nil
 }
 
+// SetMetadata replaces the metadata of the blob.
+func (i *item) SetMetadata(metadata map[string]interface{}) error {
+	if metadata == nil {
+		return nil
+	}
+	mdParsed, err := prepMetadata(metadata)
+	if err != nil {
+		return errors.Wrap(err, "unable to set metadata, preparing metadata")
+	}
+	err = i.container.SetItemMetadata(i.id, mdParsed)
+	if err != nil {
+		return errors.Wrap(err, "unable to set metadata")
+	}
+	i.metadata, err = parseMetadata(mdParsed)
+	return err
+}
+
 func (i *item) ensureInfo() error {
 	if i.metadata == nil {
 		i.infoOnce.Do(func() {
@@ -66,7 +66,7 @@ func (l *location) Container(id string) (stow.Container, error) {
 	for {
 		containers, crsr, err := l.Containers(id[:3], cursor, 100)
 		if err != nil {
-			return nil, stow.ErrNotFound
+			return nil, stow.NotFound(err)
 		}
 		for _, i := range containers {
 			if i.ID() == id {
@@ -49,6 +49,7 @@ func init() {
 		return u.Scheme == Kind
 	}
 	stow.Register(Kind, makefn, kindfn, validatefn)
+	stow.RegisterConfigKeys(Kind, []string{ConfigApplicationKey}, []string{ConfigAccountID, ConfigKeyID})
 }
 
 func newB2Client(cfg stow.Config) (*backblaze.B2, error) {
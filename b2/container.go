@@ -142,7 +142,7 @@ func (c *container) getItem(id string) (*item, error) {
 	if err != nil {
 		lowered := strings.ToLower(err.Error())
 		if (strings.Contains(lowered, "not") && strings.Contains(lowered, "found")) || (strings.Contains(lowered, "bad") && strings.Contains(lowered, "fileid")) {
-			return nil, stow.ErrNotFound
+			return nil, stow.NotFound(err)
 		}
 		return nil, err
 	}
@@ -55,7 +55,7 @@ func (l *location) Containers(prefix string, cursor string, count int) ([]stow.C
 func (l *location) Container(id string) (stow.Container, error) {
 	bucket, err := l.client.Bucket(id)
 	if err != nil || bucket == nil {
-		return nil, stow.ErrNotFound
+		return nil, stow.NotFound(err)
 	}
 
 	return &container{
@@ -83,7 +83,7 @@ func (l *location) ItemByURL(u *url.URL) (stow.Item, error) {
 	}
 	response, err := c.(*container).bucket.ListFileNames(filename, 1)
 	if err != nil {
-		return nil, stow.ErrNotFound
+		return nil, stow.NotFound(err)
 	}
 
 	if len(response.Files) != 1 {
@@ -1,6 +1,7 @@
 package b2
 
 import (
+	"errors"
 	"math/rand"
 	"os"
 	"strings"
@@ -98,7 +99,7 @@ func TestStow(t *testing.T) {
 
 		// verify item is gone
 		_, err = container.Item(i.ID())
-		is.Equal(err, stow.ErrNotFound)
+		is.True(errors.Is(err, stow.ErrNotFound))
 	})
 }
 
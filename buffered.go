@@ -0,0 +1,32 @@
+package stow
+
+import (
+	"bufio"
+	"io"
+)
+
+// BufferedOpen opens the Item for reading like Open, reading the
+// contents through a buffer of bufSize bytes, so that many small reads
+// make few reads of the underlying reader. Closing the returned
+// io.ReadCloser closes the underlying reader.
+func BufferedOpen(item Item, bufSize int) (io.ReadCloser, error) {
+	rc, err := item.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &bufferedReadCloser{
+		Reader: bufio.NewReaderSize(rc, bufSize),
+		c:      rc,
+	}, nil
+}
+
+// bufferedReadCloser is a bufio.Reader that closes the
+// underlying reader.
+type bufferedReadCloser struct {
+	*bufio.Reader
+	c io.Closer
+}
+
+func (r *bufferedReadCloser) Close() error {
+	return r.c.Close()
+}
@@ -0,0 +1,60 @@
+package stow_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// countingReadCloser counts the reads of the underlying
+// reader, and whether it was closed.
+type countingReadCloser struct {
+	io.Reader
+	reads  int
+	closed bool
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	r.reads++
+	return r.Reader.Read(p)
+}
+
+func (r *countingReadCloser) Close() error {
+	r.closed = true
+	return nil
+}
+
+// openedItem is a testItem that opens to a countingReadCloser.
+type openedItem struct {
+	*testItem
+	rc *countingReadCloser
+}
+
+func (i *openedItem) Open() (io.ReadCloser, error) {
+	i.rc = &countingReadCloser{Reader: bytes.NewReader(i.data)}
+	return i.rc, nil
+}
+
+func TestBufferedOpen(t *testing.T) {
+	is := is.New(t)
+	item := &openedItem{testItem: &testItem{name: "item", data: bytes.Repeat([]byte("x"), 1000)}}
+	rc, err := stow.BufferedOpen(item, 4096)
+	is.NoErr(err)
+	var n int
+	p := make([]byte, 10)
+	for {
+		m, err := rc.Read(p)
+		n += m
+		if err == io.EOF {
+			break
+		}
+		is.NoErr(err)
+	}
+	is.Equal(n, 1000)
+	is.True(item.rc.reads <= 2)
+	is.NoErr(rc.Close())
+	is.True(item.rc.closed)
+}
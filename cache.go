@@ -0,0 +1,347 @@
+package stow
+
+import (
+	"container/list"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// cacheMaxEntries is the maximum number of Items a CachingLocation
+// holds metadata for. The least recently used Items are evicted
+// first.
+const cacheMaxEntries = 1024
+
+// CachedLocation wraps a Location so that the size and metadata of
+// the Items got from it, and from the Containers got from it, are
+// cached for the ttl duration.
+// Only the size and metadata are cached, never the contents of the
+// Items. The cache is keyed on the Container ID and the Item ID, and
+// entries are invalidated when an Item is put or removed through the
+// wrapped Containers. Items got with ItemByURL are keyed on their URL.
+// Once cached values expire, they are got again from the Item asking
+// for them only if it was got after they were cached or invalidated,
+// and otherwise from the Item got again, as on some backends, such as
+// local, Items keep the size and metadata they first got.
+// The metadata is copied, so callers may change the maps they get.
+// The wrapped Containers and Items only implement the methods of the
+// Container and Item interfaces.
+// The returned CachingLocation is safe for concurrent use.
+func CachedLocation(loc Location, ttl time.Duration) *CachingLocation {
+	return &CachingLocation{
+		Location: loc,
+		ttl:      ttl,
+		entries:  make(map[cacheKey]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// CachingLocation is a Location that caches the size and metadata
+// of Items. It is created with CachedLocation.
+type CachingLocation struct {
+	Location
+	ttl time.Duration
+
+	lock    sync.Mutex // protects entries and lru
+	entries map[cacheKey]*list.Element
+	lru     *list.List // of *cacheEntry, most recently used first
+}
+
+// cacheKey identifies an Item in the cache. Item IDs are only unique
+// within their Container on some backends, such as S3.
+type cacheKey struct {
+	container string
+	id        string
+}
+
+// cacheEntry holds the cached values for a single Item.
+type cacheEntry struct {
+	key cacheKey
+	// gen is incremented when the entry is invalidated, so that the
+	// result of a lookup started before then is not stored.
+	gen         int
+	size        int64
+	sizeAt      time.Time // when the size was cached or invalidated
+	sizeExpires time.Time
+	metadata    map[string]interface{}
+	metaAt      time.Time // when the metadata was cached or invalidated
+	metaExpires time.Time
+}
+
+// invalidate drops the cached values of the entry.
+func (e *cacheEntry) invalidate() {
+	now := time.Now()
+	e.gen++
+	e.size, e.sizeAt, e.sizeExpires = 0, now, time.Time{}
+	e.metadata, e.metaAt, e.metaExpires = nil, now, time.Time{}
+}
+
+// Invalidate removes any cached values for the Items with the
+// specified ID, in all of the Containers.
+func (l *CachingLocation) Invalidate(itemID string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for key, e := range l.entries {
+		if key.id == itemID {
+			e.Value.(*cacheEntry).invalidate()
+		}
+	}
+}
+
+// invalidate removes any cached values for the Item.
+func (l *CachingLocation) invalidate(key cacheKey) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.entry(key).invalidate()
+}
+
+// entry gets the cache entry for the Item, creating it if there
+// isn't one and marking it as recently used.
+// l.lock must be held.
+func (l *CachingLocation) entry(key cacheKey) *cacheEntry {
+	if e, ok := l.entries[key]; ok {
+		l.lru.MoveToFront(e)
+		return e.Value.(*cacheEntry)
+	}
+	entry := &cacheEntry{key: key}
+	l.entries[key] = l.lru.PushFront(entry)
+	for l.lru.Len() > cacheMaxEntries {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.entries, oldest.Value.(*cacheEntry).key)
+	}
+	return entry
+}
+
+// cached gets whether the entry is still in the cache and has not
+// been invalidated since gen, so that the result of a lookup started
+// before the entry was invalidated or evicted is not stored.
+// l.lock must be held.
+func (l *CachingLocation) cached(entry *cacheEntry, gen int) bool {
+	e, ok := l.entries[entry.key]
+	return ok && e.Value.(*cacheEntry) == entry && entry.gen == gen
+}
+
+func (l *CachingLocation) size(i *cachedItem) (int64, error) {
+	l.lock.Lock()
+	entry := l.entry(i.key)
+	if time.Now().Before(entry.sizeExpires) {
+		size := entry.size
+		l.lock.Unlock()
+		return size, nil
+	}
+	gen := entry.gen
+	fresh := i.got.After(entry.sizeAt)
+	l.lock.Unlock()
+	start := time.Now()
+	item, err := i.lookup(fresh)
+	if err != nil {
+		return 0, err
+	}
+	size, err := item.Size()
+	if err != nil {
+		return 0, err
+	}
+	l.lock.Lock()
+	if l.cached(entry, gen) {
+		entry.size = size
+		entry.sizeAt = start
+		entry.sizeExpires = time.Now().Add(l.ttl)
+	}
+	l.lock.Unlock()
+	return size, nil
+}
+
+func (l *CachingLocation) metadata(i *cachedItem) (map[string]interface{}, error) {
+	l.lock.Lock()
+	entry := l.entry(i.key)
+	if time.Now().Before(entry.metaExpires) {
+		md := copyMetadata(entry.metadata)
+		l.lock.Unlock()
+		return md, nil
+	}
+	gen := entry.gen
+	fresh := i.got.After(entry.metaAt)
+	l.lock.Unlock()
+	start := time.Now()
+	item, err := i.lookup(fresh)
+	if err != nil {
+		return nil, err
+	}
+	md, err := item.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	l.lock.Lock()
+	if l.cached(entry, gen) {
+		entry.metadata = copyMetadata(md)
+		entry.metaAt = start
+		entry.metaExpires = time.Now().Add(l.ttl)
+	}
+	l.lock.Unlock()
+	return md, nil
+}
+
+// copyMetadata makes a deep copy of the metadata, copying the maps and
+// slices in it.
+func copyMetadata(md map[string]interface{}) map[string]interface{} {
+	if md == nil {
+		return nil
+	}
+	cp := make(map[string]interface{}, len(md))
+	for k, v := range md {
+		cp[k] = copyMetadataValue(v)
+	}
+	return cp
+}
+
+func copyMetadataValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return copyMetadata(v)
+	case []interface{}:
+		cp := make([]interface{}, len(v))
+		for i, e := range v {
+			cp[i] = copyMetadataValue(e)
+		}
+		return cp
+	case map[string]string:
+		cp := make(map[string]string, len(v))
+		for k, e := range v {
+			cp[k] = e
+		}
+		return cp
+	case []string:
+		return append([]string(nil), v...)
+	}
+	return v
+}
+
+func (l *CachingLocation) CreateContainer(name string) (Container, error) {
+	c, err := l.Location.CreateContainer(name)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedContainer{Container: c, cache: l}, nil
+}
+
+func (l *CachingLocation) Containers(prefix string, cursor string, count int) ([]Container, string, error) {
+	cs, cursor, err := l.Location.Containers(prefix, cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+	for i, c := range cs {
+		cs[i] = &cachedContainer{Container: c, cache: l}
+	}
+	return cs, cursor, nil
+}
+
+func (l *CachingLocation) Container(id string) (Container, error) {
+	c, err := l.Location.Container(id)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedContainer{Container: c, cache: l}, nil
+}
+
+func (l *CachingLocation) ItemByURL(u *url.URL) (Item, error) {
+	got := time.Now()
+	item, err := l.Location.ItemByURL(u)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedItem{
+		Item:  item,
+		cache: l,
+		key:   cacheKey{id: u.String()},
+		got:   got,
+		again: func() (Item, error) { return l.Location.ItemByURL(u) },
+	}, nil
+}
+
+type cachedContainer struct {
+	Container
+	cache *CachingLocation
+}
+
+func (c *cachedContainer) Item(id string) (Item, error) {
+	got := time.Now()
+	item, err := c.Container.Item(id)
+	if err != nil {
+		return nil, err
+	}
+	return c.item(item, got), nil
+}
+
+// item wraps the Item of the Container, which was got at the time.
+func (c *cachedContainer) item(item Item, got time.Time) *cachedItem {
+	id := item.ID()
+	return &cachedItem{
+		Item:  item,
+		cache: c.cache,
+		key:   c.key(id),
+		got:   got,
+		again: func() (Item, error) { return c.Container.Item(id) },
+	}
+}
+
+// key gets the cache key of the Item of the Container with the ID.
+func (c *cachedContainer) key(id string) cacheKey {
+	return cacheKey{container: c.Container.ID(), id: id}
+}
+
+func (c *cachedContainer) Items(prefix, cursor string, count int) ([]Item, string, error) {
+	got := time.Now()
+	items, cursor, err := c.Container.Items(prefix, cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+	for i, item := range items {
+		items[i] = c.item(item, got)
+	}
+	return items, cursor, nil
+}
+
+func (c *cachedContainer) RemoveItem(id string) error {
+	// invalidated afterwards, so that lookups finishing during the
+	// removal do not store what they got
+	err := c.Container.RemoveItem(id)
+	c.cache.invalidate(c.key(id))
+	return err
+}
+
+func (c *cachedContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error) {
+	item, err := c.Container.Put(name, r, size, metadata)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.invalidate(c.key(item.ID()))
+	// the put Item has the values it was put with
+	return c.item(item, time.Now()), nil
+}
+
+type cachedItem struct {
+	Item
+	cache *CachingLocation
+	key   cacheKey
+	got   time.Time            // when the Item was got
+	again func() (Item, error) // gets the Item again
+}
+
+// lookup gets the Item to get values that are not cached from, which
+// is this Item if it is fresh, and otherwise the Item got again.
+func (i *cachedItem) lookup(fresh bool) (Item, error) {
+	if fresh {
+		return i.Item, nil
+	}
+	return i.again()
+}
+
+func (i *cachedItem) Size() (int64, error) {
+	return i.cache.size(i)
+}
+
+func (i *cachedItem) Metadata() (map[string]interface{}, error) {
+	return i.cache.metadata(i)
+}
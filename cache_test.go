@@ -0,0 +1,262 @@
+package stow_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// countingContainer is a testContainer whose Items count
+// the calls to Size and Metadata.
+type countingContainer struct {
+	*testContainer
+	lock  sync.Mutex // protects calls
+	calls int
+}
+
+func (c *countingContainer) Item(id string) (stow.Item, error) {
+	item, err := c.testContainer.Item(id)
+	if err != nil {
+		return nil, err
+	}
+	return &countingItem{Item: item, container: c}, nil
+}
+
+func (c *countingContainer) count() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.calls
+}
+
+type countingItem struct {
+	stow.Item
+	container *countingContainer
+}
+
+func (i *countingItem) Size() (int64, error) {
+	i.container.lock.Lock()
+	i.container.calls++
+	i.container.lock.Unlock()
+	return i.Item.Size()
+}
+
+func (i *countingItem) Metadata() (map[string]interface{}, error) {
+	i.container.lock.Lock()
+	i.container.calls++
+	i.container.lock.Unlock()
+	return i.Item.Metadata()
+}
+
+func TestCachedLocation(t *testing.T) {
+	is := is.New(t)
+	c := &countingContainer{testContainer: newTestContainer("c")}
+	_, err := c.Put("item", strings.NewReader("item"), 4, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+	loc := stow.CachedLocation(&singleLocation{container: c}, time.Hour)
+	cc, err := loc.Container("c")
+	is.NoErr(err)
+
+	var wg sync.WaitGroup
+	for n := 0; n < 10; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			item, err := cc.Item("item")
+			is.NoErr(err)
+			size, err := item.Size()
+			is.NoErr(err)
+			is.Equal(size, int64(4))
+			md, err := item.Metadata()
+			is.NoErr(err)
+			is.Equal(md["a"], "b")
+		}()
+	}
+	wg.Wait()
+	// concurrent misses may all reach the Container,
+	// but later calls are served from the cache
+	calls := c.count()
+	item, err := cc.Item("item")
+	is.NoErr(err)
+	_, err = item.Size()
+	is.NoErr(err)
+	_, err = item.Metadata()
+	is.NoErr(err)
+	is.Equal(c.count(), calls)
+
+	loc.Invalidate("item")
+	_, err = item.Size()
+	is.NoErr(err)
+	is.Equal(c.count(), calls+1)
+
+	// putting the item again invalidates it
+	_, err = cc.Put("item", strings.NewReader("longer"), 6, nil)
+	is.NoErr(err)
+	item, err = cc.Item("item")
+	is.NoErr(err)
+	size, err := item.Size()
+	is.NoErr(err)
+	is.Equal(size, int64(6))
+}
+
+func TestCachedLocationTTL(t *testing.T) {
+	is := is.New(t)
+	c := &countingContainer{testContainer: newTestContainer("c")}
+	_, err := c.Put("item", strings.NewReader("item"), 4, nil)
+	is.NoErr(err)
+	loc := stow.CachedLocation(&singleLocation{container: c}, 10*time.Millisecond)
+	cc, err := loc.Container("c")
+	is.NoErr(err)
+	item, err := cc.Item("item")
+	is.NoErr(err)
+
+	_, err = item.Size()
+	is.NoErr(err)
+	_, err = item.Size()
+	is.NoErr(err)
+	is.Equal(c.count(), 1)
+	time.Sleep(20 * time.Millisecond)
+	_, err = item.Size()
+	is.NoErr(err)
+	is.Equal(c.count(), 2)
+}
+
+// containersLocation is a Location of testContainers by ID.
+type containersLocation struct {
+	testLocation
+	containers map[string]*testContainer
+}
+
+func (l *containersLocation) Container(id string) (stow.Container, error) {
+	return l.containers[id], nil
+}
+
+func TestCachedLocationContainers(t *testing.T) {
+	is := is.New(t)
+	l := &containersLocation{containers: map[string]*testContainer{}}
+	for _, name := range []string{"a", "b"} {
+		l.containers[name] = newTestContainer(name)
+		_, err := l.containers[name].Put("item", strings.NewReader(name), 1, map[string]interface{}{"container": name})
+		is.NoErr(err)
+	}
+	loc := stow.CachedLocation(l, time.Hour)
+	// the Items have the same ID in both Containers
+	for _, name := range []string{"a", "b", "a", "b"} {
+		c, err := loc.Container(name)
+		is.NoErr(err)
+		item, err := c.Item("item")
+		is.NoErr(err)
+		md, err := item.Metadata()
+		is.NoErr(err)
+		is.Equal(md["container"], name)
+	}
+}
+
+// blockingItem is an Item whose Size waits for a signal.
+type blockingItem struct {
+	stow.Item
+	started chan struct{}
+	release chan struct{}
+}
+
+func (i *blockingItem) Size() (int64, error) {
+	close(i.started)
+	<-i.release
+	return i.Item.Size()
+}
+
+// blockingContainer gets blockingItems until block is nil.
+type blockingContainer struct {
+	*countingContainer
+	block *blockingItem
+}
+
+func (c *blockingContainer) Item(id string) (stow.Item, error) {
+	item, err := c.countingContainer.Item(id)
+	if err != nil || c.block == nil {
+		return item, err
+	}
+	c.block.Item = item
+	return c.block, nil
+}
+
+func TestCachedLocationInvalidateDuringLookup(t *testing.T) {
+	is := is.New(t)
+	block := &blockingItem{started: make(chan struct{}), release: make(chan struct{})}
+	c := &blockingContainer{
+		countingContainer: &countingContainer{testContainer: newTestContainer("c")},
+		block:             block,
+	}
+	_, err := c.Put("item", strings.NewReader("item"), 4, nil)
+	is.NoErr(err)
+	loc := stow.CachedLocation(&singleLocation{container: c}, time.Hour)
+	cc, err := loc.Container("c")
+	is.NoErr(err)
+	stale, err := cc.Item("item")
+	is.NoErr(err)
+	c.block = nil
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		size, err := stale.Size()
+		is.NoErr(err)
+		is.Equal(size, int64(4))
+	}()
+	<-block.started
+	// the lookup finishes after the put invalidated the Item
+	_, err = cc.Put("item", strings.NewReader("longer"), 6, nil)
+	is.NoErr(err)
+	close(block.release)
+	<-done
+
+	item, err := cc.Item("item")
+	is.NoErr(err)
+	size, err := item.Size()
+	is.NoErr(err)
+	is.Equal(size, int64(6))
+}
+
+func TestCachedLocationStaleItem(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	_, err := c.Put("item", strings.NewReader("item"), 4, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+	loc := stow.CachedLocation(&singleLocation{container: c}, 10*time.Millisecond)
+	cc, err := loc.Container("c")
+	is.NoErr(err)
+	// testItems keep the values they were put with
+	stale, err := cc.Item("item")
+	is.NoErr(err)
+	size, err := stale.Size()
+	is.NoErr(err)
+	is.Equal(size, int64(4))
+	md, err := stale.Metadata()
+	is.NoErr(err)
+	is.Equal(md["a"], "b")
+
+	// changing the metadata got does not change the cache
+	md["a"] = "changed"
+	md, err = stale.Metadata()
+	is.NoErr(err)
+	is.Equal(md["a"], "b")
+
+	// the stale Item does not refill the cache once it expires
+	_, err = c.Put("item", strings.NewReader("longer"), 6, map[string]interface{}{"a": "c"})
+	is.NoErr(err)
+	time.Sleep(20 * time.Millisecond)
+	size, err = stale.Size()
+	is.NoErr(err)
+	is.Equal(size, int64(6))
+	md, err = stale.Metadata()
+	is.NoErr(err)
+	is.Equal(md["a"], "c")
+	item, err := cc.Item("item")
+	is.NoErr(err)
+	size, err = item.Size()
+	is.NoErr(err)
+	is.Equal(size, int64(6))
+}
@@ -0,0 +1,54 @@
+package stow
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"strings"
+)
+
+// PutWithChecksum puts an Item like Container.Put, and checks that the
+// MD5 checksum of the contents matches md5hex.
+// If the ETag of the new Item is an MD5 checksum, as it is for many
+// backends, the checksum is compared against it. Otherwise it is compared
+// against the checksum of the bytes read from r.
+// If the checksums do not match the Item is removed and
+// ErrChecksumMismatch is returned.
+func PutWithChecksum(c Container, name string, r io.Reader, size int64, md5hex string) (Item, error) {
+	h := md5.New()
+	item, err := c.Put(name, io.TeeReader(r, h), size, nil)
+	if err != nil {
+		return nil, err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if etag, ok := md5ETag(item); ok {
+		sum = etag
+	}
+	if !strings.EqualFold(sum, md5hex) {
+		if err := c.RemoveItem(item.ID()); err != nil {
+			return nil, err
+		}
+		return nil, ErrChecksumMismatch
+	}
+	return item, nil
+}
+
+// md5ETag gets the ETag of the Item if it is an MD5 checksum,
+// without any surrounding quotes.
+func md5ETag(item Item) (string, bool) {
+	etag, err := item.ETag()
+	if err != nil {
+		return "", false
+	}
+	etag = strings.Trim(etag, `"`)
+	return etag, isMD5(etag)
+}
+
+// isMD5 gets whether s looks like a hex encoded MD5 checksum.
+func isMD5(s string) bool {
+	if len(s) != hex.EncodedLen(md5.Size) {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
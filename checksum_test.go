@@ -0,0 +1,66 @@
+package stow_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestPutWithChecksum(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+
+	// md5 of "contents"
+	const sum = "98bf7d8c15784f0a3d63204441e1e2aa"
+	item, err := stow.PutWithChecksum(c, "good", strings.NewReader("contents"), 8, sum)
+	is.NoErr(err)
+	is.OK(item)
+	_, err = c.Item("good")
+	is.NoErr(err)
+
+	item, err = stow.PutWithChecksum(c, "bad", strings.NewReader("corrupt!"), 8, sum)
+	is.Equal(err, stow.ErrChecksumMismatch)
+	is.Nil(item)
+	_, err = c.Item("bad")
+	is.Equal(err, stow.ErrNotFound)
+}
+
+// md5Container is a testContainer whose items have MD5 ETags.
+type md5Container struct {
+	*testContainer
+	etag string
+}
+
+func (c *md5Container) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+	item, err := c.testContainer.Put(name, r, size, metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &md5Item{testItem: item.(*testItem), etag: c.etag}, nil
+}
+
+type md5Item struct {
+	*testItem
+	etag string
+}
+
+func (i *md5Item) ETag() (string, error) {
+	return i.etag, nil
+}
+
+func TestPutWithChecksumETag(t *testing.T) {
+	is := is.New(t)
+	const sum = "98bf7d8c15784f0a3d63204441e1e2aa"
+
+	// the server reports a different checksum than was sent
+	c := &md5Container{testContainer: newTestContainer("c"), etag: "00000000000000000000000000000000"}
+	_, err := stow.PutWithChecksum(c, "item", strings.NewReader("contents"), 8, sum)
+	is.Equal(err, stow.ErrChecksumMismatch)
+
+	c.etag = strings.ToUpper(sum)
+	_, err = stow.PutWithChecksum(c, "item", strings.NewReader("contents"), 8, sum)
+	is.NoErr(err)
+}
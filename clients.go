@@ -0,0 +1,114 @@
+package stow
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"sync"
+)
+
+// ClientRegistry shares clients, such as network connections, between
+// the Locations dialed with the same configuration. Clients are
+// reference counted, and closed once the last Location using them
+// releases them.
+// The zero value is ready to use, and is safe for concurrent use.
+type ClientRegistry struct {
+	lock    sync.Mutex // protects clients
+	clients map[string]*sharedClient
+}
+
+// sharedClient is a client in a ClientRegistry.
+type sharedClient struct {
+	ready  chan struct{} // closed once the client is dialed
+	client io.Closer
+	err    error
+	refs   int
+}
+
+// Acquire gets the client for the key, calling dial to create it if
+// there is none yet. Concurrent calls for the same key wait for a
+// single dial. Every successful call must be matched by a call to
+// Release.
+func (r *ClientRegistry) Acquire(key string, dial func() (io.Closer, error)) (io.Closer, error) {
+	r.lock.Lock()
+	if r.clients == nil {
+		r.clients = make(map[string]*sharedClient)
+	}
+	c, ok := r.clients[key]
+	if ok {
+		c.refs++
+		r.lock.Unlock()
+		<-c.ready
+		if c.err != nil {
+			return nil, c.err
+		}
+		return c.client, nil
+	}
+	c = &sharedClient{ready: make(chan struct{}), refs: 1}
+	r.clients[key] = c
+	r.lock.Unlock()
+
+	c.client, c.err = dial()
+	if c.err != nil {
+		r.lock.Lock()
+		delete(r.clients, key)
+		r.lock.Unlock()
+	}
+	close(c.ready)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.client, nil
+}
+
+// Release releases the client for the key, closing it if it is
+// no longer used.
+func (r *ClientRegistry) Release(key string) error {
+	r.lock.Lock()
+	c, ok := r.clients[key]
+	if !ok {
+		r.lock.Unlock()
+		return nil
+	}
+	c.refs--
+	if c.refs > 0 {
+		r.lock.Unlock()
+		return nil
+	}
+	delete(r.clients, key)
+	r.lock.Unlock()
+	return c.client.Close()
+}
+
+// Refs gets the number of unreleased acquisitions of the
+// client for the key.
+func (r *ClientRegistry) Refs(key string) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if c, ok := r.clients[key]; ok {
+		return c.refs
+	}
+	return 0
+}
+
+// ConfigKey gets a ClientRegistry key for the values of the specified
+// configuration items. Configs with the same values get the same key.
+// The key is a hash, so secret values can be included safely.
+func ConfigKey(config Config, keys ...string) string {
+	h := sha256.New()
+	var n [8]byte
+	for _, key := range keys {
+		value, ok := config.Config(key)
+		if !ok {
+			// missing values differ from empty ones
+			h.Write([]byte{0})
+			continue
+		}
+		h.Write([]byte{1})
+		binary.BigEndian.PutUint64(n[:], uint64(len(value)))
+		h.Write(n[:])
+		h.Write([]byte(value))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
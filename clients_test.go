@@ -0,0 +1,87 @@
+package stow_test
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// testClient is a client counting how often it was closed.
+type testClient struct {
+	lock   sync.Mutex // protects closed
+	closed int
+}
+
+func (c *testClient) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed++
+	return nil
+}
+
+func TestClientRegistry(t *testing.T) {
+	is := is.New(t)
+	var (
+		r     stow.ClientRegistry
+		lock  sync.Mutex // protects dials
+		dials int
+	)
+	client := &testClient{}
+	dial := func() (io.Closer, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		dials++
+		return client, nil
+	}
+	key := stow.ConfigKey(stow.ConfigMap{"host": "example.com"}, "host", "port")
+
+	var wg sync.WaitGroup
+	for n := 0; n < 10; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := r.Acquire(key, dial)
+			is.NoErr(err)
+			is.Equal(c, client)
+		}()
+	}
+	wg.Wait()
+	is.Equal(dials, 1)
+	is.Equal(r.Refs(key), 10)
+
+	for n := 0; n < 9; n++ {
+		is.NoErr(r.Release(key))
+	}
+	is.Equal(client.closed, 0)
+	is.NoErr(r.Release(key))
+	is.Equal(client.closed, 1)
+	is.Equal(r.Refs(key), 0)
+
+	// a failed dial is not kept
+	errDial := errors.New("dial failed")
+	_, err := r.Acquire(key, func() (io.Closer, error) {
+		return nil, errDial
+	})
+	is.Equal(err, errDial)
+	is.Equal(r.Refs(key), 0)
+	_, err = r.Acquire(key, dial)
+	is.NoErr(err)
+	is.Equal(dials, 2)
+}
+
+func TestConfigKey(t *testing.T) {
+	is := is.New(t)
+	a := stow.ConfigKey(stow.ConfigMap{"host": "a", "port": "22"}, "host", "port")
+	b := stow.ConfigKey(stow.ConfigMap{"port": "22", "host": "a", "other": "x"}, "host", "port")
+	is.Equal(a, b)
+	c := stow.ConfigKey(stow.ConfigMap{"host": "a", "port": ""}, "host", "port")
+	d := stow.ConfigKey(stow.ConfigMap{"host": "a"}, "host", "port")
+	is.NotEqual(a, c)
+	is.NotEqual(c, d)
+	e := stow.ConfigKey(stow.ConfigMap{"host": "a2", "port": "2"}, "host", "port")
+	is.NotEqual(a, e)
+}
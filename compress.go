@@ -0,0 +1,161 @@
+package stow
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"strconv"
+)
+
+const (
+	// MetadataCompression is the metadata key that CompressedContainer
+	// uses to mark the Items it compressed. Its value is "gzip".
+	MetadataCompression = "stow_compression"
+	// MetadataUncompressedSize is the metadata key that
+	// CompressedContainer uses to store the size of the contents of
+	// an Item before compression, when it was known.
+	MetadataUncompressedSize = "stow_uncompressed_size"
+)
+
+// compressionGzip is the MetadataCompression value of gzipped Items.
+const compressionGzip = "gzip"
+
+// CompressedContainer wraps a Container so that the contents of the
+// Items put to it are gzipped with the compression level, such as
+// gzip.DefaultCompression, and the contents of gzipped Items are
+// gunzipped when they are opened.
+// Items are marked as gzipped in their metadata, so Items that were
+// put without compression are opened as they are.
+// The size of an Item is the compressed size reported by the
+// Container, the uncompressed size is kept in the metadata.
+// Compressed contents are put with an unknown size, which the
+// Container must support.
+// The wrapped Items only implement the methods of the Item interface.
+func CompressedContainer(c Container, level int) Container {
+	return &compressedContainer{
+		Container: c,
+		level:     level,
+	}
+}
+
+type compressedContainer struct {
+	Container
+	level int
+}
+
+func (c *compressedContainer) Item(id string) (Item, error) {
+	item, err := c.Container.Item(id)
+	if err != nil {
+		return nil, err
+	}
+	return &compressedItem{Item: item}, nil
+}
+
+func (c *compressedContainer) Items(prefix, cursor string, count int) ([]Item, string, error) {
+	items, cursor, err := c.Container.Items(prefix, cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+	for i, item := range items {
+		items[i] = &compressedItem{Item: item}
+	}
+	return items, cursor, nil
+}
+
+func (c *compressedContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error) {
+	zw, err := gzip.NewWriterLevel(nil, c.level)
+	if err != nil {
+		return nil, err
+	}
+	md := make(map[string]interface{}, len(metadata)+2)
+	for k, v := range metadata {
+		md[k] = v
+	}
+	md[MetadataCompression] = compressionGzip
+	if size >= 0 {
+		md[MetadataUncompressedSize] = strconv.FormatInt(size, 10)
+	}
+	pr, pw := io.Pipe()
+	zw.Reset(pw)
+	go func() {
+		n, err := io.Copy(zw, r)
+		if err == nil && size >= 0 && n != size {
+			err = errors.New("bad size")
+		}
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	item, err := c.Container.Put(name, pr, -1, md)
+	// stop the compression if the Container did not read everything
+	pr.CloseWithError(errors.New("put finished"))
+	if err != nil {
+		return nil, err
+	}
+	return &compressedItem{Item: item}, nil
+}
+
+type compressedItem struct {
+	Item
+}
+
+// Open opens the Item for reading, gunzipping the contents if the
+// Item is marked as gzipped.
+func (i *compressedItem) Open() (io.ReadCloser, error) {
+	md, err := i.Item.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	rc, err := i.Item.Open()
+	if err != nil {
+		return nil, err
+	}
+	if compression(md) != compressionGzip {
+		return rc, nil
+	}
+	zr, err := gzip.NewReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return &gzipReadCloser{Reader: zr, rc: rc}, nil
+}
+
+// compression gets the MetadataCompression value of the metadata.
+func compression(md map[string]interface{}) string {
+	return metadataString(md, MetadataCompression)
+}
+
+// metadataString gets the string value for the key given to Put in
+// the metadata of an Item, or an empty string if there is none.
+// Backends that nest the metadata given to Put inside the metadata
+// of their Items, like the local backend, are handled too.
+func metadataString(md map[string]interface{}, key string) string {
+	if v, ok := md[key].(string); ok {
+		return v
+	}
+	for _, v := range md {
+		if nested, ok := v.(map[string]interface{}); ok {
+			if v, ok := nested[key].(string); ok {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// gzipReadCloser closes both the gzip reader and the
+// underlying reader.
+type gzipReadCloser struct {
+	*gzip.Reader
+	rc io.ReadCloser
+}
+
+func (r *gzipReadCloser) Close() error {
+	err := r.Reader.Close()
+	if cerr := r.rc.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
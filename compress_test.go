@@ -0,0 +1,54 @@
+package stow_test
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestCompressedContainer(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	_, err := c.Put("legacy", strings.NewReader("plain"), 5, nil)
+	is.NoErr(err)
+	cc := stow.CompressedContainer(c, gzip.BestCompression)
+
+	contents := strings.Repeat("compress me ", 100)
+	item, err := cc.Put("compressed", strings.NewReader(contents), int64(len(contents)), map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+	size, err := item.Size()
+	is.NoErr(err)
+	is.True(size < int64(len(contents)))
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md["a"], "b")
+	is.Equal(md[stow.MetadataCompression], "gzip")
+	is.Equal(md[stow.MetadataUncompressedSize], "1200")
+	// the stored contents are gzipped
+	is.Equal(string(c.items["compressed"].data[:2]), "\x1f\x8b")
+
+	items, _, err := cc.Items(stow.NoPrefix, stow.CursorStart, 10)
+	is.NoErr(err)
+	is.Equal(len(items), 2)
+	expected := map[string]string{
+		"compressed": contents,
+		"legacy":     "plain",
+	}
+	for _, item := range items {
+		rc, err := item.Open()
+		is.NoErr(err)
+		b, err := ioutil.ReadAll(rc)
+		is.NoErr(err)
+		is.NoErr(rc.Close())
+		is.Equal(string(b), expected[item.Name()])
+	}
+
+	_, err = cc.Put("short", strings.NewReader("short"), 10, nil)
+	is.Err(err)
+	_, err = stow.CompressedContainer(c, 42).Put("bad", strings.NewReader("bad"), 3, nil)
+	is.Err(err)
+}
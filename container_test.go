@@ -0,0 +1,140 @@
+package stow_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graymeta/stow"
+)
+
+// testContainer is an in-memory stow.Container used to
+// test the helpers in this package.
+type testContainer struct {
+	name  string
+	lock  sync.Mutex // protects items
+	items map[string]*testItem
+}
+
+func newTestContainer(name string) *testContainer {
+	return &testContainer{
+		name:  name,
+		items: map[string]*testItem{},
+	}
+}
+
+func (c *testContainer) ID() string {
+	return c.name
+}
+
+func (c *testContainer) Name() string {
+	return c.name
+}
+
+func (c *testContainer) Item(id string) (stow.Item, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	item, ok := c.items[id]
+	if !ok {
+		return nil, stow.ErrNotFound
+	}
+	return item, nil
+}
+
+func (c *testContainer) Items(prefix, cursor string, count int) ([]stow.Item, string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var names []string
+	for name := range c.items {
+		if strings.HasPrefix(name, prefix) && name >= cursor {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	cursor = ""
+	if len(names) > count {
+		cursor = names[count]
+		names = names[:count]
+	}
+	items := make([]stow.Item, 0, len(names))
+	for _, name := range names {
+		items = append(items, c.items[name])
+	}
+	return items, cursor, nil
+}
+
+func (c *testContainer) RemoveItem(id string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.items[id]; !ok {
+		return stow.ErrNotFound
+	}
+	delete(c.items, id)
+	return nil
+}
+
+func (c *testContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	md := make(map[string]interface{}, len(metadata))
+	for k, v := range metadata {
+		md[k] = v
+	}
+	item := &testItem{
+		name:     name,
+		data:     b,
+		metadata: md,
+		lastMod:  time.Now(),
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.items[name] = item
+	return item, nil
+}
+
+// testItem is an in-memory stow.Item.
+type testItem struct {
+	name     string
+	data     []byte
+	metadata map[string]interface{}
+	lastMod  time.Time
+}
+
+func (i *testItem) ID() string {
+	return i.name
+}
+
+func (i *testItem) Name() string {
+	return i.name
+}
+
+func (i *testItem) URL() *url.URL {
+	return &url.URL{Scheme: testKind, Path: i.name}
+}
+
+func (i *testItem) Size() (int64, error) {
+	return int64(len(i.data)), nil
+}
+
+func (i *testItem) Open() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(i.data)), nil
+}
+
+func (i *testItem) ETag() (string, error) {
+	return i.lastMod.String(), nil
+}
+
+func (i *testItem) LastMod() (time.Time, error) {
+	return i.lastMod, nil
+}
+
+func (i *testItem) Metadata() (map[string]interface{}, error) {
+	return i.metadata, nil
+}
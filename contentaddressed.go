@@ -0,0 +1,69 @@
+package stow
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// contentTempPrefix is the start of the names that PutContentAddressed
+// puts Items with until their digest is known.
+const contentTempPrefix = ".stow-content-"
+
+// contentHashes are the hash algorithms PutContentAddressed supports.
+var contentHashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// PutContentAddressed puts an Item into the Container named by the hex
+// encoded digest of its contents, hashed with the algo hash algorithm,
+// which is one of "md5", "sha1", "sha256" or "sha512", and gets the
+// digest too.
+// The contents are put with a temporary name starting with
+// ".stow-content-" while they are hashed, and then moved to the digest
+// with MoveItem. If there already is an Item named by the digest, the
+// temporary Item is removed and the existing Item is returned instead.
+func PutContentAddressed(c Container, r io.Reader, size int64, algo string) (Item, string, error) {
+	newHash, ok := contentHashes[algo]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown hash algorithm %q", algo)
+	}
+	var suffix [8]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return nil, "", err
+	}
+	tempName := contentTempPrefix + hex.EncodeToString(suffix[:])
+	h := newHash()
+	temp, err := c.Put(tempName, io.TeeReader(r, h), size, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	digest := hex.EncodeToString(h.Sum(nil))
+	existing, err := c.Item(digest)
+	if err == nil {
+		if err := DeleteItem(c, temp); err != nil {
+			return nil, "", err
+		}
+		return existing, digest, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		DeleteItem(c, temp)
+		return nil, "", err
+	}
+	item, err := MoveItem(c, temp.ID(), digest)
+	if err != nil {
+		DeleteItem(c, temp)
+		return nil, "", err
+	}
+	return item, digest, nil
+}
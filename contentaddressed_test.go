@@ -0,0 +1,40 @@
+package stow_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestPutContentAddressed(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	sum := sha256.Sum256([]byte("contents"))
+	want := hex.EncodeToString(sum[:])
+
+	item, digest, err := stow.PutContentAddressed(c, strings.NewReader("contents"), 8, "sha256")
+	is.NoErr(err)
+	is.Equal(digest, want)
+	is.Equal(item.Name(), want)
+	rc, err := item.Open()
+	is.NoErr(err)
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	is.NoErr(err)
+	is.Equal(string(b), "contents")
+
+	// the same contents are not stored twice
+	again, digest, err := stow.PutContentAddressed(c, strings.NewReader("contents"), 8, "sha256")
+	is.NoErr(err)
+	is.Equal(digest, want)
+	is.Equal(again, item)
+	is.Equal(len(c.items), 1)
+
+	_, _, err = stow.PutContentAddressed(c, strings.NewReader("contents"), 8, "crc32")
+	is.Err(err)
+}
@@ -0,0 +1,179 @@
+package stow
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// ServerSideCopier represents a Container that can copy an Item
+// into itself without streaming the contents through the client.
+type ServerSideCopier interface {
+	// ServerSideCopy copies src into this Container with the
+	// specified name.
+	// If src cannot be copied server side, for example because it
+	// belongs to a different kind of Location, an error satisfying
+	// IsNotSupported is returned.
+	ServerSideCopy(dstName string, src Item) (Item, error)
+}
+
+// CopyItem copies the src Item into the dst Container with the
+// specified name, carrying over its user metadata. For Items that are
+// TypedMetadata, such as local Items, that is the metadata given to
+// Put or SetMetadata, without what the backend adds, like file stats,
+// which other backends may not accept. For other Items it is
+// all of their Metadata.
+// If dst is a ServerSideCopier it is used, otherwise the contents
+// are streamed from src into dst.
+// If the ETag of src is an MD5 checksum, the copy is checked against
+// it: the checksum of the streamed contents, and the ETag of the new
+// Item if it is an MD5 checksum too, must match. If they do not, the
+// new Item is removed and ErrChecksumMismatch is returned.
+func CopyItem(dst Container, dstName string, src Item) (Item, error) {
+	srcSum, check := md5ETag(src)
+	if copier, ok := dst.(ServerSideCopier); ok {
+		item, err := copier.ServerSideCopy(dstName, src)
+		if !IsNotSupported(err) {
+			if err != nil || !check {
+				return item, err
+			}
+			return checkCopy(dst, item, srcSum, "")
+		}
+	}
+	size, err := src.Size()
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := userMetadata(src)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := src.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	if !check {
+		return dst.Put(dstName, rc, size, metadata)
+	}
+	h := md5.New()
+	item, err := dst.Put(dstName, io.TeeReader(rc, h), size, metadata)
+	if err != nil {
+		return nil, err
+	}
+	return checkCopy(dst, item, srcSum, hex.EncodeToString(h.Sum(nil)))
+}
+
+// userMetadata gets the metadata of the Item to put with its copy,
+// which for TypedMetadata is just the user metadata.
+func userMetadata(item Item) (map[string]interface{}, error) {
+	tm, ok := item.(TypedMetadata)
+	if !ok {
+		return item.Metadata()
+	}
+	var md map[string]interface{}
+	if err := tm.UnmarshalUserMetadata(&md); err != nil {
+		return nil, err
+	}
+	return md, nil
+}
+
+// checkCopy checks that the checksum of the copied contents, if
+// known, and the ETag of the copied Item, if it is an MD5 checksum,
+// match the checksum of the source. If they do not, the Item is
+// removed and ErrChecksumMismatch is returned.
+func checkCopy(dst Container, item Item, srcSum, copiedSum string) (Item, error) {
+	ok := copiedSum == "" || strings.EqualFold(copiedSum, srcSum)
+	if etag, isSum := md5ETag(item); ok && isSum {
+		ok = strings.EqualFold(etag, srcSum)
+	}
+	if ok {
+		return item, nil
+	}
+	if err := DeleteItem(dst, item); err != nil {
+		return nil, err
+	}
+	return nil, ErrChecksumMismatch
+}
+
+// copyContainerPageSize is the number of Items CopyContainer
+// lists per request.
+const copyContainerPageSize = 100
+
+// CopyContainer copies all of the Items in the src Container into the
+// dst Container with CopyItem, keeping their names and metadata, and
+// gets the number of Items copied.
+// The specified number of workers copy Items at once. Items that
+// already exist in dst with the same ETag as in src are skipped, so an
+// interrupted copy can be run again to resume it.
+// Items that fail to copy do not stop the others from being copied,
+// and a *multierror.Error holding their errors is returned. Failing to
+// list the Items of src stops the copy.
+func CopyContainer(dst, src Container, workers int) (int, error) {
+	return copyContainer(src, workers, func(item Item) (bool, error) {
+		return copyIfChanged(dst, item)
+	})
+}
+
+// copyContainer calls copyItem for each of the Items in the src
+// Container from the specified number of workers, and gets the number
+// of Items it copied. Errors copying Items are collected into a
+// *multierror.Error.
+func copyContainer(src Container, workers int, copyItem func(item Item) (bool, error)) (int, error) {
+	var (
+		lock   sync.Mutex // protects copied and merr
+		copied int
+		merr   *multierror.Error
+	)
+	err := WalkParallel(src, NoPrefix, copyContainerPageSize, workers, func(item Item, err error) error {
+		if err != nil {
+			return err
+		}
+		ok, err := copyItem(item)
+		lock.Lock()
+		defer lock.Unlock()
+		if err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("copying %s: %w", item.Name(), err))
+		} else if ok {
+			copied++
+		}
+		return nil
+	})
+	if err != nil {
+		return copied, err
+	}
+	return copied, merr.ErrorOrNil()
+}
+
+// copyIfChanged copies the src Item into the dst Container with its
+// name, unless an Item with the same name and ETag is already there.
+// It gets whether the Item was copied.
+func copyIfChanged(dst Container, src Item) (bool, error) {
+	existing, err := dst.Item(src.Name())
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+	if err == nil {
+		srcETag, err := src.ETag()
+		if err != nil {
+			return false, err
+		}
+		dstETag, err := existing.ETag()
+		if err != nil {
+			return false, err
+		}
+		if srcETag != "" && srcETag == dstETag {
+			return false, nil
+		}
+	}
+	if _, err := CopyItem(dst, src.Name(), src); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,177 @@
+package stow_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+	"github.com/hashicorp/go-multierror"
+)
+
+func TestCopyItem(t *testing.T) {
+	is := is.New(t)
+	src := newTestContainer("src")
+	dst := newTestContainer("dst")
+
+	item, err := src.Put("a/item", strings.NewReader("contents"), 8, map[string]interface{}{"key": "value"})
+	is.NoErr(err)
+
+	copied, err := stow.CopyItem(dst, "b/copy", item)
+	is.NoErr(err)
+	is.Equal(copied.Name(), "b/copy")
+
+	copied, err = dst.Item("b/copy")
+	is.NoErr(err)
+	rc, err := copied.Open()
+	is.NoErr(err)
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	is.NoErr(err)
+	is.Equal(string(b), "contents")
+	md, err := copied.Metadata()
+	is.NoErr(err)
+	is.Equal(md, map[string]interface{}{"key": "value"})
+}
+
+// copierContainer is a testContainer that copies server side
+// from other testContainers.
+type copierContainer struct {
+	*testContainer
+	copied int
+}
+
+func (c *copierContainer) ServerSideCopy(dstName string, src stow.Item) (stow.Item, error) {
+	item, ok := src.(*testItem)
+	if !ok {
+		return nil, stow.NotSupported("copy")
+	}
+	if item.name == "fail" {
+		return nil, errors.New("copy failed")
+	}
+	c.copied++
+	c.testContainer.items[dstName] = &testItem{name: dstName, data: item.data, metadata: item.metadata}
+	return c.testContainer.items[dstName], nil
+}
+
+func TestCopyItemServerSide(t *testing.T) {
+	is := is.New(t)
+	src := newTestContainer("src")
+	dst := &copierContainer{testContainer: newTestContainer("dst")}
+
+	item, err := src.Put("item", strings.NewReader("contents"), 8, nil)
+	is.NoErr(err)
+	_, err = stow.CopyItem(dst, "copy", item)
+	is.NoErr(err)
+	is.Equal(dst.copied, 1)
+
+	// errors other than not supported are returned
+	item, err = src.Put("fail", strings.NewReader("contents"), 8, nil)
+	is.NoErr(err)
+	_, err = stow.CopyItem(dst, "copy", item)
+	is.Equal(err.Error(), "copy failed")
+
+	// unsupported copies fall back to streaming
+	_, err = stow.CopyItem(dst, "other", &otherItem{item.(*testItem)})
+	is.NoErr(err)
+	is.Equal(dst.copied, 1)
+	_, err = dst.Item("other")
+	is.NoErr(err)
+}
+
+// otherItem is an item the copierContainer cannot copy.
+type otherItem struct {
+	*testItem
+}
+
+func TestCopyItemChecksum(t *testing.T) {
+	is := is.New(t)
+	// md5 of "contents"
+	const sum = "98bf7d8c15784f0a3d63204441e1e2aa"
+	src := &md5Container{testContainer: newTestContainer("src"), etag: `"` + sum + `"`}
+	item, err := src.Put("item", strings.NewReader("contents"), 8, nil)
+	is.NoErr(err)
+
+	dst := newTestContainer("dst")
+	_, err = stow.CopyItem(dst, "copy", item)
+	is.NoErr(err)
+
+	// the contents read do not match the source checksum
+	src.etag = "00000000000000000000000000000000"
+	item, err = src.Put("item", strings.NewReader("contents"), 8, nil)
+	is.NoErr(err)
+	_, err = stow.CopyItem(dst, "bad", item)
+	is.Equal(err, stow.ErrChecksumMismatch)
+	_, err = dst.Item("bad")
+	is.Equal(err, stow.ErrNotFound)
+
+	// the destination reports a different checksum
+	src.etag = sum
+	item, err = src.Put("item", strings.NewReader("contents"), 8, nil)
+	is.NoErr(err)
+	bad := &md5Container{testContainer: newTestContainer("bad"), etag: "11111111111111111111111111111111"}
+	_, err = stow.CopyItem(bad, "copy", item)
+	is.Equal(err, stow.ErrChecksumMismatch)
+	_, err = bad.Item("copy")
+	is.Equal(err, stow.ErrNotFound)
+}
+
+// rejectingContainer is a testContainer that fails to put the Item
+// with the rejected name, and puts Items with the same fixed ETag.
+type rejectingContainer struct {
+	*testContainer
+	rejected string
+}
+
+func (c *rejectingContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+	if name == c.rejected {
+		return nil, errDenied
+	}
+	item, err := c.testContainer.Put(name, r, size, metadata)
+	if err != nil {
+		return nil, err
+	}
+	item.(*testItem).lastMod = time.Time{}
+	return item, nil
+}
+
+func TestCopyContainer(t *testing.T) {
+	is := is.New(t)
+	src := newTestContainer("src")
+	for n := 0; n < 250; n++ {
+		name := fmt.Sprintf("item%03d", n)
+		item, err := src.Put(name, strings.NewReader(name), int64(len(name)), map[string]interface{}{"n": n})
+		is.NoErr(err)
+		item.(*testItem).lastMod = time.Time{}
+	}
+	dst := &rejectingContainer{testContainer: newTestContainer("dst"), rejected: "item100"}
+	// unchanged items are skipped, and changed ones copied again
+	_, err := dst.Put("item000", strings.NewReader("item000"), 7, nil)
+	is.NoErr(err)
+	_, err = dst.Put("item001", strings.NewReader("old"), 3, nil)
+	is.NoErr(err)
+	dst.items["item001"].lastMod = time.Now()
+
+	copied, err := stow.CopyContainer(dst, src, 8)
+	is.Equal(copied, 248)
+	merr, ok := err.(*multierror.Error)
+	is.True(ok)
+	is.Equal(len(merr.Errors), 1)
+	is.True(errors.Is(merr.Errors[0], errDenied))
+	item, err := dst.Item("item001")
+	is.NoErr(err)
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md["n"], 1)
+
+	// resuming only copies what is missing
+	dst.rejected = ""
+	copied, err = stow.CopyContainer(dst, src, 8)
+	is.NoErr(err)
+	is.Equal(copied, 1)
+}
@@ -0,0 +1,36 @@
+package stow
+
+import "io"
+
+// WithDefaultMetadata wraps a Container so that the default metadata
+// is given to every Put, merged under the metadata of the Put, whose
+// values win for keys they both have. The defaults are copied, so
+// later changes to the map do not affect the wrapped Container.
+// The wrapped Container only implements the methods of the Container
+// interface.
+func WithDefaultMetadata(c Container, defaults map[string]interface{}) Container {
+	md := make(map[string]interface{}, len(defaults))
+	for k, v := range defaults {
+		md[k] = v
+	}
+	return &defaultMetadataContainer{
+		Container: c,
+		defaults:  md,
+	}
+}
+
+type defaultMetadataContainer struct {
+	Container
+	defaults map[string]interface{}
+}
+
+func (c *defaultMetadataContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error) {
+	md := make(map[string]interface{}, len(c.defaults)+len(metadata))
+	for k, v := range c.defaults {
+		md[k] = v
+	}
+	for k, v := range metadata {
+		md[k] = v
+	}
+	return c.Container.Put(name, r, size, md)
+}
@@ -0,0 +1,32 @@
+package stow_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestWithDefaultMetadata(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	defaults := map[string]interface{}{"project": "alpha", "retention": "30d"}
+	dc := stow.WithDefaultMetadata(c, defaults)
+	defaults["project"] = "changed"
+
+	item, err := dc.Put("a", strings.NewReader("a"), 1, nil)
+	is.NoErr(err)
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md, map[string]interface{}{"project": "alpha", "retention": "30d"})
+
+	metadata := map[string]interface{}{"retention": "7d", "owner": "ops"}
+	item, err = dc.Put("b", strings.NewReader("b"), 1, metadata)
+	is.NoErr(err)
+	md, err = item.Metadata()
+	is.NoErr(err)
+	is.Equal(md, map[string]interface{}{"project": "alpha", "retention": "7d", "owner": "ops"})
+	// the metadata of the Put is not changed
+	is.Equal(len(metadata), 2)
+}
@@ -0,0 +1,311 @@
+package stow
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+)
+
+const (
+	// MetadataEncryption is the metadata key that EncryptedContainer
+	// uses to mark the Items it encrypted. Its value is "aes-gcm".
+	MetadataEncryption = "stow_encryption"
+	// MetadataPlaintextSize is the metadata key that EncryptedContainer
+	// uses to store the size of the contents of an Item before
+	// encryption, when it was known.
+	MetadataPlaintextSize = "stow_plaintext_size"
+)
+
+// ErrDecrypt is the error of opening an Item of an EncryptedContainer
+// that was not encrypted, or was encrypted with another key.
+var ErrDecrypt = errors.New("not encrypted or bad key")
+
+const (
+	// encryptionAESGCM is the MetadataEncryption value of Items
+	// encrypted with AES-GCM.
+	encryptionAESGCM = "aes-gcm"
+	// encryptSegmentSize is the size of the plaintext segments
+	// that are sealed one at a time.
+	encryptSegmentSize = 64 << 10
+	// encryptPrefixSize is the size of the random nonce prefix
+	// written before the segments.
+	encryptPrefixSize = 7
+)
+
+// PlaintextSizer is an Item of an EncryptedContainer, which gets the
+// size of its contents before encryption.
+type PlaintextSizer interface {
+	// PlaintextSize gets the size of the decrypted contents of the Item.
+	PlaintextSize() (int64, error)
+}
+
+// EncryptedContainer wraps a Container so that the contents of the
+// Items put to it are encrypted with AES-GCM using the key, which must
+// be 16, 24 or 32 bytes long, and are decrypted when they are opened.
+// Contents are sealed in segments of 64KB, after a random nonce prefix,
+// so that they are streamed without being held in memory, and a
+// truncated or reordered stream fails to decrypt.
+// Items are marked as encrypted in their metadata. Opening an Item
+// that was not encrypted, or with the wrong key, fails with ErrDecrypt.
+// The size and ETag of an Item are those of the encrypted contents,
+// the wrapped Items are PlaintextSizers for the decrypted size.
+// Encrypted contents are put with an unknown size, which the Container
+// must support.
+// The wrapped Items only implement the methods of the Item interface
+// and PlaintextSizer.
+func EncryptedContainer(c Container, key []byte) Container {
+	ec := &encryptedContainer{Container: c}
+	block, err := aes.NewCipher(key)
+	if err == nil {
+		ec.aead, err = cipher.NewGCM(block)
+	}
+	ec.err = err
+	return ec
+}
+
+type encryptedContainer struct {
+	Container
+	aead cipher.AEAD
+	err  error // the error of a bad key
+}
+
+func (c *encryptedContainer) Item(id string) (Item, error) {
+	item, err := c.Container.Item(id)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedItem{Item: item, container: c}, nil
+}
+
+func (c *encryptedContainer) Items(prefix, cursor string, count int) ([]Item, string, error) {
+	items, cursor, err := c.Container.Items(prefix, cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+	for i, item := range items {
+		items[i] = &encryptedItem{Item: item, container: c}
+	}
+	return items, cursor, nil
+}
+
+func (c *encryptedContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	prefix := make([]byte, encryptPrefixSize)
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, err
+	}
+	md := make(map[string]interface{}, len(metadata)+2)
+	for k, v := range metadata {
+		md[k] = v
+	}
+	md[MetadataEncryption] = encryptionAESGCM
+	if size >= 0 {
+		md[MetadataPlaintextSize] = strconv.FormatInt(size, 10)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		n, err := c.encrypt(pw, r, prefix)
+		if err == nil && size >= 0 && n != size {
+			err = errors.New("bad size")
+		}
+		pw.CloseWithError(err)
+	}()
+	item, err := c.Container.Put(name, pr, -1, md)
+	// stop the encryption if the Container did not read everything
+	pr.CloseWithError(errors.New("put finished"))
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedItem{Item: item, container: c}, nil
+}
+
+// encrypt writes the nonce prefix and the sealed segments of the
+// contents of r to w, and gets the number of bytes read from r.
+func (c *encryptedContainer) encrypt(w io.Writer, r io.Reader, prefix []byte) (int64, error) {
+	if _, err := w.Write(prefix); err != nil {
+		return 0, err
+	}
+	var (
+		br    = bufio.NewReader(r)
+		buf   = make([]byte, encryptSegmentSize)
+		out   = make([]byte, 0, encryptSegmentSize+c.aead.Overhead())
+		total int64
+	)
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(br, buf)
+		final := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !final {
+			return total, err
+		}
+		if !final {
+			// a full segment is the last one if nothing follows it
+			if _, err := br.Peek(1); err == io.EOF {
+				final = true
+			} else if err != nil {
+				return total, err
+			}
+		}
+		total += int64(n)
+		out = c.aead.Seal(out[:0], segmentNonce(prefix, counter, final), buf[:n], nil)
+		if _, err := w.Write(out); err != nil {
+			return total, err
+		}
+		if final {
+			return total, nil
+		}
+		if counter == ^uint32(0) {
+			return total, errors.New("too many segments")
+		}
+	}
+}
+
+// segmentNonce gets the nonce of a segment, which is the prefix, the
+// counter of the segment and whether it is the last one.
+func segmentNonce(prefix []byte, counter uint32, final bool) []byte {
+	nonce := make([]byte, encryptPrefixSize+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encryptPrefixSize:], counter)
+	if final {
+		nonce[encryptPrefixSize+4] = 1
+	}
+	return nonce
+}
+
+type encryptedItem struct {
+	Item
+	container *encryptedContainer
+}
+
+// Open opens the Item for reading, decrypting the contents. The first
+// segment is decrypted before returning, so that Items that were not
+// encrypted with the key fail with ErrDecrypt.
+func (i *encryptedItem) Open() (io.ReadCloser, error) {
+	if i.container.err != nil {
+		return nil, i.container.err
+	}
+	md, err := i.Item.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	if metadataString(md, MetadataEncryption) != encryptionAESGCM {
+		return nil, ErrDecrypt
+	}
+	rc, err := i.Item.Open()
+	if err != nil {
+		return nil, err
+	}
+	r := &decryptReader{
+		aead: i.container.aead,
+		r:    bufio.NewReader(rc),
+		rc:   rc,
+		buf:  make([]byte, encryptSegmentSize+i.container.aead.Overhead()),
+	}
+	r.prefix = make([]byte, encryptPrefixSize)
+	_, err = io.ReadFull(r.r, r.prefix)
+	if err == nil {
+		err = r.next()
+	}
+	if err != nil {
+		rc.Close()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrDecrypt
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// PlaintextSize gets the size of the decrypted contents, from the
+// metadata if the size was known when the Item was put, and otherwise
+// from the size of the encrypted contents.
+func (i *encryptedItem) PlaintextSize() (int64, error) {
+	if i.container.err != nil {
+		return 0, i.container.err
+	}
+	md, err := i.Item.Metadata()
+	if err != nil {
+		return 0, err
+	}
+	if v := metadataString(md, MetadataPlaintextSize); v != "" {
+		return strconv.ParseInt(v, 10, 64)
+	}
+	size, err := i.Item.Size()
+	if err != nil {
+		return 0, err
+	}
+	overhead := int64(i.container.aead.Overhead())
+	sealed := size - encryptPrefixSize
+	segments := (sealed + encryptSegmentSize + overhead - 1) / (encryptSegmentSize + overhead)
+	if sealed < overhead || segments < 1 {
+		return 0, ErrDecrypt
+	}
+	return sealed - segments*overhead, nil
+}
+
+// decryptReader reads the decrypted segments of an encrypted Item.
+type decryptReader struct {
+	aead    cipher.AEAD
+	r       *bufio.Reader
+	rc      io.ReadCloser
+	prefix  []byte
+	buf     []byte
+	counter uint32
+	plain   []byte // the unread decrypted contents
+	final   bool   // whether the last segment was decrypted
+	err     error
+}
+
+// next decrypts the next segment.
+func (r *decryptReader) next() error {
+	n, err := io.ReadFull(r.r, r.buf)
+	last := err == io.ErrUnexpectedEOF
+	if err != nil && !last {
+		return err
+	}
+	if !last {
+		if _, err := r.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+	plain, err := r.aead.Open(r.buf[:0], segmentNonce(r.prefix, r.counter, last), r.buf[:n], nil)
+	if err != nil {
+		return ErrDecrypt
+	}
+	r.plain, r.final = plain, last
+	r.counter++
+	return nil
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.final {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			if err == io.EOF {
+				// the last segment is missing
+				err = ErrDecrypt
+			}
+			r.err = err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *decryptReader) Close() error {
+	return r.rc.Close()
+}
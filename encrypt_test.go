@@ -0,0 +1,90 @@
+package stow_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestEncryptedContainer(t *testing.T) {
+	is := is.New(t)
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	c := newTestContainer("c")
+	ec := stow.EncryptedContainer(c, key)
+
+	sizes := []int{0, 1, 100, 64<<10 - 1, 64 << 10, 64<<10 + 1, 2*64<<10 + 5}
+	for _, n := range sizes {
+		contents := bytes.Repeat([]byte("0123456789"), n/10+1)[:n]
+		for _, size := range []int64{int64(n), -1} {
+			item, err := ec.Put("secret", bytes.NewReader(contents), size, map[string]interface{}{"a": "b"})
+			is.NoErr(err)
+			is.NotEqual(c.items["secret"].data, contents)
+			md, err := item.Metadata()
+			is.NoErr(err)
+			is.Equal(md["a"], "b")
+			is.Equal(md[stow.MetadataEncryption], "aes-gcm")
+			plainSize, err := item.(stow.PlaintextSizer).PlaintextSize()
+			is.NoErr(err)
+			is.Equal(plainSize, int64(n))
+
+			item, err = ec.Item("secret")
+			is.NoErr(err)
+			rc, err := item.Open()
+			is.NoErr(err)
+			b, err := ioutil.ReadAll(rc)
+			is.NoErr(err)
+			is.NoErr(rc.Close())
+			is.True(bytes.Equal(b, contents))
+		}
+	}
+
+	// a known vector, with the nonce prefix 01..07
+	vector, err := hex.DecodeString("010203040506070bbd3b7387883b07bd1e4a530f4c9c1396aeacdc")
+	is.NoErr(err)
+	_, err = c.Put("vector", bytes.NewReader(vector), int64(len(vector)), map[string]interface{}{stow.MetadataEncryption: "aes-gcm"})
+	is.NoErr(err)
+	item, err := ec.Item("vector")
+	is.NoErr(err)
+	rc, err := item.Open()
+	is.NoErr(err)
+	b, err := ioutil.ReadAll(rc)
+	is.NoErr(err)
+	is.Equal(string(b), "stow")
+	plainSize, err := item.(stow.PlaintextSizer).PlaintextSize()
+	is.NoErr(err)
+	is.Equal(plainSize, int64(4))
+
+	// truncated contents fail to decrypt
+	_, err = c.Put("truncated", bytes.NewReader(vector[:len(vector)-1]), int64(len(vector)-1), map[string]interface{}{stow.MetadataEncryption: "aes-gcm"})
+	is.NoErr(err)
+	item, err = ec.Item("truncated")
+	is.NoErr(err)
+	_, err = item.Open()
+	is.Equal(err, stow.ErrDecrypt)
+
+	// plain Items and other keys fail to decrypt
+	_, err = c.Put("plain", strings.NewReader("plain"), 5, nil)
+	is.NoErr(err)
+	item, err = ec.Item("plain")
+	is.NoErr(err)
+	_, err = item.Open()
+	is.Equal(err, stow.ErrDecrypt)
+	otherKey := make([]byte, 32)
+	item, err = stow.EncryptedContainer(c, otherKey).Item("vector")
+	is.NoErr(err)
+	_, err = item.Open()
+	is.Equal(err, stow.ErrDecrypt)
+
+	_, err = ec.Put("short", strings.NewReader("short"), 10, nil)
+	is.Err(err)
+	_, err = stow.EncryptedContainer(c, []byte("bad key")).Put("bad", strings.NewReader("bad"), 3, nil)
+	is.Err(err)
+}
@@ -0,0 +1,30 @@
+package stow
+
+import "errors"
+
+// Existencer represents a Container that can check whether an Item
+// exists without getting it.
+type Existencer interface {
+	// Exists gets whether the Item with the specified ID exists.
+	// An error is only returned if the check itself failed.
+	Exists(id string) (bool, error)
+}
+
+// ItemExists gets whether the Item with the specified ID exists
+// in the Container.
+// The Container is used as an Existencer if possible, otherwise
+// the Item is got and errors matching ErrNotFound mean it does not
+// exist.
+func ItemExists(c Container, id string) (bool, error) {
+	if e, ok := c.(Existencer); ok {
+		return e.Exists(id)
+	}
+	_, err := c.Item(id)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,38 @@
+package stow_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// failingContainer is a testContainer whose Item always fails.
+type failingContainer struct {
+	*testContainer
+}
+
+var errDenied = errors.New("denied")
+
+func (c *failingContainer) Item(id string) (stow.Item, error) {
+	return nil, errDenied
+}
+
+func TestItemExists(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	_, err := c.Put("item", strings.NewReader("item"), 4, nil)
+	is.NoErr(err)
+
+	ok, err := stow.ItemExists(c, "item")
+	is.NoErr(err)
+	is.True(ok)
+	ok, err = stow.ItemExists(c, "nope")
+	is.NoErr(err)
+	is.False(ok)
+
+	_, err = stow.ItemExists(&failingContainer{testContainer: c}, "item")
+	is.Equal(err, errDenied)
+}
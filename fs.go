@@ -0,0 +1,376 @@
+//go:build go1.16
+// +build go1.16
+
+package stow
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fsPageSize is the number of entries the FS of a Container gets
+// per request when it lists a directory.
+const fsPageSize = 1000
+
+// FS gets an fs.FS backed by the Container, which also implements
+// fs.ReadDirFS and fs.StatFS, so that it can be used with packages
+// such as net/http and html/template, or walked with fs.WalkDir.
+// The slash separated names of the Items are their paths, and the
+// directories are the prefixes of the names ending with a slash.
+// Items whose names are not valid paths, such as names starting
+// with a slash, cannot be opened.
+// The FileInfo of a file gets its size and modification time from
+// the Item, and its Sys method returns the Item. Opened files can
+// seek, which reopens the Item at the new offset, using OpenRange
+// if the Item is a RangeOpener.
+func FS(c Container) fs.FS {
+	return &containerFS{c: c}
+}
+
+type containerFS struct {
+	c Container
+}
+
+func (f *containerFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	item, entries, err := f.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if item != nil {
+		return &fsFile{item: item}, nil
+	}
+	return &fsDir{name: name, entries: entries}, nil
+}
+
+func (f *containerFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	entries, err := f.readDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return entries, nil
+}
+
+func (f *containerFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	item, _, err := f.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	if item != nil {
+		return itemFileInfo(item)
+	}
+	return dirFileInfo(name), nil
+}
+
+// lookup gets the Item with the specified name, or else the entries
+// of the directory with that name. The root directory is never an
+// Item. Directories are looked for even if getting the Item fails
+// with another error than ErrNotFound, as some backends fail to get
+// directories as Items, but that error is returned if there is no
+// directory either.
+func (f *containerFS) lookup(name string) (Item, []fs.DirEntry, error) {
+	var err error
+	if name != "." {
+		var item Item
+		item, err = f.c.Item(name)
+		if err == nil {
+			return item, nil, nil
+		}
+	}
+	entries, derr := f.readDir(name)
+	if derr == nil {
+		return nil, entries, nil
+	}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, nil, err
+	}
+	return nil, nil, derr
+}
+
+// readDir gets the entries of the directory with the specified name,
+// sorted by name. Directories without any entries do not exist,
+// except for the root directory.
+// The Container is listed with Prefixes if it is a PrefixLister,
+// otherwise all of the Items in the directory are walked.
+func (f *containerFS) readDir(name string) ([]fs.DirEntry, error) {
+	prefix := ""
+	if name != "." {
+		prefix = name + "/"
+	}
+	var (
+		entries []fs.DirEntry
+		dirs    = make(map[string]bool)
+	)
+	addDir := func(p string) {
+		dir := strings.SplitN(strings.TrimPrefix(p, prefix), "/", 2)[0]
+		if dir != "" && !dirs[dir] {
+			dirs[dir] = true
+			entries = append(entries, fs.FileInfoToDirEntry(dirFileInfo(dir)))
+		}
+	}
+	addItem := func(item Item) error {
+		rest := strings.TrimPrefix(item.Name(), prefix)
+		if strings.Contains(rest, "/") {
+			addDir(item.Name())
+			return nil
+		}
+		if rest == "" {
+			return nil
+		}
+		info, err := itemFileInfo(item)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+		return nil
+	}
+	if pl, ok := f.c.(PrefixLister); ok {
+		cursor := CursorStart
+		for {
+			prefixes, items, next, err := pl.Prefixes(prefix, "/", cursor, fsPageSize)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range prefixes {
+				addDir(p)
+			}
+			for _, item := range items {
+				if err := addItem(item); err != nil {
+					return nil, err
+				}
+			}
+			if IsCursorEnd(next) {
+				break
+			}
+			cursor = next
+		}
+	} else {
+		err := Walk(f.c, prefix, fsPageSize, func(item Item, err error) error {
+			if err != nil {
+				return err
+			}
+			return addItem(item)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(entries) == 0 && name != "." {
+		return nil, fs.ErrNotExist
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// fileInfo is the fs.FileInfo of an Item or a directory.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	item    Item
+}
+
+// itemFileInfo gets the fs.FileInfo of the Item.
+func itemFileInfo(item Item) (fs.FileInfo, error) {
+	size, err := item.Size()
+	if err != nil {
+		return nil, err
+	}
+	modTime, err := item.LastMod()
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{
+		name:    path.Base(item.Name()),
+		size:    size,
+		mode:    0444,
+		modTime: modTime,
+		item:    item,
+	}, nil
+}
+
+// dirFileInfo gets the fs.FileInfo of the directory with the
+// specified name.
+func dirFileInfo(name string) fs.FileInfo {
+	return &fileInfo{
+		name: path.Base(name),
+		mode: fs.ModeDir | 0555,
+	}
+}
+
+func (i *fileInfo) Name() string       { return i.name }
+func (i *fileInfo) Size() int64        { return i.size }
+func (i *fileInfo) Mode() fs.FileMode  { return i.mode }
+func (i *fileInfo) ModTime() time.Time { return i.modTime }
+func (i *fileInfo) IsDir() bool        { return i.mode.IsDir() }
+
+// Sys gets the Item of a file, or nil for a directory.
+func (i *fileInfo) Sys() interface{} {
+	if i.item == nil {
+		// not a nil Item
+		return nil
+	}
+	return i.item
+}
+
+// fsFile is an opened Item. The Item is opened on the first read,
+// and again after seeking.
+type fsFile struct {
+	item   Item
+	rc     io.ReadCloser
+	offset int64 // the offset of the next read
+	pos    int64 // the offset of rc
+	closed bool
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	return itemFileInfo(f.item)
+}
+
+func (f *fsFile) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.rc != nil && f.pos != f.offset {
+		f.rc.Close()
+		f.rc = nil
+	}
+	if f.rc == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.rc.Read(p)
+	f.offset += int64(n)
+	f.pos = f.offset
+	return n, err
+}
+
+// open opens the Item for reading from the offset.
+func (f *fsFile) open() error {
+	if f.offset > 0 {
+		if r, ok := f.item.(RangeOpener); ok {
+			size, err := f.item.Size()
+			if err != nil {
+				return err
+			}
+			if f.offset >= size {
+				f.rc, f.pos = ioutil.NopCloser(strings.NewReader("")), f.offset
+				return nil
+			}
+			rc, err := r.OpenRange(uint64(f.offset), uint64(size-1))
+			if err != nil {
+				return err
+			}
+			f.rc, f.pos = rc, f.offset
+			return nil
+		}
+	}
+	rc, err := f.item.Open()
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(ioutil.Discard, rc, f.offset); err != nil && err != io.EOF {
+		rc.Close()
+		return err
+	}
+	f.rc, f.pos = rc, f.offset
+	return nil
+}
+
+func (f *fsFile) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		size, err := f.item.Size()
+		if err != nil {
+			return 0, err
+		}
+		offset += size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *fsFile) Close() error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+	f.closed = true
+	if f.rc == nil {
+		return nil
+	}
+	return f.rc.Close()
+}
+
+// fsDir is an opened directory, holding its entries.
+type fsDir struct {
+	name    string
+	entries []fs.DirEntry
+	closed  bool
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) {
+	return dirFileInfo(d.name), nil
+}
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+// ReadDir gets the next n entries of the directory, or all of the
+// remaining ones if n is not positive.
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.closed {
+		return nil, fs.ErrClosed
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+func (d *fsDir) Close() error {
+	if d.closed {
+		return fs.ErrClosed
+	}
+	d.closed = true
+	return nil
+}
@@ -0,0 +1,58 @@
+//go:build go1.16
+// +build go1.16
+
+package stow_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestFS(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	for _, name := range []string{"index.html", "a/b/deep.txt", "a/one.txt", "a/two.txt"} {
+		_, err := c.Put(name, strings.NewReader(name), int64(len(name)), nil)
+		is.NoErr(err)
+	}
+	fsys := stow.FS(c)
+	is.NoErr(fstest.TestFS(fsys, "index.html", "a/b/deep.txt", "a/one.txt", "a/two.txt"))
+
+	entries, err := fs.ReadDir(fsys, "a")
+	is.NoErr(err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	is.Equal(names, []string{"b", "one.txt", "two.txt"})
+	info, err := fs.Stat(fsys, "a/one.txt")
+	is.NoErr(err)
+	is.Equal(info.Size(), int64(9))
+	is.Equal(info.Sys().(stow.Item).Name(), "a/one.txt")
+	info, err = fs.Stat(fsys, "a/b")
+	is.NoErr(err)
+	is.True(info.IsDir())
+	_, err = fsys.Open("missing")
+	is.True(errors.Is(err, fs.ErrNotExist))
+
+	// files seek by reopening the item
+	f, err := fsys.Open("a/two.txt")
+	is.NoErr(err)
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	is.NoErr(err)
+	is.Equal(string(b), "a/two.txt")
+	_, err = f.(io.Seeker).Seek(2, io.SeekStart)
+	is.NoErr(err)
+	b, err = ioutil.ReadAll(f)
+	is.NoErr(err)
+	is.Equal(string(b), "two.txt")
+}
@@ -0,0 +1,57 @@
+package stow
+
+import (
+	"path"
+	"strings"
+)
+
+// GlobLister represents a Container that can find its Items
+// by a glob pattern.
+type GlobLister interface {
+	// Glob gets the Items whose slash separated names match the
+	// pattern, in the syntax of path.Match, sorted by name. The
+	// pattern is malformed if it fails with path.ErrBadPattern.
+	Glob(pattern string) ([]Item, error)
+}
+
+// globPageSize is the number of Items Glob gets per request
+// when it walks a Container.
+const globPageSize = 100
+
+// Glob gets the Items of the Container whose names match the pattern,
+// in the syntax of path.Match. The pattern is matched against the
+// whole name, so "*" does not match across slashes.
+// The Container is used as a GlobLister if possible, otherwise the
+// Items whose names start with the part of the pattern before its
+// first special character are walked.
+func Glob(c Container, pattern string) ([]Item, error) {
+	// check the pattern even when nothing is walked
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if g, ok := c.(GlobLister); ok {
+		return g.Glob(pattern)
+	}
+	prefix := pattern
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		prefix = pattern[:i]
+	}
+	var items []Item
+	err := Walk(c, prefix, globPageSize, func(item Item, err error) error {
+		if err != nil {
+			return err
+		}
+		ok, err := path.Match(pattern, item.Name())
+		if err != nil {
+			return err
+		}
+		if ok {
+			items = append(items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
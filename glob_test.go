@@ -0,0 +1,42 @@
+package stow_test
+
+import (
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestGlob(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	for _, name := range []string{"config-a.yaml", "config-b.yaml", "config-b.json", "nested/config-c.yaml", "other"} {
+		_, err := c.Put(name, strings.NewReader(name), int64(len(name)), nil)
+		is.NoErr(err)
+	}
+	names := func(items []stow.Item) []string {
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name())
+		}
+		return names
+	}
+
+	items, err := stow.Glob(c, "config-*.yaml")
+	is.NoErr(err)
+	is.Equal(names(items), []string{"config-a.yaml", "config-b.yaml"})
+	items, err = stow.Glob(c, "*/config-?.yaml")
+	is.NoErr(err)
+	is.Equal(names(items), []string{"nested/config-c.yaml"})
+	items, err = stow.Glob(c, "other")
+	is.NoErr(err)
+	is.Equal(names(items), []string{"other"})
+	items, err = stow.Glob(c, "missing*")
+	is.NoErr(err)
+	is.Equal(len(items), 0)
+
+	_, err = stow.Glob(c, "config-[")
+	is.Equal(err, path.ErrBadPattern)
+}
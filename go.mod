@@ -10,8 +10,10 @@ require (
 	github.com/aws/aws-sdk-go v1.23.4
 	github.com/cheekybits/is v0.0.0-20150225183255-68e9c0620927
 	github.com/dnaeon/go-vcr v1.1.0 // indirect
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/google/readahead v0.0.0-20161222183148-eaceba169032 // indirect
 	github.com/hashicorp/go-multierror v1.0.0
+	github.com/klauspost/compress v1.11.13
 	github.com/kr/fs v0.1.0 // indirect
 	github.com/ncw/swift v1.0.49
 	github.com/pkg/errors v0.8.1
@@ -22,6 +24,8 @@ require (
 	golang.org/x/crypto v0.0.0-20190701094942-4def268fd1a4
 	golang.org/x/net v0.0.0-20190813141303-74dc4d7220e7 // indirect
 	golang.org/x/oauth2 v0.0.0-20190604053449-0f29369cfe45
+	golang.org/x/sys v0.0.0-20201119102817-f84b799fce68
+	golang.org/x/time v0.0.0-20181108054448-85acf8d2951c
 	google.golang.org/api v0.8.0
 	gopkg.in/kothar/go-backblaze.v0 v0.0.0-20190520213052-702d4e7eb465
 )
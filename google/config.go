@@ -68,6 +68,7 @@ func init() {
 	}
 
 	stow.Register(Kind, makefn, kindfn, validatefn)
+	stow.RegisterConfigKeys(Kind, []string{ConfigJSON, ConfigProjectId}, []string{ConfigScopes})
 }
 
 // Attempts to create a session based on the information given.
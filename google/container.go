@@ -33,7 +33,7 @@ func (c *Container) Name() string {
 }
 
 // Bucket returns the google bucket attributes
-func (c *Container) Bucket() *storage.BucketHandle{
+func (c *Container) Bucket() *storage.BucketHandle {
 	return c.client.Bucket(c.name)
 }
 
@@ -43,7 +43,7 @@ func (c *Container) Item(id string) (stow.Item, error) {
 	item, err := c.Bucket().Object(id).Attrs(c.ctx)
 	if err != nil {
 		if err == storage.ErrObjectNotExist {
-			return nil, stow.ErrNotFound
+			return nil, stow.NotFound(err)
 		}
 		return nil, err
 	}
@@ -79,7 +79,11 @@ func (c *Container) Items(prefix string, cursor string, count int) ([]stow.Item,
 
 // RemoveItem will delete a google storage Object
 func (c *Container) RemoveItem(id string) error {
-	return c.Bucket().Object(id).Delete(c.ctx)
+	err := c.Bucket().Object(id).Delete(c.ctx)
+	if err == storage.ErrObjectNotExist {
+		return stow.NotFound(err)
+	}
+	return err
 }
 
 // Put sends a request to upload content to the container. The arguments
@@ -84,7 +84,7 @@ func (l *Location) Container(id string) (stow.Container, error) {
 	attrs, err := l.client.Bucket(id).Attrs(l.ctx)
 	if err != nil {
 		if err == storage.ErrBucketNotExist {
-			return nil, stow.ErrNotFound
+			return nil, stow.NotFound(err)
 		}
 		return nil, err
 	}
@@ -102,7 +102,7 @@ func (l *Location) Container(id string) (stow.Container, error) {
 func (l *Location) RemoveContainer(id string) error {
 	if err := l.client.Bucket(id).Delete(l.ctx); err != nil {
 		if e, ok := err.(*googleapi.Error); ok && e.Code == 404 {
-			return stow.ErrNotFound
+			return stow.NotFound(err)
 		}
 		return err
 	}
@@ -122,12 +122,12 @@ func (l *Location) ItemByURL(url *url.URL) (stow.Item, error) {
 
 	c, err := l.Container(pieces[5])
 	if err != nil {
-		return nil, stow.ErrNotFound
+		return nil, stow.NotFound(err)
 	}
 
 	i, err := c.Item(pieces[7])
 	if err != nil {
-		return nil, stow.ErrNotFound
+		return nil, stow.NotFound(err)
 	}
 
 	return i, nil
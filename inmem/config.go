@@ -0,0 +1,26 @@
+package inmem
+
+import (
+	"net/url"
+
+	"github.com/graymeta/stow"
+)
+
+// Kind is the kind of Location this package provides.
+const Kind = "mem"
+
+func init() {
+	validatefn := func(config stow.Config) error {
+		return nil
+	}
+	makefn := func(config stow.Config) (stow.Location, error) {
+		return &location{
+			containers: make(map[string]*container),
+		}, nil
+	}
+	kindfn := func(u *url.URL) bool {
+		return u.Scheme == Kind
+	}
+	stow.Register(Kind, makefn, kindfn, validatefn)
+	stow.RegisterConfigKeys(Kind, nil, nil)
+}
@@ -0,0 +1,106 @@
+package inmem
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graymeta/stow"
+)
+
+type container struct {
+	name  string
+	lock  sync.RWMutex // protects items
+	items map[string]*item
+}
+
+func (c *container) ID() string {
+	return c.name
+}
+
+func (c *container) Name() string {
+	return c.name
+}
+
+func (c *container) Item(id string) (stow.Item, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	item, ok := c.items[id]
+	if !ok {
+		return nil, stow.ErrNotFound
+	}
+	return item, nil
+}
+
+func (c *container) Items(prefix, cursor string, count int) ([]stow.Item, string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	var names []string
+	for name := range c.items {
+		if strings.HasPrefix(name, prefix) && name >= cursor {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	cursor = ""
+	if len(names) > count {
+		cursor = names[count]
+		names = names[:count]
+	}
+	items := make([]stow.Item, 0, len(names))
+	for _, name := range names {
+		items = append(items, c.items[name])
+	}
+	return items, cursor, nil
+}
+
+func (c *container) RemoveItem(id string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.items[id]; !ok {
+		return stow.ErrNotFound
+	}
+	delete(c.items, id)
+	return nil
+}
+
+// Put stores a copy of the contents and metadata as an item,
+// replacing any item with the same name.
+func (c *container) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if size >= 0 && int64(len(b)) != size {
+		return nil, errors.New("bad size")
+	}
+	hash := md5.Sum(b)
+	item := &item{
+		container: c,
+		name:      name,
+		data:      b,
+		metadata:  copyMetadata(metadata),
+		etag:      hex.EncodeToString(hash[:]),
+		lastMod:   time.Now(),
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.items[name] = item
+	return item, nil
+}
+
+// copyMetadata gets a copy of the metadata, so that later
+// changes to the map are not seen by items.
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	md := make(map[string]interface{}, len(metadata))
+	for k, v := range metadata {
+		md[k] = v
+	}
+	return md
+}
@@ -0,0 +1,78 @@
+package inmem_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+	"github.com/graymeta/stow/inmem"
+)
+
+func TestItemsPaging(t *testing.T) {
+	is := is.New(t)
+	l, err := stow.Dial(inmem.Kind, stow.ConfigMap{})
+	is.NoErr(err)
+	c, err := l.CreateContainer("c")
+	is.NoErr(err)
+	for i := 0; i < 25; i++ {
+		_, err := c.Put(fmt.Sprintf("item%02d", i), strings.NewReader("x"), 1, nil)
+		is.NoErr(err)
+	}
+	var names []string
+	err = stow.Walk(c, stow.NoPrefix, 10, func(item stow.Item, err error) error {
+		if err != nil {
+			return err
+		}
+		names = append(names, item.Name())
+		return nil
+	})
+	is.NoErr(err)
+	is.Equal(len(names), 25)
+	is.Equal(names[0], "item00")
+	is.Equal(names[24], "item24")
+
+	// locations do not share their contents
+	l2, err := stow.Dial(inmem.Kind, stow.ConfigMap{})
+	is.NoErr(err)
+	_, err = l2.Container("c")
+	is.True(errors.Is(err, stow.ErrNotFound))
+}
+
+func TestPutReplaces(t *testing.T) {
+	is := is.New(t)
+	l, err := stow.Dial(inmem.Kind, stow.ConfigMap{})
+	is.NoErr(err)
+	c, err := l.CreateContainer("c")
+	is.NoErr(err)
+	md := map[string]interface{}{"a": "b"}
+	old, err := c.Put("item", strings.NewReader("old"), 3, md)
+	is.NoErr(err)
+	md["a"] = "changed"
+	_, err = c.Put("item", strings.NewReader("newer"), 5, nil)
+	is.NoErr(err)
+
+	item, err := c.Item("item")
+	is.NoErr(err)
+	size, err := item.Size()
+	is.NoErr(err)
+	is.Equal(size, int64(5))
+	oldMD, err := old.Metadata()
+	is.NoErr(err)
+	is.Equal(oldMD["a"], "b")
+	etag, err := item.ETag()
+	is.NoErr(err)
+	is.Equal(etag, "0c10f4a0c12ba89211235026b861263d") // md5("newer")
+
+	rc, err := item.(stow.ItemRanger).OpenRange(1, 100)
+	is.NoErr(err)
+	b, err := ioutil.ReadAll(rc)
+	is.NoErr(err)
+	is.Equal(string(b), "ewer")
+
+	_, err = c.Put("short", strings.NewReader("short"), 10, nil)
+	is.Err(err)
+}
@@ -0,0 +1,28 @@
+/*
+Package inmem provides a Location that keeps everything in memory, for use in tests. A Stow Container is a named set of items, and a Stow Item holds its contents and metadata.
+
+Usage and Credentials
+
+No configuration is needed, stow.Dial with the Kind ("mem") and an empty stow.ConfigMap gives a new, empty Location. Every Location is separate from the others, and its contents are lost once it is no longer used.
+
+Location
+
+The methods of inmem.location allow the creation, retrieval and removal of containers (CreateContainer, Container, Containers, RemoveContainer), and the retrieval of an item by its URL (ItemByURL). Container names cannot contain slashes.
+
+Container
+
+The methods of inmem.container allow the retrieval of one or more items (Item or Items), their removal (RemoveItem) and their creation (Put). Items are listed in name order. Putting an item with an existing name replaces it, items that were already got keep their old contents.
+
+Item
+
+The methods of inmem.item allow the retrieval of:
+- name (ID or Name)
+- size in bytes (Size)
+- metadata given to Put (Metadata)
+- MD5 hash of the contents (ETag)
+- time it was put (LastMod)
+- content (Open, OpenRange)
+
+Locations, Containers and Items are safe for concurrent use.
+*/
+package inmem
@@ -0,0 +1,75 @@
+package inmem
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"time"
+)
+
+// item is an immutable snapshot of the contents and
+// metadata put to a container.
+type item struct {
+	container *container
+	name      string
+	data      []byte
+	metadata  map[string]interface{}
+	etag      string
+	lastMod   time.Time
+}
+
+func (i *item) ID() string {
+	return i.name
+}
+
+func (i *item) Name() string {
+	return i.name
+}
+
+func (i *item) URL() *url.URL {
+	return &url.URL{
+		Scheme: Kind,
+		Path:   "/" + i.container.name + "/" + i.name,
+	}
+}
+
+func (i *item) Size() (int64, error) {
+	return int64(len(i.data)), nil
+}
+
+func (i *item) Open() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(i.data)), nil
+}
+
+// OpenRange opens the item for reading from byte start to
+// byte end, both included.
+func (i *item) OpenRange(start, end uint64) (io.ReadCloser, error) {
+	size := uint64(len(i.data))
+	if start >= size || end < start {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+	if end >= size {
+		end = size - 1
+	}
+	return ioutil.NopCloser(bytes.NewReader(i.data[start : end+1])), nil
+}
+
+// ETag gets the MD5 hash of the contents of the item.
+func (i *item) ETag() (string, error) {
+	return i.etag, nil
+}
+
+func (i *item) LastMod() (time.Time, error) {
+	return i.lastMod, nil
+}
+
+// Delete removes the item from its container.
+func (i *item) Delete() error {
+	return i.container.RemoveItem(i.name)
+}
+
+// Metadata gets a copy of the metadata given to Put.
+func (i *item) Metadata() (map[string]interface{}, error) {
+	return copyMetadata(i.metadata), nil
+}
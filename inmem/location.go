@@ -0,0 +1,96 @@
+package inmem
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/graymeta/stow"
+)
+
+type location struct {
+	lock       sync.RWMutex // protects containers
+	containers map[string]*container
+}
+
+func (l *location) Close() error {
+	return nil // nothing to close
+}
+
+func (l *location) CreateContainer(name string) (stow.Container, error) {
+	if name == "" || strings.Contains(name, "/") {
+		return nil, errors.New("invalid container name")
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, ok := l.containers[name]; ok {
+		return nil, errors.New("container already exists")
+	}
+	c := &container{
+		name:  name,
+		items: make(map[string]*item),
+	}
+	l.containers[name] = c
+	return c, nil
+}
+
+func (l *location) Containers(prefix string, cursor string, count int) ([]stow.Container, string, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	var names []string
+	for name := range l.containers {
+		if strings.HasPrefix(name, prefix) && name >= cursor {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	cursor = ""
+	if len(names) > count {
+		cursor = names[count]
+		names = names[:count]
+	}
+	containers := make([]stow.Container, 0, len(names))
+	for _, name := range names {
+		containers = append(containers, l.containers[name])
+	}
+	return containers, cursor, nil
+}
+
+func (l *location) Container(id string) (stow.Container, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	c, ok := l.containers[id]
+	if !ok {
+		return nil, stow.ErrNotFound
+	}
+	return c, nil
+}
+
+func (l *location) RemoveContainer(id string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, ok := l.containers[id]; !ok {
+		return stow.ErrNotFound
+	}
+	delete(l.containers, id)
+	return nil
+}
+
+// ItemByURL gets the item for a mem:// URL got from the URL
+// method of an item.
+func (l *location) ItemByURL(u *url.URL) (stow.Item, error) {
+	if u.Scheme != Kind {
+		return nil, errors.New("unexpected url scheme " + u.Scheme)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("url path must hold a container and item name")
+	}
+	c, err := l.Container(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	return c.Item(parts[1])
+}
@@ -0,0 +1,12 @@
+package inmem_test
+
+import (
+	"testing"
+
+	"github.com/graymeta/stow"
+	"github.com/graymeta/stow/test"
+)
+
+func TestStow(t *testing.T) {
+	test.All(t, "mem", stow.ConfigMap{})
+}
@@ -0,0 +1,148 @@
+package local
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/graymeta/stow"
+)
+
+// appendLock serializes the appends and truncations of this process,
+// on platforms where files cannot be locked.
+var appendLock sync.Mutex
+
+// Append appends the contents of r to the file of the item with the
+// specified ID, creating it and its directory if they do not exist,
+// and gets the item. The file is opened with O_APPEND and locked while
+// the contents are written, so concurrent appends, even from other
+// processes, do not interleave. The metadata of the item is kept, but
+// a checksum recorded under stow.MetadataContentMD5 is updated to the
+// new contents. Compressed items, whose contents are decompressed
+// transparently, cannot be appended to.
+func (c *container) Append(id string, r io.Reader) (_ stow.Item, err error) {
+	defer wrapErr(&err, "append", id)
+
+	path, err := c.appendPath(id)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(longPath(filepath.Dir(path)), c.location.dirMode())
+	if err != nil {
+		return nil, err
+	}
+	release := c.location.writeFiles.acquire()
+	defer release()
+	f, err := os.OpenFile(longPath(path), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	unlock, err := lockAppend(f)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	buf := c.location.copyBuffers.Get().(*[]byte)
+	// hide f.ReadFrom, which would copy through its own buffer
+	_, err = io.CopyBuffer(writerOnly{f}, r, *buf)
+	c.location.copyBuffers.Put(buf)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.updateContentMD5(path); err != nil {
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return c.newItem(path, existingMeta(path)), nil
+}
+
+// Truncate changes the size of the file of the item with the
+// specified ID, holding the same lock as Append, and updates its
+// recorded checksum in the same way.
+func (c *container) Truncate(id string, size int64) (err error) {
+	defer wrapErr(&err, "truncate", id)
+
+	if size < 0 {
+		return errors.New("negative size")
+	}
+	path, err := c.appendPath(id)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(longPath(path), os.O_WRONLY, 0)
+	if os.IsNotExist(err) {
+		return stow.NotFound(err)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	unlock, err := lockAppend(f)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+	if err := c.updateContentMD5(path); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// appendPath gets the path of the file of the item with the specified
+// ID to append to or truncate, failing for compressed items.
+func (c *container) appendPath(id string) (string, error) {
+	path := c.storedPath(c.itemPath(id))
+	if !c.includedPath(path) {
+		return "", stow.ErrNotFound
+	}
+	if c.location.transparentDecompress && compressedExt(path) != "" {
+		return "", stow.NotSupported("appending to compressed items")
+	}
+	return path, nil
+}
+
+// updateContentMD5 replaces the checksum recorded in the user metadata
+// of the file at path after its contents changed, or removes it if
+// checksums are no longer recorded.
+func (c *container) updateContentMD5(path string) error {
+	i := c.newItem(path, existingMeta(path))
+	if err := i.ensureInfo(); err != nil {
+		return err
+	}
+	md, _ := i.metadata[MetadataUser].(map[string]interface{})
+	if c.location.recordMD5 {
+		var err error
+		if md, err = c.withContentMD5(path, md); err != nil {
+			return err
+		}
+	} else if _, ok := md[stow.MetadataContentMD5]; ok {
+		delete(md, stow.MetadataContentMD5)
+	} else {
+		return nil
+	}
+	_, err := c.writeMeta(path, md)
+	return err
+}
+
+// lockAppend locks the opened file for appending to it, both within
+// the process and, where files can be locked, for other processes.
+func lockAppend(f *os.File) (func(), error) {
+	appendLock.Lock()
+	unlock, err := lockFile(f)
+	if err != nil {
+		appendLock.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		appendLock.Unlock()
+	}, nil
+}
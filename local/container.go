@@ -1,6 +1,7 @@
 package local
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,21 +9,62 @@ import (
 	"io/ioutil"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/graymeta/stow"
+	"github.com/hashicorp/go-multierror"
 )
 
 type container struct {
-	name string
-	path string
+	name     string
+	path     string
+	location *location
+	include  string // glob the names of items match, for virtual containers
+	byURL    bool   // made for an item got by URL, not got from the location
 }
 
+// ID gets the path of the directory of the container, or the name
+// of a virtual container.
 func (c *container) ID() string {
+	if c.include != "" {
+		return c.name
+	}
 	return c.path
 }
 
+// included gets whether the file with the specified name, relative
+// to the container, is an item of the container.
+func (c *container) included(name string) bool {
+	return c.include == "" || matchGlob(c.include, name)
+}
+
+// includedPath gets whether the file at path is an item
+// of the container.
+func (c *container) includedPath(path string) bool {
+	if c.include == "" {
+		return true
+	}
+	name, err := filepath.Rel(c.path, path)
+	if err != nil || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return false
+	}
+	return c.included(name)
+}
+
+// checkName checks that an item with the specified name can be
+// put into the container.
+func (c *container) checkName(name string) error {
+	if !c.included(name) {
+		return fmt.Errorf("name %s does not match %s of virtual container %s", name, c.include, c.name)
+	}
+	return nil
+}
+
 func (c *container) Name() string {
 	return c.name
 }
@@ -34,21 +76,35 @@ func (c *container) URL() *url.URL {
 	}
 }
 
-func (c *container) CreateItem(name string) (stow.Item, io.WriteCloser, error) {
+func (c *container) CreateItem(name string) (_ stow.Item, _ io.WriteCloser, err error) {
+	defer wrapErr(&err, "create", name)
+
+	if err := c.checkName(name); err != nil {
+		return nil, nil, err
+	}
 	path := filepath.Join(c.path, filepath.FromSlash(name))
-	item := &item{
-		path:          path,
-		contPrefixLen: len(c.path) + 1,
+	item := c.newItem(path, "")
+	if err := os.MkdirAll(longPath(filepath.Dir(path)), c.location.dirMode()); err != nil {
+		return nil, nil, err
 	}
-	f, err := os.Create(path)
+	f, err := os.Create(longPath(path))
 	if err != nil {
 		return nil, nil, err
 	}
 	return item, f, nil
 }
 
-func (c *container) RemoveItem(id string) error {
-	err := os.Remove(id)
+func (c *container) RemoveItem(id string) (err error) {
+	defer wrapErr(&err, "remove", id)
+
+	id = c.storedPath(c.itemPath(id))
+	if !c.includedPath(id) {
+		return stow.ErrNotFound
+	}
+	err = os.Remove(id)
+	if os.IsNotExist(err) {
+		return stow.NotFound(err)
+	}
 	if err != nil {
 		return err
 	}
@@ -60,53 +116,358 @@ func (c *container) RemoveItem(id string) error {
 			return errors.New(fmt.Sprintf("failed to remove meta data: %s", err.Error()))
 		}
 	}
+	if c.location.metaIndex {
+		if err := c.removeIndexed(id); err != nil {
+			return fmt.Errorf("failed to remove meta data: %w", err)
+		}
+	}
+	if err := removeTags(id); err != nil {
+		return fmt.Errorf("failed to remove tags: %w", err)
+	}
 	return nil
 }
 
-func (c *container) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+// Move renames the file of the item with the specified ID, its
+// metadata file or indexed metadata, and its tags file, to dstName
+// within the container. Missing parent
+// directories are created. Files cannot be moved across devices, for
+// example into a directory that another filesystem is mounted on.
+// A compressed file, whose contents are decompressed transparently,
+// moves to the compressed file of dstName, replacing any other file
+// of that name, and the other way around.
+func (c *container) Move(srcID, dstName string) (_ stow.Item, err error) {
+	defer wrapErr(&err, "move", srcID)
+
+	if err := c.checkName(dstName); err != nil {
+		return nil, err
+	}
+	src := c.storedPath(c.itemPath(srcID))
+	if !c.includedPath(src) {
+		return nil, stow.ErrNotFound
+	}
+	info, err := os.Lstat(src)
+	if os.IsNotExist(err) {
+		return nil, stow.NotFound(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, errors.New("unexpected directory")
+	}
+	dst, err := filepath.Abs(filepath.Join(c.path, filepath.FromSlash(dstName)))
+	if err != nil {
+		return nil, err
+	}
+	var others []string
+	if c.location.transparentDecompress {
+		if ext := compressedExt(src); ext != "" && compressedExt(dst) == "" {
+			dst += ext
+		}
+		others = otherPaths(dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), c.location.dirMode()); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		if isCrossDevice(err) {
+			return nil, fmt.Errorf("cannot move %s to %s across devices: %w", srcID, dstName, err)
+		}
+		return nil, err
+	}
+	// the metadata file follows the file, replacing any existing one
+	if metaPath := existingMeta(src); metaPath != "" {
+		err = os.Rename(metaPath, dst+MetadataFileExt)
+	} else if err = os.Remove(dst + MetadataFileExt); os.IsNotExist(err) {
+		err = nil
+	}
+	if err == nil && c.location.metaIndex {
+		err = c.moveIndexed(src, dst)
+	}
+	if err == nil {
+		// so do the tags
+		if err = os.Rename(src+TagsFileExt, dst+TagsFileExt); os.IsNotExist(err) {
+			err = removeTags(dst)
+		}
+	}
+	if err != nil {
+		os.Rename(dst, src)
+		return nil, fmt.Errorf("failed to move meta data: %w", err)
+	}
+	for _, other := range others {
+		if other == src {
+			continue
+		}
+		if err := c.removeOther(other); err != nil {
+			return nil, err
+		}
+	}
+	return c.newItem(dst, existingMeta(dst)), nil
+}
+
+// RemoveItems removes the items with the specified IDs, carrying on
+// past any failures.
+func (c *container) RemoveItems(ids []string) error {
+	var errs *multierror.Error
+	for _, id := range ids {
+		if err := c.RemoveItem(id); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("removing %s: %w", id, err))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// Exists gets whether the file for the item with the specified ID
+// exists, without following symlinks. Directories are not items.
+func (c *container) Exists(id string) (_ bool, err error) {
+	defer wrapErr(&err, "exists", id)
+
+	path := c.itemPath(id)
+	if !c.includedPath(path) {
+		return false, nil
+	}
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !info.IsDir(), nil
+}
+
+// Stat gets the number of items in the container and the sum
+// of their sizes in bytes, with a single walk of the directory.
+func (c *container) Stat() (_, _ int64, err error) {
+	defer wrapErr(&err, "stat", c.name)
+
+	files, err := c.files()
+	if err != nil {
+		return 0, 0, err
+	}
+	var total int64
+	for _, f := range files {
+		total += f.Size()
+	}
+	return int64(len(files)), total, nil
+}
+
+func (c *container) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (_ stow.Item, err error) {
+	defer wrapErr(&err, "put", name)
+
+	if err := c.checkName(name); err != nil {
+		return nil, err
+	}
 	path := filepath.Join(c.path, filepath.FromSlash(name))
-	item := &item{
-		path:          path,
-		contPrefixLen: len(c.path) + 1,
+	var others []string
+	if c.location.transparentDecompress {
+		var stop func()
+		path, r, size, others, stop = compressPut(path, r, size, metadata)
+		defer stop()
 	}
-	err := os.MkdirAll(filepath.Dir(path), 0777)
+	item := c.newItem(path, "")
+	err = os.MkdirAll(longPath(filepath.Dir(path)), c.location.dirMode())
 	if err != nil {
 		return nil, err
 	}
-	f, err := os.Create(path)
+	item.written, err = c.writeFile(path, r, size, metadata)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	n, err := io.Copy(f, r)
+	for _, other := range others {
+		if err := c.removeOther(other); err != nil {
+			return nil, err
+		}
+	}
+	if err := removeTags(path); err != nil {
+		return nil, err
+	}
+
+	metadata, err = c.withContentMD5(path, metadata)
 	if err != nil {
 		return nil, err
 	}
-	if n != size {
-		return nil, errors.New("bad size")
+	item.metaPath, err = c.writeMeta(path, metadata)
+	if err != nil {
+		return item, errors.New(fmt.Sprintf("failed to save meta data: %s", err.Error()))
 	}
 
-	if len(metadata) > 0 {
-		metaPath := path + MetadataFileExt
+	return item, nil
+}
 
-		var j []byte
-		j, err = json.MarshalIndent(metadata, "", "    ")
-		err = ioutil.WriteFile(metaPath, j, 0644)
-		if err != nil {
-			return item, errors.New(fmt.Sprintf("failed to save meta data: %s", err.Error()))
+// writeMeta writes the user metadata for the file at path into its
+// metadata file, leaving out any of the reserved file metadata keys.
+// The metadata file is replaced atomically. If there is no user
+// metadata, any existing metadata file is removed.
+// The path of the metadata file is returned, or an empty string if
+// none was written.
+func writeMeta(path string, metadata map[string]interface{}) (string, error) {
+	metaPath := path + MetadataFileExt
+	md := userMetadata(metadata)
+	if len(md) == 0 {
+		err := os.Remove(metaPath)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
 		}
-		item.metaPath = metaPath
+		return "", nil
+	}
+	j, err := json.MarshalIndent(md, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(metaPath, j, 0644); err != nil {
+		return "", err
 	}
+	return metaPath, nil
+}
 
+// writeFileAtomic writes data to the file at path through a temporary
+// file that is renamed into place, so that readers either see the old
+// or the new contents of the file, never partly written ones.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := createTemp(longPath(filepath.Dir(path)))
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), longPath(path))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// existingMeta gets the path of the metadata file for the file at
+// path, or an empty string if there is none.
+func existingMeta(path string) string {
+	metaPath := path + MetadataFileExt
+	fi, err := os.Stat(longPath(metaPath))
+	if err != nil || fi == nil || fi.IsDir() {
+		return ""
+	}
+	return metaPath
+}
+
+// PutContext creates a new item like Put, but stops writing with the
+// context error once the context is done.
+func (c *container) PutContext(ctx context.Context, name string, r io.Reader, size int64, metadata map[string]interface{}) (_ stow.Item, err error) {
+	defer wrapErr(&err, "put", name)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Put(name, &ctxReader{ctx: ctx, r: r}, size, metadata)
+}
+
+// PutIfNotExists creates a new item like Put, but fails with
+// stow.ErrAlreadyExists if the file already exists. The file is
+// created exclusively, so it is written in place even when atomic
+// puts are enabled, and removed if writing fails. If compressed files
+// are decompressed transparently, the contents are compressed as by
+// Put, and the item also exists if one of the other files with its
+// name does, which is checked before the file is created.
+func (c *container) PutIfNotExists(name string, r io.Reader, size int64, metadata map[string]interface{}) (_ stow.Item, err error) {
+	defer wrapErr(&err, "put", name)
+
+	if err := c.checkName(name); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(c.path, filepath.FromSlash(name))
+	if c.location.transparentDecompress {
+		var others []string
+		var stop func()
+		path, r, size, others, stop = compressPut(path, r, size, metadata)
+		defer stop()
+		for _, other := range others {
+			_, err := os.Lstat(longPath(other))
+			if err == nil {
+				return nil, stow.ErrAlreadyExists
+			}
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+	}
+	item := c.newItem(path, "")
+	err = os.MkdirAll(longPath(filepath.Dir(path)), c.location.dirMode())
+	if err != nil {
+		return nil, err
+	}
+	item.written, err = c.writeNewFile(path, r, size, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err = c.withContentMD5(path, metadata)
+	if err != nil {
+		return nil, err
+	}
+	item.metaPath, err = c.writeMeta(path, metadata)
+	if err != nil {
+		return item, errors.New(fmt.Sprintf("failed to save meta data: %s", err.Error()))
+	}
 	return item, nil
 }
 
-func (c *container) Items(prefix, cursor string, count int) ([]stow.Item, string, error) {
+// PutWriter starts putting a new item, and gets a writer for its
+// contents. The contents are written to a temporary file in the same
+// directory, or the temp dir, which closing the writer renames into
+// place, even when atomic puts are disabled. CloseWithError removes
+// the temporary file instead.
+func (c *container) PutWriter(name string, size int64, metadata map[string]interface{}) (_ io.WriteCloser, err error) {
+	defer wrapErr(&err, "put", name)
+
+	if err := c.checkName(name); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(c.path, filepath.FromSlash(name))
+	err = os.MkdirAll(longPath(filepath.Dir(path)), c.location.dirMode())
+	if err != nil {
+		return nil, err
+	}
+	dir, err := c.stagingDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	release := c.location.writeFiles.acquire()
+	f, err := createTemp(longPath(dir))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &fileWriter{
+		container: c,
+		name:      name,
+		path:      path,
+		size:      size,
+		metadata:  metadata,
+		f:         f,
+		release:   release,
+	}, nil
+}
+
+func (c *container) Items(prefix, cursor string, count int) (_ []stow.Item, _ string, err error) {
+	defer wrapErr(&err, "list", prefix)
+
 	prefix = filepath.FromSlash(prefix)
-	files, err := flatdirs(c.path)
+	files, err := c.files()
 	if err != nil {
 		return nil, "", err
 	}
+	if c.location.sortItems {
+		sort.Slice(files, func(i, j int) bool {
+			return filepath.ToSlash(files[i].Name()) < filepath.ToSlash(files[j].Name())
+		})
+	}
 	if cursor != stow.CursorStart {
 		// seek to the cursor
 		ok := false
@@ -139,30 +500,253 @@ func (c *container) Items(prefix, cursor string, count int) ([]stow.Item, string
 		if !strings.HasPrefix(f.Name(), prefix) {
 			continue
 		}
-		metaPath := path + MetadataFileExt
-		fi, err := os.Stat(metaPath)
-		if err != nil || fi == nil || fi.IsDir() {
-			metaPath = ""
+		item := c.newItem(path, existingMeta(path))
+		if c.location.eagerStat {
+			item.prefillInfo(f.(fileinfo).FileInfo)
+		}
+		items = append(items, item)
+	}
+	return items, cursor, nil
+}
+
+// ItemsSince gets a page of the items whose names start with the
+// prefix, and whose files were modified after since. The modification
+// times come from the listing, so the files of the other items are
+// not stat'ed, and the items that are returned have their file info
+// filled in.
+func (c *container) ItemsSince(prefix string, since time.Time, cursor string, count int) (_ []stow.Item, _ string, err error) {
+	defer wrapErr(&err, "list", prefix)
+
+	prefix = filepath.FromSlash(prefix)
+	all, err := c.files()
+	if err != nil {
+		return nil, "", err
+	}
+	var files []os.FileInfo
+	for _, f := range all {
+		if !f.IsDir() && strings.HasPrefix(f.Name(), prefix) && f.ModTime().After(since) {
+			files = append(files, f)
+		}
+	}
+	if c.location.sortItems {
+		sort.Slice(files, func(i, j int) bool {
+			return filepath.ToSlash(files[i].Name()) < filepath.ToSlash(files[j].Name())
+		})
+	}
+	if cursor != stow.CursorStart {
+		i := 0
+		for i < len(files) && files[i].Name() != cursor {
+			i++
+		}
+		if i == len(files) {
+			return nil, "", stow.ErrBadCursor
 		}
-		item := &item{
-			path:          path,
-			metaPath:      metaPath,
-			contPrefixLen: len(c.path) + 1,
+		files = files[i:]
+	}
+	cursor = "" // end
+	if len(files) > count {
+		cursor = files[count].Name()
+		files = files[:count]
+	}
+	items := make([]stow.Item, 0, len(files))
+	for _, f := range files {
+		path, err := filepath.Abs(filepath.Join(c.path, f.Name()))
+		if err != nil {
+			return nil, "", err
 		}
+		item := c.newItem(path, existingMeta(path))
+		item.prefillInfo(f.(fileinfo).FileInfo)
 		items = append(items, item)
 	}
 	return items, cursor, nil
 }
 
-func (c *container) Item(id string) (stow.Item, error) {
-	path := id
-	if !filepath.IsAbs(id) {
-		path = filepath.Join(c.path, filepath.FromSlash(id))
+// ItemsOrdered gets a page of the items whose names start with the
+// prefix, in the specified order. All of the entries of the container
+// are read and held in memory to sort them, for every page, so it
+// takes as much memory as listing the container with sorted items.
+// The modification times come from the listing, so the files are not
+// stat'ed again.
+func (c *container) ItemsOrdered(prefix string, order stow.Order, cursor string, count int) (_ []stow.Item, _ string, err error) {
+	defer wrapErr(&err, "list", prefix)
+
+	all, err := c.files()
+	if err != nil {
+		return nil, "", err
 	}
-	info, err := os.Stat(path)
+	var files []os.FileInfo
+	for _, f := range all {
+		if !f.IsDir() && strings.HasPrefix(filepath.ToSlash(f.Name()), prefix) {
+			files = append(files, f)
+		}
+	}
+	byName := func(i, j int) bool {
+		return filepath.ToSlash(files[i].Name()) < filepath.ToSlash(files[j].Name())
+	}
+	var less func(i, j int) bool
+	switch order {
+	case stow.NameAsc:
+		less = byName
+	case stow.NameDesc:
+		less = func(i, j int) bool { return byName(j, i) }
+	case stow.ModTimeAsc:
+		less = func(i, j int) bool {
+			if ti, tj := files[i].ModTime(), files[j].ModTime(); !ti.Equal(tj) {
+				return ti.Before(tj)
+			}
+			return byName(i, j)
+		}
+	case stow.ModTimeDesc:
+		less = func(i, j int) bool {
+			if ti, tj := files[i].ModTime(), files[j].ModTime(); !ti.Equal(tj) {
+				return ti.After(tj)
+			}
+			return byName(j, i)
+		}
+	default:
+		return nil, "", stow.NotSupported("order " + strconv.Itoa(int(order)))
+	}
+	sort.Slice(files, less)
+	if cursor != stow.CursorStart {
+		i := 0
+		for i < len(files) && files[i].Name() != cursor {
+			i++
+		}
+		if i == len(files) {
+			return nil, "", stow.ErrBadCursor
+		}
+		files = files[i:]
+	}
+	cursor = "" // end
+	if len(files) > count {
+		cursor = files[count].Name()
+		files = files[:count]
+	}
+	items := make([]stow.Item, 0, len(files))
+	for _, f := range files {
+		path, err := filepath.Abs(filepath.Join(c.path, f.Name()))
+		if err != nil {
+			return nil, "", err
+		}
+		item := c.newItem(path, existingMeta(path))
+		if c.location.eagerStat {
+			item.prefillInfo(f.(fileinfo).FileInfo)
+		}
+		items = append(items, item)
+	}
+	return items, cursor, nil
+}
+
+// Prefixes lists a single directory of the container: the directory
+// named by the prefix up to its last slash. Its files whose names start
+// with the prefix are returned as items, and its subdirectories as
+// prefixes ending with a slash. Only the "/" delimiter is supported.
+func (c *container) Prefixes(prefix, delimiter, cursor string, count int) (_ []string, _ []stow.Item, _ string, err error) {
+	defer wrapErr(&err, "list", prefix)
+
+	if delimiter != "/" {
+		return nil, nil, "", stow.NotSupported("delimiter " + delimiter)
+	}
+	dir := prefix[:strings.LastIndex(prefix, "/")+1]
+	infos, err := ioutil.ReadDir(filepath.Join(c.path, filepath.FromSlash(dir)))
 	if os.IsNotExist(err) {
+		return nil, nil, "", nil
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+	var (
+		keys  []string
+		files = make(map[string]os.FileInfo)
+	)
+	for _, info := range infos {
+		key := dir + info.Name()
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if info.Mode()&os.ModeSymlink == os.ModeSymlink && c.location.followSymlinks {
+			info, err = os.Stat(filepath.Join(c.path, filepath.FromSlash(key)))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, nil, "", err
+			}
+		}
+		if c.location.ignored(key) {
+			continue
+		}
+		if info.IsDir() {
+			keys = append(keys, key+"/")
+			continue
+		}
+		if isSidecar(key) || isTemp(key) || !c.included(key) {
+			continue
+		}
+		keys = append(keys, key)
+		files[key] = info
+	}
+	// prefixes sort differently from the directory names
+	sort.Strings(keys)
+	if cursor != stow.CursorStart {
+		i := sort.SearchStrings(keys, cursor)
+		if i == len(keys) || keys[i] != cursor {
+			return nil, nil, "", stow.ErrBadCursor
+		}
+		keys = keys[i:]
+	}
+	cursor = "" // end
+	if len(keys) > count {
+		cursor = keys[count]
+		keys = keys[:count]
+	}
+	var (
+		prefixes []string
+		items    []stow.Item
+	)
+	for _, key := range keys {
+		info, ok := files[key]
+		if !ok {
+			prefixes = append(prefixes, key)
+			continue
+		}
+		path, err := filepath.Abs(filepath.Join(c.path, filepath.FromSlash(key)))
+		if err != nil {
+			return nil, nil, "", err
+		}
+		item := c.newItem(path, existingMeta(path))
+		if c.location.eagerStat {
+			item.prefillInfo(info)
+		}
+		items = append(items, item)
+	}
+	return prefixes, items, cursor, nil
+}
+
+func (c *container) Item(id string) (_ stow.Item, err error) {
+	defer wrapErr(&err, "get", id)
+
+	path := c.storedPath(c.itemPath(id))
+	if !c.includedPath(path) {
 		return nil, stow.ErrNotFound
 	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) && c.location.caseInsensitive {
+		folded, ferr := c.findFold(path)
+		if ferr != nil {
+			return nil, ferr
+		}
+		if folded != "" {
+			path = folded
+			info, err = os.Stat(path)
+		}
+	}
+	if os.IsNotExist(err) {
+		return nil, stow.NotFound(err)
+	}
+	if err != nil {
+		return nil, err
+	}
 	if info.IsDir() {
 		return nil, errors.New("unexpected directory")
 	}
@@ -170,28 +754,184 @@ func (c *container) Item(id string) (stow.Item, error) {
 	if err != nil {
 		return nil, err
 	}
-	item := &item{
+	return c.newItem(path, existingMeta(path)), nil
+}
+
+// Glob gets the items whose names, relative to the container, match
+// the pattern, sorted by name. The pattern is slash separated on all
+// platforms. Directories, and files that are not items, such as
+// metadata files or ignored files, are left out.
+func (c *container) Glob(pattern string) (_ []stow.Item, err error) {
+	defer wrapErr(&err, "glob", pattern)
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(c.path, filepath.FromSlash(pattern)))
+	if err != nil {
+		return nil, err
+	}
+	var items []stow.Item
+	for _, p := range paths {
+		name, err := filepath.Rel(c.path, p)
+		if err != nil {
+			return nil, err
+		}
+		if isSidecar(p) || isTemp(p) || !c.included(name) || c.ignoredPath(name) {
+			continue
+		}
+		info, err := os.Lstat(p)
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode()&os.ModeSymlink == os.ModeSymlink && c.location.followSymlinks {
+			info, err = os.Stat(p)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if info.IsDir() {
+			continue
+		}
+		items = append(items, c.newItem(p, existingMeta(p)))
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Name() < items[j].Name()
+	})
+	return items, nil
+}
+
+// ignoredPath gets whether the file with the specified name, relative
+// to the container, or any of the directories holding it, matches
+// any of the ignore globs.
+func (c *container) ignoredPath(name string) bool {
+	for {
+		if c.location.ignored(name) {
+			return true
+		}
+		dir := filepath.Dir(name)
+		if dir == "." || dir == name {
+			return false
+		}
+		name = dir
+	}
+}
+
+// findFold finds the file at path in the container ignoring the case
+// of the names of the file and the directories holding it, where no
+// file has the exact name. An empty path is returned if there is no
+// such file, and an error if more than one name matches.
+func (c *container) findFold(path string) (string, error) {
+	rel, err := filepath.Rel(c.path, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", nil
+	}
+	found := c.path
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		exact := filepath.Join(found, name)
+		if _, err := os.Lstat(exact); err == nil {
+			found = exact
+			continue
+		}
+		infos, err := ioutil.ReadDir(found)
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		var matches []string
+		for _, info := range infos {
+			if strings.EqualFold(info.Name(), name) && !isSidecar(info.Name()) && !isTemp(info.Name()) {
+				matches = append(matches, info.Name())
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return "", nil
+		case 1:
+			found = filepath.Join(found, matches[0])
+		default:
+			return "", fmt.Errorf("name %s is ambiguous, it matches %s", name, strings.Join(matches, ", "))
+		}
+	}
+	if !c.includedPath(found) {
+		return "", nil
+	}
+	return found, nil
+}
+
+// itemPath gets the path of the file for the item with the specified
+// ID, or name relative to the container. Both slash and OS specific
+// separators are accepted.
+func (c *container) itemPath(id string) string {
+	path := filepath.FromSlash(id)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(c.path, path)
+	}
+	return path
+}
+
+// newItem makes an item belonging to this container for the
+// file at path, with an optional metadata file.
+func (c *container) newItem(path, metaPath string) *item {
+	return &item{
+		container:     c,
 		path:          path,
+		metaPath:      metaPath,
 		contPrefixLen: len(c.path) + 1,
 	}
-	return item, nil
+}
+
+// files gets the files of the items of the container.
+func (c *container) files() ([]os.FileInfo, error) {
+	files, err := flatdirs(c.path, c.location)
+	if err == nil && c.location.transparentDecompress {
+		files = logicalFiles(files)
+	}
+	if err != nil || c.include == "" {
+		return files, err
+	}
+	included := files[:0]
+	for _, f := range files {
+		if c.included(f.Name()) {
+			included = append(included, f)
+		}
+	}
+	return included, nil
 }
 
 // flatdirs walks the entire tree returning a list of
 // os.FileInfo for all items encountered.
-func flatdirs(path string) ([]os.FileInfo, error) {
+// If the location follows symlinks, symlinked directories are walked
+// too. Files and directories matching the ignore globs of the
+// location are skipped. If directories are nested containers, only
+// the files directly inside path are listed.
+func flatdirs(path string, l *location) ([]os.FileInfo, error) {
+	if l.followSymlinks {
+		return flatdirsFollow(path, l)
+	}
 	var list []os.FileInfo
 	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
-			return nil
-		}
 		flatname, err := filepath.Rel(path, p)
 		if err != nil {
 			return err
 		}
+		if info.IsDir() {
+			if p != path && (l.dirsAsContainers || l.ignored(flatname)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isSidecar(p) || isTemp(p) || l.ignored(flatname) {
+			return nil
+		}
 		list = append(list, fileinfo{
 			FileInfo: info,
 			name:     flatname,
@@ -204,6 +944,70 @@ func flatdirs(path string) ([]os.FileInfo, error) {
 	return list, nil
 }
 
+// flatdirsFollow walks the entire tree like flatdirs, following
+// symlinks. Directories that were already visited through another
+// path are skipped, as are broken symlinks.
+func flatdirsFollow(root string, l *location) ([]os.FileInfo, error) {
+	var (
+		list    []os.FileInfo
+		visited []os.FileInfo
+		walk    func(dir string) error
+	)
+	walk = func(dir string) error {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		for _, v := range visited {
+			if os.SameFile(v, info) {
+				return nil
+			}
+		}
+		visited = append(visited, info)
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, fi := range infos {
+			p := filepath.Join(dir, fi.Name())
+			if fi.Mode()&os.ModeSymlink == os.ModeSymlink {
+				fi, err = os.Stat(p)
+				if os.IsNotExist(err) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+			}
+			flatname, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			if fi.IsDir() {
+				if l.dirsAsContainers || l.ignored(flatname) {
+					continue
+				}
+				if err := walk(p); err != nil {
+					return err
+				}
+				continue
+			}
+			if isSidecar(p) || isTemp(p) || l.ignored(flatname) {
+				continue
+			}
+			list = append(list, fileinfo{
+				FileInfo: fi,
+				name:     flatname,
+			})
+		}
+		return nil
+	}
+	if err := walk(root); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
 type fileinfo struct {
 	os.FileInfo
 	name string
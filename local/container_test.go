@@ -1,12 +1,24 @@
 package local_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/cheekybits/is"
 	"github.com/graymeta/stow"
+	"github.com/graymeta/stow/inmem"
 	"github.com/graymeta/stow/local"
 )
 
@@ -57,6 +69,832 @@ func TestItemsPaging(t *testing.T) {
 
 	// bad cursor
 	_, _, err = container.Items("item-", "made up cursor", 10)
-	is.Equal(err, stow.ErrBadCursor)
+	is.True(errors.Is(err, stow.ErrBadCursor))
 
 }
+
+func TestPutMetadata(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("one")
+	is.NoErr(err)
+
+	md := map[string]interface{}{
+		"owner":            "me",
+		local.MetadataSize: 1000,
+	}
+	_, err = c.Put("with-meta", strings.NewReader("item"), 4, md)
+	is.NoErr(err)
+
+	items, _, err := c.Items(stow.NoPrefix, stow.CursorStart, 10)
+	is.NoErr(err)
+	is.Equal(len(items), 1)
+	is.Equal(items[0].Name(), "with-meta")
+
+	item, err := c.Item("with-meta")
+	is.NoErr(err)
+	itemMD, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(itemMD[local.MetadataUser], map[string]interface{}{"owner": "me"})
+	is.Equal(itemMD[local.MetadataSize], int64(4))
+
+	// putting again without metadata clears it
+	_, err = c.Put("with-meta", strings.NewReader("item"), 4, nil)
+	is.NoErr(err)
+	item, err = c.Item("with-meta")
+	is.NoErr(err)
+	itemMD, err = item.Metadata()
+	is.NoErr(err)
+	is.Nil(itemMD[local.MetadataUser])
+}
+
+func TestCopyItemMetadata(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	one, err := l.Container("one")
+	is.NoErr(err)
+	two, err := l.Container("two")
+	is.NoErr(err)
+
+	item, err := one.Put("item", strings.NewReader("item"), 4, map[string]interface{}{"owner": "me"})
+	is.NoErr(err)
+	_, err = stow.CopyItem(two, "copy", item)
+	is.NoErr(err)
+
+	copied, err := two.Item("copy")
+	is.NoErr(err)
+	md, err := copied.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataUser], map[string]interface{}{"owner": "me"})
+}
+
+func TestRemoveItems(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	br, ok := c.(stow.BatchRemover)
+	is.True(ok)
+	item1, err := c.Item("item1")
+	is.NoErr(err)
+	item2, err := c.Item("item2")
+	is.NoErr(err)
+	err = br.RemoveItems([]string{item1.ID(), item2.ID() + "nope", item2.ID()})
+	is.Err(err)
+	is.True(strings.Contains(err.Error(), "nope"))
+
+	items, _, err := c.Items(stow.NoPrefix, stow.CursorStart, 10)
+	is.NoErr(err)
+	is.Equal(len(items), 1)
+	is.Equal(items[0].Name(), "item3")
+}
+
+func TestStat(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	// metadata files are not counted
+	_, err = c.Put("sub/item4", strings.NewReader("item4"), 5, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+
+	_, ok := c.(stow.StatReporter)
+	is.True(ok)
+	count, total, err := stow.ContainerStat(c)
+	is.NoErr(err)
+	is.Equal(count, int64(4))
+	is.Equal(total, int64(14))
+}
+
+func TestIgnoreGlobs(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:     testDir,
+		local.ConfigIgnoreGlobs: "*.swp, .DS_Store,skip",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	for _, name := range []string{".DS_Store", "item1.swp", "sub/.item2.swp", "sub/item4", "skip/item5", "sub/skip/item6"} {
+		_, err := c.Put(name, strings.NewReader("x"), 1, map[string]interface{}{"a": "b"})
+		is.NoErr(err)
+	}
+
+	var names []string
+	err = stow.Walk(c, stow.NoPrefix, 100, func(item stow.Item, err error) error {
+		if err != nil {
+			return err
+		}
+		names = append(names, item.Name())
+		return nil
+	})
+	is.NoErr(err)
+	is.Equal(names, []string{"item1", "item2", "item3", "sub/item4"})
+
+	// ignored items can still be got directly
+	_, err = c.Item("item1.swp")
+	is.NoErr(err)
+
+	_, err = stow.Dial(local.Kind, stow.ConfigMap{
+		local.ConfigKeyPath:     testDir,
+		local.ConfigIgnoreGlobs: "[",
+	})
+	is.Err(err)
+}
+
+func TestExists(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container(testDir)
+	is.NoErr(err)
+
+	_, ok := c.(stow.Existencer)
+	is.True(ok)
+	for id, exists := range map[string]bool{
+		"three/item1":     true,
+		"three/nope":      false,
+		"three":           false,
+		"z-links/symlink": true, // broken symlinks exist
+	} {
+		ok, err := stow.ItemExists(c, id)
+		is.NoErr(err)
+		is.Equal(ok, exists)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+
+	_, err = l.Container("nope")
+	is.True(errors.Is(err, stow.ErrNotFound))
+	c, err := l.Container("three")
+	is.NoErr(err)
+	_, err = c.Item("nope")
+	is.True(errors.Is(err, stow.ErrNotFound))
+	is.True(errors.Is(err, os.ErrNotExist))
+	err = c.RemoveItem(filepath.Join(testDir, "three", "nope"))
+	is.True(errors.Is(err, stow.ErrNotFound))
+}
+
+func TestEagerStat(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	for _, eager := range []string{"true", "false"} {
+		cfg := stow.ConfigMap{
+			local.ConfigKeyPath:   testDir,
+			local.ConfigEagerStat: eager,
+		}
+		l, err := stow.Dial(local.Kind, cfg)
+		is.NoErr(err)
+		c, err := l.CreateContainer("eager-" + eager)
+		is.NoErr(err)
+		_, err = c.Put("first", strings.NewReader("first"), 5, nil)
+		is.NoErr(err)
+		_, err = c.Put("second", strings.NewReader("second"), 6, nil)
+		is.NoErr(err)
+		items, _, err := c.Items(stow.NoPrefix, stow.CursorStart, 10)
+		is.NoErr(err)
+		is.Equal(len(items), 2)
+
+		// the first item is stat'ed once, before its file is removed
+		size, err := items[0].Size()
+		is.NoErr(err)
+		is.Equal(size, int64(5))
+		is.NoErr(os.Remove(filepath.Join(c.ID(), "first")))
+		size, err = items[0].Size()
+		is.NoErr(err)
+		is.Equal(size, int64(5))
+
+		// the second item is only stat'ed with eager stat
+		is.NoErr(os.Remove(filepath.Join(c.ID(), "second")))
+		size, err = items[1].Size()
+		if eager == "true" {
+			is.NoErr(err)
+			is.Equal(size, int64(6))
+			_, err = items[1].Metadata()
+			is.NoErr(err)
+		} else {
+			is.Err(err)
+		}
+	}
+}
+
+func TestWalkWithMetadata(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	for _, eager := range []string{"true", "false"} {
+		cfg := stow.ConfigMap{
+			local.ConfigKeyPath:   testDir,
+			local.ConfigEagerStat: eager,
+		}
+		l, err := stow.Dial(local.Kind, cfg)
+		is.NoErr(err)
+		c, err := l.Container("three")
+		is.NoErr(err)
+		_, err = c.Put("item1", strings.NewReader("3.1"), 3, map[string]interface{}{"a": "b"})
+		is.NoErr(err)
+
+		var names []string
+		err = stow.WalkWithMetadata(c, stow.NoPrefix, 2, func(item stow.Item, md map[string]interface{}) error {
+			_, listed := item.(stow.ListingMetadataer).ListingMetadata()
+			is.Equal(listed, eager == "true")
+			is.Equal(md[local.MetadataName], path.Base(item.Name()))
+			if item.Name() == "item1" {
+				is.Equal(md[local.MetadataUser], map[string]interface{}{"a": "b"})
+			}
+			names = append(names, item.Name())
+			return nil
+		})
+		is.NoErr(err)
+		is.Equal(names, []string{"item1", "item2", "item3"})
+	}
+}
+
+func TestWalkSince(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	until := time.Now().Add(-time.Hour)
+	for _, name := range []string{"item2", "item3"} {
+		is.NoErr(os.Chtimes(filepath.Join(testDir, "three", name), until, until))
+	}
+
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	var names []string
+	err = stow.WalkSince(c, stow.NoPrefix, until, func(item stow.Item, err error) error {
+		if err != nil {
+			return err
+		}
+		names = append(names, item.Name())
+		return nil
+	})
+	is.NoErr(err)
+	is.Equal(names, []string{"item1"})
+
+	items, cursor, err := c.(stow.SinceLister).ItemsSince("item", until.Add(-time.Minute), stow.CursorStart, 2)
+	is.NoErr(err)
+	is.Equal(len(items), 2)
+	lastMod, err := items[1].LastMod()
+	is.NoErr(err)
+	is.True(lastMod.After(until.Add(-time.Minute)))
+	items, cursor, err = c.(stow.SinceLister).ItemsSince("item", until.Add(-time.Minute), cursor, 2)
+	is.NoErr(err)
+	is.Equal(len(items), 1)
+	is.True(stow.IsCursorEnd(cursor))
+}
+
+func TestPrefixes(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	for _, name := range []string{"sub/a", "sub/b/c", "sub.txt"} {
+		_, err = c.Put(name, strings.NewReader(name), int64(len(name)), map[string]interface{}{"a": "b"})
+		is.NoErr(err)
+	}
+
+	pl, ok := c.(stow.PrefixLister)
+	is.True(ok)
+	list := func(prefix string, count int) ([]string, []string) {
+		var prefixes, names []string
+		cursor := stow.CursorStart
+		for {
+			ps, items, next, err := pl.Prefixes(prefix, "/", cursor, count)
+			is.NoErr(err)
+			is.True(len(ps)+len(items) <= count)
+			prefixes = append(prefixes, ps...)
+			for _, item := range items {
+				names = append(names, item.Name())
+			}
+			if next == stow.CursorStart {
+				return prefixes, names
+			}
+			cursor = next
+		}
+	}
+	for _, count := range []int{1, 2, 10} {
+		prefixes, names := list(stow.NoPrefix, count)
+		is.Equal(prefixes, []string{"sub/"})
+		is.Equal(names, []string{"item1", "item2", "item3", "sub.txt"})
+		prefixes, names = list("sub/", count)
+		is.Equal(prefixes, []string{"sub/b/"})
+		is.Equal(names, []string{"sub/a"})
+	}
+	prefixes, names := list("su", 10)
+	is.Equal(prefixes, []string{"sub/"})
+	is.Equal(names, []string{"sub.txt"})
+	prefixes, names = list("nope/", 10)
+	is.Equal(len(prefixes), 0)
+	is.Equal(len(names), 0)
+
+	_, _, _, err = pl.Prefixes(stow.NoPrefix, "/", "nope", 10)
+	is.True(errors.Is(err, stow.ErrBadCursor))
+	_, _, _, err = pl.Prefixes(stow.NoPrefix, ",", stow.CursorStart, 10)
+	is.True(stow.IsNotSupported(err))
+}
+
+func TestSortItems(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:   testDir,
+		local.ConfigSortItems: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.CreateContainer("sorted")
+	is.NoErr(err)
+	for _, name := range []string{"a/b", "a.txt", "a-c"} {
+		_, err = c.Put(name, strings.NewReader(name), int64(len(name)), nil)
+		is.NoErr(err)
+	}
+
+	for _, count := range []int{1, 10} {
+		var names []string
+		err = stow.Walk(c, stow.NoPrefix, count, func(item stow.Item, err error) error {
+			if err != nil {
+				return err
+			}
+			names = append(names, item.Name())
+			return nil
+		})
+		is.NoErr(err)
+		// the directory a is walked before a-c and a.txt, but sorts after them
+		is.Equal(names, []string{"a-c", "a.txt", "a/b"})
+	}
+}
+
+func TestMove(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	_, err = c.Put("item4", strings.NewReader("item4"), 5, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+
+	_, ok := c.(stow.Mover)
+	is.True(ok)
+	item, err := stow.MoveItem(c, "item4", "sub/moved")
+	is.NoErr(err)
+	is.Equal(item.Name(), "sub/moved")
+	for _, name := range []string{"item4", "item4" + local.MetadataFileExt} {
+		_, err = os.Stat(filepath.Join(testDir, "three", name))
+		is.True(os.IsNotExist(err))
+	}
+	item, err = c.Item("sub/moved")
+	is.NoErr(err)
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataUser], map[string]interface{}{"a": "b"})
+
+	// moving over an item replaces its metadata
+	item, err = stow.MoveItem(c, "item1", "sub/moved")
+	is.NoErr(err)
+	md, err = item.Metadata()
+	is.NoErr(err)
+	is.Nil(md[local.MetadataUser])
+	_, err = os.Stat(filepath.Join(testDir, "three", "sub", "moved"+local.MetadataFileExt))
+	is.True(os.IsNotExist(err))
+
+	_, err = stow.MoveItem(c, "nope", "sub/nope")
+	is.True(errors.Is(err, stow.ErrNotFound))
+}
+
+func TestMetaIndex(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:   testDir,
+		local.ConfigMetaIndex: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.CreateContainer("indexed")
+	is.NoErr(err)
+
+	var wg sync.WaitGroup
+	for n := 0; n < 10; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			name := fmt.Sprintf("dir/item%d", n)
+			_, err := c.Put(name, strings.NewReader(name), int64(len(name)), map[string]interface{}{"n": strconv.Itoa(n)})
+			is.NoErr(err)
+		}(n)
+	}
+	wg.Wait()
+	_, err = os.Stat(filepath.Join(c.ID(), "dir", "item0"+local.MetadataFileExt))
+	is.True(os.IsNotExist(err))
+
+	items, _, err := c.Items(stow.NoPrefix, stow.CursorStart, 100)
+	is.NoErr(err)
+	is.Equal(len(items), 10)
+	for _, item := range items {
+		md, err := item.Metadata()
+		is.NoErr(err)
+		user := md[local.MetadataUser].(map[string]interface{})
+		is.Equal(user["n"], strings.TrimPrefix(item.Name(), "dir/item"))
+	}
+
+	moved, err := stow.MoveItem(c, items[0].ID(), "moved")
+	is.NoErr(err)
+	md, err := moved.Metadata()
+	is.NoErr(err)
+	is.OK(md[local.MetadataUser])
+	is.NoErr(c.RemoveItem(moved.ID()))
+	b, err := ioutil.ReadFile(filepath.Join(c.ID(), local.MetaIndexFile))
+	is.NoErr(err)
+	is.False(strings.Contains(string(b), "moved"))
+}
+
+func TestMetaIndexChanged(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:   testDir,
+		local.ConfigMetaIndex: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.CreateContainer("indexed")
+	is.NoErr(err)
+	_, err = c.Put("item", strings.NewReader("item"), 4, map[string]interface{}{"n": "1"})
+	is.NoErr(err)
+
+	userMeta := func() interface{} {
+		item, err := c.Item("item")
+		is.NoErr(err)
+		md, err := item.Metadata()
+		is.NoErr(err)
+		return md[local.MetadataUser]
+	}
+	is.Equal(userMeta(), map[string]interface{}{"n": "1"})
+	// changing the metadata returned does not change the index
+	userMeta().(map[string]interface{})["n"] = "changed"
+	is.Equal(userMeta(), map[string]interface{}{"n": "1"})
+
+	item, err := c.Item("item")
+	is.NoErr(err)
+	is.NoErr(item.(stow.MetadataSetter).SetMetadata(map[string]interface{}{"n": "2"}))
+	is.Equal(userMeta(), map[string]interface{}{"n": "2"})
+
+	// the index file is read again once another process changes it,
+	// even in place and keeping its size
+	path := filepath.Join(c.ID(), local.MetaIndexFile)
+	info, err := os.Stat(path)
+	is.NoErr(err)
+	b, err := ioutil.ReadFile(path)
+	is.NoErr(err)
+	b = bytes.Replace(b, []byte(`"2"`), []byte(`"3"`), 1)
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	is.NoErr(err)
+	_, err = f.Write(b)
+	is.NoErr(err)
+	is.NoErr(f.Close())
+	mtime := info.ModTime().Add(time.Second)
+	is.NoErr(os.Chtimes(path, mtime, mtime))
+	is.Equal(userMeta(), map[string]interface{}{"n": "3"})
+}
+
+func TestMigrateToMetaIndex(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	l, err := stow.Dial(local.Kind, stow.ConfigMap{local.ConfigKeyPath: testDir})
+	is.NoErr(err)
+	c, err := l.Container(filepath.Join(testDir, "one"))
+	is.NoErr(err)
+	_, err = c.Put("a/b", strings.NewReader("b"), 1, map[string]interface{}{"key": "value"})
+	is.NoErr(err)
+
+	is.NoErr(local.MigrateToMetaIndex(c))
+	_, err = os.Stat(filepath.Join(c.ID(), "a", "b"+local.MetadataFileExt))
+	is.True(os.IsNotExist(err))
+
+	l, err = stow.Dial(local.Kind, stow.ConfigMap{
+		local.ConfigKeyPath:   testDir,
+		local.ConfigMetaIndex: "true",
+	})
+	is.NoErr(err)
+	c, err = l.Container(filepath.Join(testDir, "one"))
+	is.NoErr(err)
+	item, err := c.Item("a/b")
+	is.NoErr(err)
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataUser].(map[string]interface{})["key"], "value")
+}
+
+func TestErrors(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	l, err := stow.Dial(local.Kind, stow.ConfigMap{local.ConfigKeyPath: testDir})
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	_, err = c.Item("missing")
+	var serr *stow.Error
+	is.True(errors.As(err, &serr))
+	is.Equal(serr.Op, "get")
+	is.Equal(serr.Kind, local.Kind)
+	is.Equal(serr.Name, "missing")
+	is.True(errors.Is(err, stow.ErrNotFound))
+	is.True(errors.Is(err, os.ErrNotExist))
+
+	// errors are only wrapped once
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.(stow.ContextPutter).PutContext(ctx, "ctx", strings.NewReader("ctx"), 3, nil)
+	is.True(errors.As(err, &serr))
+	is.Equal(serr.Op, "put")
+	is.Equal(serr.Err, context.Canceled)
+	is.Equal(err.Error(), `stow: put "ctx" (local): context canceled`)
+}
+
+func TestGlob(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		"path":                  testDir,
+		local.ConfigIgnoreGlobs: "skipped",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	_, err = c.Put("config-b.yaml", strings.NewReader("b"), 1, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+	_, err = c.Put("config-a.yaml", strings.NewReader("a"), 1, nil)
+	is.NoErr(err)
+	_, err = c.Put("nested/config-c.yaml", strings.NewReader("c"), 1, nil)
+	is.NoErr(err)
+	is.NoErr(os.MkdirAll(filepath.Join(testDir, "three", "skipped"), 0777))
+	is.NoErr(ioutil.WriteFile(filepath.Join(testDir, "three", "skipped", "config-d.yaml"), []byte("d"), 0666))
+
+	names := func(items []stow.Item) []string {
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name())
+		}
+		return names
+	}
+	items, err := stow.Glob(c, "config-*.yaml")
+	is.NoErr(err)
+	is.Equal(names(items), []string{"config-a.yaml", "config-b.yaml"})
+	md, err := items[1].Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataUser], map[string]interface{}{"a": "b"})
+
+	// patterns are slash separated, and ignored files are left out
+	items, err = stow.Glob(c, "*/config-*.yaml")
+	is.NoErr(err)
+	is.Equal(names(items), []string{"nested/config-c.yaml"})
+
+	// directories are not items
+	items, err = stow.Glob(c, "*")
+	is.NoErr(err)
+	is.Equal(names(items), []string{"config-a.yaml", "config-b.yaml", "item1", "item2", "item3"})
+
+	_, err = stow.Glob(c, "config-[")
+	is.True(errors.Is(err, path.ErrBadPattern))
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		"path":                      testDir,
+		local.ConfigCaseInsensitive: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	_, err = c.Put("Photos/IMG_0001.JPG", strings.NewReader("img"), 3, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+
+	item, err := c.Item("photos/img_0001.jpg")
+	is.NoErr(err)
+	is.Equal(item.Name(), "Photos/IMG_0001.JPG")
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataUser], map[string]interface{}{"a": "b"})
+	item, err = c.Item("ITEM1")
+	is.NoErr(err)
+	is.Equal(item.Name(), "item1")
+	_, err = c.Item("missing")
+	is.True(errors.Is(err, stow.ErrNotFound))
+
+	// exact names are found even if others match ignoring case
+	_, err = c.Put("Item1", strings.NewReader("Item1"), 5, nil)
+	is.NoErr(err)
+	item, err = c.Item("Item1")
+	is.NoErr(err)
+	is.Equal(item.Name(), "Item1")
+	if _, err := os.Stat(filepath.Join(testDir, "three", "ITEM1")); err == nil {
+		// the filesystem ignores case itself
+		return
+	}
+	_, err = c.Item("ITEM1")
+	is.Err(err)
+	is.False(errors.Is(err, stow.ErrNotFound))
+
+	// without the config, names are exact
+	l, err = stow.Dial(local.Kind, stow.ConfigMap{"path": testDir})
+	is.NoErr(err)
+	c, err = l.Container("three")
+	is.NoErr(err)
+	_, err = c.Item("photos/img_0001.jpg")
+	is.True(errors.Is(err, stow.ErrNotFound))
+}
+
+func TestItemsOrdered(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	l, err := stow.Dial(local.Kind, stow.ConfigMap{"path": testDir})
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	_, err = c.Put("dir/item4", strings.NewReader("3.4"), 3, nil)
+	is.NoErr(err)
+	now := time.Now()
+	for i, name := range []string{"item3", "item1", "dir/item4", "item2"} {
+		mtime := now.Add(time.Duration(i) * time.Minute)
+		is.NoErr(os.Chtimes(filepath.Join(testDir, "three", filepath.FromSlash(name)), mtime, mtime))
+	}
+	list := func(prefix string, order stow.Order) []string {
+		var names []string
+		cursor := stow.CursorStart
+		for {
+			items, next, err := c.(stow.OrderedLister).ItemsOrdered(prefix, order, cursor, 3)
+			is.NoErr(err)
+			for _, item := range items {
+				names = append(names, item.Name())
+			}
+			if stow.IsCursorEnd(next) {
+				return names
+			}
+			cursor = next
+		}
+	}
+	is.Equal(list("", stow.NameAsc), []string{"dir/item4", "item1", "item2", "item3"})
+	is.Equal(list("", stow.NameDesc), []string{"item3", "item2", "item1", "dir/item4"})
+	is.Equal(list("", stow.ModTimeAsc), []string{"item3", "item1", "dir/item4", "item2"})
+	is.Equal(list("", stow.ModTimeDesc), []string{"item2", "dir/item4", "item1", "item3"})
+	is.Equal(list("item", stow.ModTimeDesc), []string{"item2", "item1", "item3"})
+	is.Equal(list("dir/", stow.NameDesc), []string{"dir/item4"})
+
+	_, _, err = c.(stow.OrderedLister).ItemsOrdered("", stow.NameDesc, "missing", 3)
+	is.True(errors.Is(err, stow.ErrBadCursor))
+}
+
+// stringMetadataContainer rejects metadata values that are not
+// strings, like the s3 backend.
+type stringMetadataContainer struct {
+	stow.Container
+}
+
+func (c *stringMetadataContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+	for key, value := range metadata {
+		if _, ok := value.(string); !ok {
+			return nil, fmt.Errorf("value of key '%s' in metadata must be of type string", key)
+		}
+	}
+	return c.Container.Put(name, r, size, metadata)
+}
+
+func TestCopyItemToOtherBackend(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	l, err := stow.Dial(local.Kind, stow.ConfigMap{local.ConfigKeyPath: testDir})
+	is.NoErr(err)
+	src, err := l.Container(filepath.Join(testDir, "one"))
+	is.NoErr(err)
+	item, err := src.Put("a", strings.NewReader("a"), 1, map[string]interface{}{"owner": "me"})
+	is.NoErr(err)
+
+	mem, err := stow.Dial(inmem.Kind, stow.ConfigMap{})
+	is.NoErr(err)
+	memc, err := mem.CreateContainer("dst")
+	is.NoErr(err)
+	dst := &stringMetadataContainer{Container: memc}
+
+	copied, err := stow.CopyItem(dst, "copy", item)
+	is.NoErr(err)
+	md, err := copied.Metadata()
+	is.NoErr(err)
+	is.Equal(md, map[string]interface{}{"owner": "me"})
+}
+
+func TestCopyContainerToOtherBackend(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	l, err := stow.Dial(local.Kind, stow.ConfigMap{local.ConfigKeyPath: testDir})
+	is.NoErr(err)
+	src, err := l.Container(filepath.Join(testDir, "one"))
+	is.NoErr(err)
+	_, err = src.Put("a", strings.NewReader("a"), 1, map[string]interface{}{"owner": "me"})
+	is.NoErr(err)
+	_, err = src.Put("b", strings.NewReader("b"), 1, nil)
+	is.NoErr(err)
+
+	mem, err := stow.Dial(inmem.Kind, stow.ConfigMap{})
+	is.NoErr(err)
+	memc, err := mem.CreateContainer("dst")
+	is.NoErr(err)
+	dst := &stringMetadataContainer{Container: memc}
+
+	n, err := stow.CopyContainer(dst, src, 2)
+	is.NoErr(err)
+	is.Equal(n, 2)
+	copied, err := memc.Item("a")
+	is.NoErr(err)
+	md, err := copied.Metadata()
+	is.NoErr(err)
+	is.Equal(md, map[string]interface{}{"owner": "me"})
+	copied, err = memc.Item("b")
+	is.NoErr(err)
+	md, err = copied.Metadata()
+	is.NoErr(err)
+	is.Equal(len(md), 0)
+}
@@ -0,0 +1,289 @@
+package local
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/graymeta/stow"
+	"github.com/klauspost/compress/zstd"
+)
+
+// The extensions of the compressed files that are decompressed
+// transparently.
+const (
+	gzipExt = ".gz"
+	zstdExt = ".zst"
+)
+
+// compressedExts are the extensions of the compressed files, in the
+// order in which the files holding an item are looked for.
+var compressedExts = []string{gzipExt, zstdExt}
+
+// compressedExt gets the extension of the compressed file at path, or
+// an empty string if it is not compressed.
+func compressedExt(path string) string {
+	for _, ext := range compressedExts {
+		if strings.HasSuffix(path, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// compressed gets the extension of the item if it is a compressed
+// file, whose contents are decompressed transparently, or an empty
+// string if it is not.
+func (i *item) compressed() string {
+	if i.container == nil || !i.container.location.transparentDecompress {
+		return ""
+	}
+	return compressedExt(i.path)
+}
+
+// logicalPath gets the path of the item as it is named, which for a
+// compressed file leaves out the extension.
+func (i *item) logicalPath() string {
+	return strings.TrimSuffix(i.path, i.compressed())
+}
+
+// open opens the item for reading, decompressing the contents of a
+// compressed file.
+func (i *item) open() (io.ReadCloser, error) {
+	f, err := i.openFile()
+	if err != nil {
+		return nil, err
+	}
+	return i.decompress(f)
+}
+
+// decompress gets a reader of the contents of the opened file of the
+// item, which are decompressed if it is a compressed file, and which
+// closes the file.
+func (i *item) decompress(f readFile) (io.ReadCloser, error) {
+	switch i.compressed() {
+	case gzipExt:
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &gzipFile{Reader: zr, f: f}, nil
+	case zstdExt:
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &zstdFile{Decoder: zr, f: f}, nil
+	}
+	return f, nil
+}
+
+// gzipFile reads the gunzipped contents of a file, and closes both
+// the gzip reader and the file.
+type gzipFile struct {
+	*gzip.Reader
+	f readFile
+}
+
+func (r *gzipFile) Close() error {
+	err := r.Reader.Close()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// zstdFile reads the decompressed contents of a zstd compressed file,
+// and closes both the decoder and the file.
+type zstdFile struct {
+	*zstd.Decoder
+	f readFile
+}
+
+func (r *zstdFile) Close() error {
+	r.Decoder.Close()
+	return r.f.Close()
+}
+
+// gzipSize gets the uncompressed size of the gzipped file at path
+// from its trailer, which holds the size modulo 4GiB, so the size of
+// larger contents is wrong.
+func gzipSize(path string) (int64, error) {
+	f, err := os.Open(longPath(path))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(-4, io.SeekEnd); err != nil {
+		return 0, err
+	}
+	var size uint32
+	if err := binary.Read(f, binary.LittleEndian, &size); err != nil {
+		return 0, err
+	}
+	return int64(size), nil
+}
+
+// zstdSize gets the uncompressed size of the zstd compressed file at
+// path from the header of its first frame or, if the header leaves
+// out the size, by decompressing the contents. The header holds the
+// size of its own frame, so the size of contents compressed into
+// several frames that have sizes is wrong.
+func zstdSize(path string) (int64, error) {
+	f, err := os.Open(longPath(path))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	b := make([]byte, zstd.HeaderMaxSize)
+	n, err := io.ReadFull(f, b)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, err
+	}
+	var h zstd.Header
+	if err := h.Decode(b[:n]); err != nil {
+		return 0, err
+	}
+	if h.HasFCS {
+		return int64(h.FrameContentSize), nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+	return io.Copy(ioutil.Discard, zr)
+}
+
+// storedPath gets the path of the file holding the item at path,
+// which is a compressed file if the contents are decompressed
+// transparently and only the compressed file exists.
+func (c *container) storedPath(path string) string {
+	if !c.location.transparentDecompress || compressedExt(path) != "" {
+		return path
+	}
+	if _, err := os.Lstat(longPath(path)); !os.IsNotExist(err) {
+		return path
+	}
+	for _, ext := range compressedExts {
+		if _, err := os.Lstat(longPath(path + ext)); err == nil {
+			return path + ext
+		}
+	}
+	return path
+}
+
+// otherPaths gets the paths of the other files that may hold the item
+// at path: the plain file and the compressed files with its name.
+func otherPaths(path string) []string {
+	logical := strings.TrimSuffix(path, compressedExt(path))
+	var others []string
+	for _, p := range append([]string{logical}, logical+gzipExt, logical+zstdExt) {
+		if p != path {
+			others = append(others, p)
+		}
+	}
+	return others
+}
+
+// logicalFiles leaves out the compressed files for which there is a
+// plain file with the same name, or a compressed file looked for
+// before them, so that each name is listed once.
+func logicalFiles(files []os.FileInfo) []os.FileInfo {
+	names := make(map[string]bool, len(files))
+	for _, f := range files {
+		names[f.Name()] = true
+	}
+	logical := files[:0]
+	for _, f := range files {
+		if !shadowed(names, f.Name()) {
+			logical = append(logical, f)
+		}
+	}
+	return logical
+}
+
+// shadowed gets whether the item in the compressed file with the
+// specified name is held by another of the named files.
+func shadowed(names map[string]bool, name string) bool {
+	ext := compressedExt(name)
+	if ext == "" {
+		return false
+	}
+	logical := strings.TrimSuffix(name, ext)
+	if names[logical] {
+		return true
+	}
+	for _, other := range compressedExts {
+		if other == ext {
+			return false
+		}
+		if names[logical+other] {
+			return true
+		}
+	}
+	return false
+}
+
+// compressPut gets the path to put the item at path to, and the
+// contents to write and their size, along with the paths of the other
+// files with its name, which must be removed once the item is put.
+// The contents are compressed into a ".gz" or ".zst" file, of unknown
+// size, if the metadata asks for gzip or zstd compression. The
+// returned func stops the compression, once the contents are no
+// longer read.
+func compressPut(path string, r io.Reader, size int64, metadata map[string]interface{}) (string, io.Reader, int64, []string, func()) {
+	var ext string
+	var newWriter func(io.Writer) (io.WriteCloser, error)
+	switch v, _ := metadata[stow.MetadataCompression].(string); v {
+	case "gzip":
+		ext = gzipExt
+		newWriter = func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		}
+	case "zstd":
+		ext = zstdExt
+		newWriter = func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w)
+		}
+	default:
+		return path, r, size, otherPaths(path), func() {}
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		zw, err := newWriter(pw)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		n, err := io.Copy(zw, r)
+		if err == nil && size >= 0 && n != size {
+			err = errors.New("bad size")
+		}
+		if cerr := zw.Close(); err == nil {
+			err = cerr
+		}
+		pw.CloseWithError(err)
+	}()
+	return path + ext, pr, -1, otherPaths(path + ext), func() {
+		pr.CloseWithError(errors.New("put finished"))
+	}
+}
+
+// removeOther removes the item in the file at path, if there is one,
+// after the item with the same name was put to another file.
+func (c *container) removeOther(path string) error {
+	if _, err := os.Lstat(longPath(path)); err != nil {
+		return nil
+	}
+	return c.RemoveItem(path)
+}
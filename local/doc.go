@@ -21,6 +21,10 @@ Of a directory, methods of local.container allow the retrieval of its name (ID o
 
 Additional local.container methods allow the removal of a file (RemoveItem) and the creation of one (Put).
 
+User metadata given to Put is stored as JSON in a file next to the item, named with the MetadataFileExt extension. These files are not listed as items, and their contents are available under the MetadataUser key of the item metadata.
+
+Tags set with SetTags are stored apart from the user metadata, as JSON in another file next to the item, named with the TagsFileExt extension, which is not listed as an item either.
+
 Item
 
 Methods of local.Item allow the retrieval of quite detailed information. They are:
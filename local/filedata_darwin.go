@@ -43,8 +43,16 @@ func getFileMetadata(path string, info os.FileInfo) map[string]interface{} {
 	}
 
 	if stat := info.Sys().(*syscall.Stat_t); stat != nil {
-		m["atime"] = time.Unix(int64(stat.Atimespec.Sec), int64(stat.Atimespec.Nsec)).Format(time.RFC3339Nano)
+		if stat.Atimespec.Sec != 0 || stat.Atimespec.Nsec != 0 {
+			m[MetadataAccessTime] = time.Unix(int64(stat.Atimespec.Sec), int64(stat.Atimespec.Nsec)).Format(time.RFC3339Nano)
+		}
 		m["mtime"] = time.Unix(int64(stat.Mtimespec.Sec), int64(stat.Mtimespec.Nsec)).Format(time.RFC3339Nano)
+		m[MetadataChangeTime] = time.Unix(int64(stat.Ctimespec.Sec), int64(stat.Ctimespec.Nsec)).Format(time.RFC3339Nano)
+		if stat.Birthtimespec.Sec != 0 || stat.Birthtimespec.Nsec != 0 {
+			m[MetadataCreatedTime] = time.Unix(int64(stat.Birthtimespec.Sec), int64(stat.Birthtimespec.Nsec)).Format(time.RFC3339Nano)
+		}
+		m[MetadataDevice] = uint64(uint32(stat.Dev))
+		m[MetadataAllocatedSize] = int64(stat.Blocks) * 512
 		m["uid"] = stat.Uid
 		m["gid"] = stat.Gid
 	}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"syscall"
 	"time"
+
+	"golang.org/x/sys/unix"
 )
 
 func getFileMetadata(path string, info os.FileInfo) map[string]interface{} {
@@ -43,8 +45,16 @@ func getFileMetadata(path string, info os.FileInfo) map[string]interface{} {
 	}
 
 	if stat := info.Sys().(*syscall.Stat_t); stat != nil {
-		m["atime"] = time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec)).Format(time.RFC3339Nano)
+		if stat.Atim.Sec != 0 || stat.Atim.Nsec != 0 {
+			m[MetadataAccessTime] = time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec)).Format(time.RFC3339Nano)
+		}
 		m["mtime"] = time.Unix(int64(stat.Mtim.Sec), int64(stat.Mtim.Nsec)).Format(time.RFC3339Nano)
+		m[MetadataChangeTime] = time.Unix(int64(stat.Ctim.Sec), int64(stat.Ctim.Nsec)).Format(time.RFC3339Nano)
+		if btime, ok := birthTime(path, info); ok {
+			m[MetadataCreatedTime] = btime.Format(time.RFC3339Nano)
+		}
+		m[MetadataDevice] = uint64(stat.Dev)
+		m[MetadataAllocatedSize] = int64(stat.Blocks) * 512
 		m["uid"] = stat.Uid
 		m["gid"] = stat.Gid
 	}
@@ -57,6 +67,24 @@ func getFileMetadata(path string, info os.FileInfo) map[string]interface{} {
 	return m
 }
 
+// birthTime gets the creation time of the file at path with statx,
+// following it only if info does. It is not found on kernels without
+// statx, or on filesystems that do not record it.
+func birthTime(path string, info os.FileInfo) (time.Time, bool) {
+	flags := unix.AT_STATX_SYNC_AS_STAT
+	if info.Mode()&os.ModeSymlink == os.ModeSymlink {
+		flags |= unix.AT_SYMLINK_NOFOLLOW
+	}
+	var stx unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, path, flags, unix.STATX_BTIME, &stx); err != nil {
+		return time.Time{}, false
+	}
+	if stx.Mask&unix.STATX_BTIME == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec)), true
+}
+
 type inodeinfo struct {
 	// NLink is the number of times this file is linked to by
 	// hardlinks.
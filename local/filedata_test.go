@@ -4,6 +4,7 @@ import (
 	"os"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/cheekybits/is"
 )
@@ -30,7 +31,10 @@ func TestFileData(t *testing.T) {
 	is.False(data["is_hardlink"])
 	is.False(data["is_symlink"])
 	is.OK(data["size"])
-
+	atime, ok := data[MetadataAccessTime].(string)
+	is.True(ok)
+	_, err = time.Parse(time.RFC3339Nano, atime)
+	is.NoErr(err)
 }
 
 func TestDotFile(t *testing.T) {
@@ -0,0 +1,61 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+)
+
+func TestFileDataDevice(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "stow-device")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	is.NoErr(ioutil.WriteFile(a, []byte("a"), 0644))
+	is.NoErr(os.Link(a, b))
+
+	// hardlinks share the device and inode
+	var ids [2][2]interface{}
+	for n, path := range []string{a, b} {
+		info, err := os.Stat(path)
+		is.NoErr(err)
+		data := getFileMetadata(path, info)
+		_, ok := data[MetadataDevice].(uint64)
+		is.True(ok)
+		ids[n] = [2]interface{}{data[MetadataDevice], data[MetadataINode].(*inodeinfo).Ino}
+	}
+	is.Equal(ids[0], ids[1])
+}
+
+func TestFileDataTimes(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "stow-times")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a")
+	before := time.Now().Add(-time.Second)
+	is.NoErr(ioutil.WriteFile(path, []byte("a"), 0644))
+	info, err := os.Stat(path)
+	is.NoErr(err)
+	data := getFileMetadata(path, info)
+
+	ctime, err := time.Parse(time.RFC3339Nano, data[MetadataChangeTime].(string))
+	is.NoErr(err)
+	is.True(ctime.After(before))
+	// creation times depend on the filesystem
+	if v, ok := data[MetadataCreatedTime]; ok {
+		btime, err := time.Parse(time.RFC3339Nano, v.(string))
+		is.NoErr(err)
+		is.True(btime.After(before))
+	}
+}
@@ -29,8 +29,13 @@ func getFileMetadata(path string, info os.FileInfo) map[string]interface{} {
 	}
 
 	if stat := info.Sys().(*syscall.Win32FileAttributeData); stat != nil {
-		m["atime"] = time.Unix(0, stat.LastAccessTime.Nanoseconds()).Format(time.RFC3339Nano)
+		if stat.LastAccessTime.Nanoseconds() != 0 {
+			m[MetadataAccessTime] = time.Unix(0, stat.LastAccessTime.Nanoseconds()).Format(time.RFC3339Nano)
+		}
 		m["mtime"] = time.Unix(0, stat.LastWriteTime.Nanoseconds()).Format(time.RFC3339Nano)
+		if stat.CreationTime.Nanoseconds() != 0 {
+			m[MetadataCreatedTime] = time.Unix(0, stat.CreationTime.Nanoseconds()).Format(time.RFC3339Nano)
+		}
 	}
 
 	ext := filepath.Ext(info.Name())
@@ -0,0 +1,38 @@
+//go:build go1.16
+// +build go1.16
+
+package local_test
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+	"github.com/graymeta/stow/local"
+)
+
+func TestFS(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	l, err := stow.Dial(local.Kind, stow.ConfigMap{"path": testDir})
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	_, err = c.Put("dir/nested", strings.NewReader("nested"), 6, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+
+	fsys := stow.FS(c)
+	is.NoErr(fstest.TestFS(fsys, "item1", "item2", "item3", "dir/nested"))
+	b, err := fs.ReadFile(fsys, "dir/nested")
+	is.NoErr(err)
+	is.Equal(string(b), "nested")
+	// metadata files are not listed
+	entries, err := fs.ReadDir(fsys, "dir")
+	is.NoErr(err)
+	is.Equal(len(entries), 1)
+}
@@ -1,14 +1,24 @@
 package local
 
 import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"io"
 	"io/ioutil"
+	"math"
+	"mime"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/graymeta/stow"
 )
 
 // Metadata constants describe the metadata available
@@ -27,33 +37,185 @@ const (
 	MetadataIsSymlink  = "is_symlink"
 	MetadataLink       = "link"
 	MetadataUser       = "user_data"
+	// MetadataAccessTime is the time the file was last accessed,
+	// formatted as time.RFC3339Nano. It is omitted when the
+	// filesystem does not record access times.
+	MetadataAccessTime = "atime"
+	// MetadataXattr is a map of the names of the extended attributes
+	// of the file to their values, as strings. It is only present if
+	// extended attributes are enabled with ConfigReadXattrs, and they
+	// could be read.
+	MetadataXattr = "xattr"
+	// MetadataDevice is the ID of the device holding the file, as a
+	// uint64. Together with the inode number in MetadataINode it
+	// identifies the file, so hardlinks to the same file can be told
+	// apart from copies. It is omitted on Windows.
+	MetadataDevice = "device"
+	// MetadataAllocatedSize is the number of bytes allocated on disk
+	// for the file, as an int64. It is less than MetadataSize for
+	// sparse files, and may be more for small files. It is omitted on
+	// Windows.
+	MetadataAllocatedSize = "allocated_size"
+	// MetadataCreatedTime is the time the file was created, formatted
+	// as time.RFC3339Nano. It is omitted when the platform or the
+	// filesystem does not record creation times, which on Linux need
+	// the statx system call.
+	MetadataCreatedTime = "btime"
+	// MetadataChangeTime is the time the inode of the file last
+	// changed, such as when its contents, permissions or owner did,
+	// formatted as time.RFC3339Nano. It is omitted on Windows.
+	MetadataChangeTime = "ctime"
 )
 
+// MetadataFileExt is the extension of the file next to an Item
+// that holds its user metadata.
 const MetadataFileExt = "._meta"
 
+// TagsFileExt is the extension of the file next to an Item
+// that holds its tags.
+const TagsFileExt = "._tags"
+
+// isSidecar gets whether the file at path holds the metadata or
+// the tags of another file, rather than being an item itself.
+func isSidecar(path string) bool {
+	return strings.HasSuffix(path, MetadataFileExt) || strings.HasSuffix(path, TagsFileExt)
+}
+
+// reservedMetadata is the set of keys describing the file itself,
+// which are never stored as user metadata.
+var reservedMetadata = map[string]bool{
+	MetadataPath:          true,
+	MetadataIsDir:         true,
+	MetadataDir:           true,
+	MetadataName:          true,
+	MetadataMode:          true,
+	MetadataModeD:         true,
+	MetadataPerm:          true,
+	MetadataINode:         true,
+	MetadataSize:          true,
+	MetadataIsHardlink:    true,
+	MetadataIsSymlink:     true,
+	MetadataLink:          true,
+	MetadataUser:          true,
+	MetadataAccessTime:    true,
+	MetadataXattr:         true,
+	MetadataDevice:        true,
+	MetadataAllocatedSize: true,
+	MetadataCreatedTime:   true,
+	MetadataChangeTime:    true,
+	"mtime":               true,
+	"uid":                 true,
+	"gid":                 true,
+	"ext":                 true,
+}
+
+// userMetadata gets a copy of metadata without the reserved keys.
+// Any user metadata nested under MetadataUser, such as in the
+// metadata of another local Item, is included.
+func userMetadata(metadata map[string]interface{}) map[string]interface{} {
+	md := make(map[string]interface{}, len(metadata))
+	if user, ok := metadata[MetadataUser].(map[string]interface{}); ok {
+		for k, v := range user {
+			md[k] = v
+		}
+	}
+	for k, v := range metadata {
+		if reservedMetadata[k] {
+			continue
+		}
+		md[k] = v
+	}
+	return md
+}
+
+// PathItem is implemented by local Items, whose contents are
+// stored in a file. Callers that need the file itself, such as to
+// mmap it or pass it to another process, can type assert a stow.Item
+// to a PathItem to get its path:
+//
+//	if pi, ok := item.(local.PathItem); ok {
+//		f, err := os.Open(pi.Path())
+//	}
+type PathItem interface {
+	stow.Item
+	// Path gets the absolute path of the file of the Item.
+	Path() string
+}
+
 type item struct {
+	container     *container
 	path          string
 	metaPath      string
 	contPrefixLen int
 	infoOnce      sync.Once // protects info
 	info          os.FileInfo
 	infoErr       error
+	prefilled     bool         // the file info was set from a directory listing
+	written       *writtenFile // what Put found out writing the file, if anything
 	metadata      map[string]interface{}
+	hashLock      sync.Mutex // protects hash, hashSize and hashModTime
+	hash          string
+	hashSize      int64
+	hashModTime   time.Time
 }
 
+// ID gets the path of the file of the item, with slash separators
+// on all platforms. The ID of a compressed file that is decompressed
+// transparently leaves out the extension.
 func (i *item) ID() string {
-	return i.path
+	return filepath.ToSlash(i.logicalPath())
 }
 
 func (i *item) Name() string {
-	return filepath.ToSlash(i.path[i.contPrefixLen:])
+	return filepath.ToSlash(i.logicalPath()[i.contPrefixLen:])
+}
+
+// Container gets the container the item was got from. For items got
+// by URL, it is the container holding the file at the root of the
+// location, or the directory of the file if it is not in one.
+func (i *item) Container() (stow.Container, error) {
+	if !i.container.byURL {
+		return i.container, nil
+	}
+	l := i.container.location
+	root, ok := l.config.Config(ConfigKeyPath)
+	if !ok {
+		return nil, errors.New("missing " + ConfigKeyPath + " configuration")
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(root, i.container.path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return i.container, nil
+	}
+	name := strings.SplitN(rel, string(filepath.Separator), 2)[0]
+	return l.Container(filepath.Join(root, name))
+}
+
+// Path gets the absolute path of the file of the item.
+func (i *item) Path() string {
+	path, err := filepath.Abs(i.path)
+	if err != nil {
+		return filepath.Clean(i.path)
+	}
+	return path
 }
 
+// Size gets the size of the file, or the uncompressed size of a
+// compressed file that is decompressed transparently.
 func (i *item) Size() (int64, error) {
 	err := i.ensureInfo()
 	if err != nil {
 		return 0, err
 	}
+	switch i.compressed() {
+	case gzipExt:
+		return gzipSize(i.path)
+	case zstdExt:
+		return zstdSize(i.path)
+	}
 	return i.info.Size(), nil
 }
 
@@ -64,17 +226,246 @@ func (i *item) URL() *url.URL {
 	}
 }
 
+// SignedURL fails with an error matching stow.ErrNotSupported, as
+// there is no endpoint serving local files.
+func (i *item) SignedURL(time.Duration) (*url.URL, error) {
+	return nil, stow.NotSupported("signed urls")
+}
+
 func (i *item) ETag() (string, error) {
 	err := i.ensureInfo()
 	if err != nil {
 		return "", nil
 	}
+	if i.container != nil && i.container.location.contentETag {
+		return i.contentHash()
+	}
 	return i.info.ModTime().String(), nil
 }
 
-// Open opens the file for reading.
+// contentHash gets the hex encoded MD5 hash of the file contents.
+// The hash is cached and only recomputed once the size or the
+// modification time of the file changes. The first hash of an item
+// returned by Put is the hash of the contents it was written with, if
+// it is known, so that the file is neither stat'ed nor read again.
+func (i *item) contentHash() (string, error) {
+	i.hashLock.Lock()
+	if w := i.written; w != nil && w.hash != "" {
+		i.hash, i.hashSize, i.hashModTime = w.hash, w.info.Size(), w.info.ModTime()
+		w.hash = ""
+		i.hashLock.Unlock()
+		return i.hash, nil
+	}
+	i.hashLock.Unlock()
+	info, err := os.Stat(i.path)
+	if err != nil {
+		return "", err
+	}
+	i.hashLock.Lock()
+	defer i.hashLock.Unlock()
+	if i.hash != "" && i.hashSize == info.Size() && i.hashModTime.Equal(info.ModTime()) {
+		return i.hash, nil
+	}
+	i.hash, err = md5File(i.path)
+	if err != nil {
+		return "", err
+	}
+	i.hashSize = info.Size()
+	i.hashModTime = info.ModTime()
+	return i.hash, nil
+}
+
+// md5File gets the hex encoded MD5 hash of the contents of
+// the file at path.
+func md5File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Open opens the file for reading. The contents of a compressed file
+// that is decompressed transparently are decompressed.
 func (i *item) Open() (io.ReadCloser, error) {
-	return os.Open(i.path)
+	return i.open()
+}
+
+// OpenContext opens the file for reading. Reading fails with the
+// context error once the context is done.
+func (i *item) OpenContext(ctx context.Context) (_ io.ReadCloser, err error) {
+	defer wrapErr(&err, "open", i.Name())
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := i.open()
+	if err != nil {
+		return nil, err
+	}
+	return &ctxReadCloser{
+		ctxReader: ctxReader{ctx: ctx, r: f},
+		Closer:    f,
+	}, nil
+}
+
+// OpenIfModifiedSince opens the file for reading if its modification
+// time is after t, and gets whether it was opened.
+func (i *item) OpenIfModifiedSince(t time.Time) (_ io.ReadCloser, _ bool, err error) {
+	defer wrapErr(&err, "open", i.Name())
+
+	f, err := i.openFile()
+	if err != nil {
+		return nil, false, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, false, err
+	}
+	if !info.ModTime().After(t) {
+		f.Close()
+		return nil, false, nil
+	}
+	rc, err := i.decompress(f)
+	if err != nil {
+		return nil, false, err
+	}
+	return rc, true, nil
+}
+
+// OpenRange opens the file for reading starting at byte start and ending
+// at byte end.
+func (i *item) OpenRange(start, end uint64) (_ io.ReadCloser, err error) {
+	defer wrapErr(&err, "open", i.Name())
+
+	if end < start {
+		return nil, errors.New("bad range")
+	}
+	if i.compressed() != "" {
+		return i.openCompressedRange(start, end)
+	}
+	f, err := i.openFile()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(int64(start), io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &rangeReader{
+		Reader: io.LimitReader(f, int64(end-start+1)),
+		Closer: f,
+	}, nil
+}
+
+// OpenReaderAt opens the file for reading at any offset, and gets
+// its size. The io.ReaderAt is the opened file, which calling code
+// must close.
+func (i *item) OpenReaderAt() (_ io.ReaderAt, _ int64, err error) {
+	defer wrapErr(&err, "open", i.Name())
+
+	if i.compressed() != "" {
+		return nil, 0, stow.NotSupported("reading compressed files at offsets")
+	}
+	f, err := i.openFile()
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+// OpenRanges opens the file for reading the ranges one after another,
+// in the order given.
+func (i *item) OpenRanges(ranges []stow.Range) (_ io.ReadCloser, err error) {
+	defer wrapErr(&err, "open", i.Name())
+
+	if len(ranges) == 0 {
+		return nil, errors.New("no ranges")
+	}
+	for _, r := range ranges {
+		if r.End < r.Start {
+			return nil, errors.New("bad range")
+		}
+	}
+	if i.compressed() != "" {
+		return nil, stow.NotSupported("reading ranges of compressed files")
+	}
+	f, err := i.openFile()
+	if err != nil {
+		return nil, err
+	}
+	sections := make([]io.Reader, len(ranges))
+	for n, r := range ranges {
+		sections[n] = io.NewSectionReader(f, int64(r.Start), int64(r.End-r.Start+1))
+	}
+	return &rangeReader{
+		Reader: io.MultiReader(sections...),
+		Closer: f,
+	}, nil
+}
+
+// openCompressedRange opens a compressed file for reading the
+// decompressed contents from byte start to byte end, which are read
+// and discarded up to start.
+func (i *item) openCompressedRange(start, end uint64) (io.ReadCloser, error) {
+	rc, err := i.open()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(ioutil.Discard, rc, int64(start)); err != nil && err != io.EOF {
+		rc.Close()
+		return nil, err
+	}
+	return &rangeReader{
+		Reader: io.LimitReader(rc, int64(end-start+1)),
+		Closer: rc,
+	}, nil
+}
+
+// rangeReader reads sections of a file and closes the file.
+type rangeReader struct {
+	io.Reader
+	io.Closer
+}
+
+// ContentType gets the MIME type of the file. A type given to Put is used
+// if there is one, otherwise it is detected from the file extension or,
+// failing that, the first 512 bytes of the file.
+func (i *item) ContentType() (string, error) {
+	md, err := i.Metadata()
+	if err != nil {
+		return "", err
+	}
+	if user, ok := md[MetadataUser].(map[string]interface{}); ok {
+		if ct, ok := user[stow.MetadataContentType].(string); ok && ct != "" {
+			return ct, nil
+		}
+	}
+	if ct := mime.TypeByExtension(filepath.Ext(i.logicalPath())); ct != "" {
+		return ct, nil
+	}
+	f, err := i.open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	b := make([]byte, 512)
+	n, err := io.ReadFull(f, b)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(b[:n]), nil
 }
 
 func (i *item) LastMod() (time.Time, error) {
@@ -88,39 +479,259 @@ func (i *item) LastMod() (time.Time, error) {
 
 func (i *item) ensureInfo() error {
 	i.infoOnce.Do(func() {
-		i.info, i.infoErr = os.Lstat(i.path) // retrieve item file info
-
-		if i.infoErr != nil {
+		if i.written != nil {
+			i.loadInfo(i.written.info)
 			return
 		}
-		i.setMetadata(i.info) // merge file and metadata maps
-		var md map[string]interface{}
-		md, i.infoErr = i.readMeta()
+		// retrieve item file info
+		var info os.FileInfo
+		if i.container != nil && i.container.location.followSymlinks {
+			info, i.infoErr = os.Stat(longPath(i.path))
+		} else {
+			info, i.infoErr = os.Lstat(longPath(i.path))
+		}
+
 		if i.infoErr != nil {
 			return
 		}
-		if md != nil {
-			i.metadata[MetadataUser] = md
-		}
+		i.loadInfo(info)
 	})
 	return i.infoErr
 }
 
+// prefillInfo sets the file info of the item from a directory
+// listing, so that the file is not stat'ed again.
+func (i *item) prefillInfo(info os.FileInfo) {
+	i.infoOnce.Do(func() {
+		i.prefilled = true
+		i.loadInfo(info)
+	})
+}
+
+// loadInfo sets the file info of the item, and loads its
+// metadata. It must only be called through i.infoOnce.
+func (i *item) loadInfo(info os.FileInfo) {
+	i.info = info
+	i.setMetadata(i.info) // merge file and metadata maps
+	var md map[string]interface{}
+	md, i.infoErr = i.readMeta()
+	if i.infoErr != nil {
+		return
+	}
+	if md != nil {
+		i.metadata[MetadataUser] = md
+	}
+}
+
 func (i *item) setMetadata(info os.FileInfo) {
 	fileMetadata := getFileMetadata(i.path, info) // retrieve file metadata
+	if i.container != nil && i.container.location.readXattrs {
+		xattrs, err := readXattrs(i.path, i.container.location.followSymlinks)
+		if err == nil && xattrs != nil {
+			fileMetadata[MetadataXattr] = xattrs
+		}
+	}
 	i.metadata = fileMetadata
 }
 
 // Metadata gets stat information for the file.
-func (i *item) Metadata() (map[string]interface{}, error) {
-	err := i.ensureInfo()
+func (i *item) Metadata() (_ map[string]interface{}, err error) {
+	defer wrapErr(&err, "metadata", i.Name())
+
+	err = i.ensureInfo()
 	if err != nil {
 		return nil, err
 	}
 	return i.metadata, nil
 }
 
+// ListingMetadata gets the metadata of the item if its file info was
+// set from the directory listing, which eager stat enables, so that
+// the file is not stat'ed again.
+func (i *item) ListingMetadata() (map[string]interface{}, bool) {
+	if !i.prefilled {
+		return nil, false
+	}
+	return i.metadata, true
+}
+
+// SetMetadata replaces the user metadata of the file, which is
+// stored in its metadata file or in the metadata index of its
+// container. The file itself is never written, so its modification
+// time, and an ETag based on it or on the contents, stay the same.
+// As the contents do not change, a checksum recorded by Put under
+// stow.MetadataContentMD5 is kept unless the metadata replaces it.
+func (i *item) SetMetadata(metadata map[string]interface{}) (err error) {
+	defer wrapErr(&err, "set metadata", i.Name())
+
+	if metadata == nil {
+		return nil
+	}
+	if err := i.ensureInfo(); err != nil {
+		return err
+	}
+	if old, _ := i.metadata[MetadataUser].(map[string]interface{}); old != nil {
+		if sum, ok := old[stow.MetadataContentMD5]; ok {
+			if _, ok := metadata[stow.MetadataContentMD5]; !ok {
+				md := make(map[string]interface{}, len(metadata)+1)
+				for k, v := range metadata {
+					md[k] = v
+				}
+				md[stow.MetadataContentMD5] = sum
+				metadata = md
+			}
+		}
+	}
+	metaPath, err := i.container.writeMeta(i.path, metadata)
+	if err != nil {
+		return err
+	}
+	i.metaPath = metaPath
+	md := userMetadata(metadata)
+	if len(md) == 0 {
+		delete(i.metadata, MetadataUser)
+	} else {
+		i.metadata[MetadataUser] = md
+	}
+	return nil
+}
+
+// Tags gets the tags of the item, which are stored in its tags file,
+// separately from its metadata.
+func (i *item) Tags() (_ map[string]string, err error) {
+	defer wrapErr(&err, "tags", i.Name())
+
+	b, err := ioutil.ReadFile(longPath(i.path + TagsFileExt))
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string)
+	if err := json.Unmarshal(b, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// SetTags replaces the tags of the item in its tags file, which is
+// removed if there are no tags. The file itself is never written.
+func (i *item) SetTags(tags map[string]string) (err error) {
+	defer wrapErr(&err, "set tags", i.Name())
+
+	if _, err := os.Lstat(i.path); err != nil {
+		if os.IsNotExist(err) {
+			return stow.NotFound(err)
+		}
+		return err
+	}
+	if len(tags) == 0 {
+		return removeTags(i.path)
+	}
+	j, err := json.MarshalIndent(tags, "", "    ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(i.path+TagsFileExt, j, 0644)
+}
+
+// removeTags removes the tags file of the file at path, if any.
+func removeTags(path string) error {
+	err := os.Remove(path + TagsFileExt)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// userData gets the user metadata of the item, which is
+// stored in its metadata file.
+func (i *item) userData() (map[string]interface{}, error) {
+	if err := i.ensureInfo(); err != nil {
+		return nil, err
+	}
+	md, _ := i.metadata[MetadataUser].(map[string]interface{})
+	return md, nil
+}
+
+// StringMetadata gets the user metadata value for the key,
+// if it is a string.
+func (i *item) StringMetadata(key string) (string, bool) {
+	md, err := i.userData()
+	if err != nil {
+		return "", false
+	}
+	s, ok := md[key].(string)
+	return s, ok
+}
+
+// IntMetadata gets the user metadata value for the key, if it is
+// an integer or a string holding one. Numbers are decoded from the
+// metadata file as float64, so only whole numbers are integers.
+func (i *item) IntMetadata(key string) (int64, bool) {
+	md, err := i.userData()
+	if err != nil {
+		return 0, false
+	}
+	v := md[key]
+	if f, ok := v.(float64); ok && f != math.Trunc(f) {
+		return 0, false
+	}
+	return metadataInt(v)
+}
+
+// UnmarshalUserMetadata decodes the JSON encoding of the user
+// metadata, as it is stored, into v. Nothing is decoded if there
+// is no user metadata.
+func (i *item) UnmarshalUserMetadata(v interface{}) error {
+	b, err := i.RawUserMetadata()
+	if err != nil || b == nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// RawUserMetadata gets the JSON encoding of the user metadata of the
+// item, read from the metadata index of its container if it is enabled
+// and holds any, or else from its metadata file. Metadata files are
+// returned as they are, but the entries of the index are encoded again
+// whenever the index is updated.
+// If there is no user metadata, nil is returned.
+func (i *item) RawUserMetadata() (_ json.RawMessage, err error) {
+	defer wrapErr(&err, "metadata", i.Name())
+
+	if i.container != nil && i.container.location.metaIndex {
+		b, err := i.container.indexedRawMeta(i.path)
+		if err != nil || b != nil {
+			return b, err
+		}
+	}
+	b, err := ioutil.ReadFile(longPath(i.path + MetadataFileExt))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Delete removes the file and its metadata file.
+func (i *item) Delete() error {
+	return i.container.RemoveItem(i.path)
+}
+
+// readMeta reads the user metadata of the item from the metadata
+// index of its container if it is enabled and holds any, or else
+// from its metadata file.
 func (i *item) readMeta() (map[string]interface{}, error) {
+	if i.container != nil && i.container.location.metaIndex {
+		md, err := i.container.indexedMeta(i.path)
+		if err != nil || md != nil {
+			return md, err
+		}
+	}
 	if len(i.metaPath) == 0 {
 		return nil, nil
 	}
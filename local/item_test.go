@@ -1,6 +1,11 @@
 package local_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
 	"io/ioutil"
 	"net/url"
 	"os"
@@ -8,10 +13,12 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cheekybits/is"
 	"github.com/graymeta/stow"
 	"github.com/graymeta/stow/local"
+	"github.com/klauspost/compress/zstd"
 )
 
 func TestItemReader(t *testing.T) {
@@ -158,3 +165,822 @@ func TestItemFromURL(t *testing.T) {
 	is.NoErr(err)
 	is.Equal(item3.Name(), "f3")
 }
+
+func TestContentETag(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:     testDir,
+		local.ConfigContentETag: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+
+	c, err := l.Container("three")
+	is.NoErr(err)
+	one, err := c.Put("same1", strings.NewReader("same"), 4, nil)
+	is.NoErr(err)
+	two, err := c.Put("same2", strings.NewReader("same"), 4, nil)
+	is.NoErr(err)
+	past := time.Now().Add(-time.Hour)
+	is.NoErr(os.Chtimes(two.ID(), past, past))
+
+	etag1, err := one.ETag()
+	is.NoErr(err)
+	etag2, err := two.ETag()
+	is.NoErr(err)
+	is.Equal(etag1, etag2)
+	is.Equal(etag1, "51037a4a37730f52c8732586d3aaa316") // md5 of "same"
+
+	// rewriting the file invalidates the cached hash
+	is.NoErr(ioutil.WriteFile(one.ID(), []byte("different"), 0666))
+	is.NoErr(os.Chtimes(one.ID(), past, past))
+	etag1, err = one.ETag()
+	is.NoErr(err)
+	is.NotEqual(etag1, etag2)
+}
+
+func TestOpenRange(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	item, err := c.Put("ranged", strings.NewReader("0123456789"), 10, nil)
+	is.NoErr(err)
+
+	ro, ok := item.(stow.RangeOpener)
+	is.True(ok)
+	rc, err := ro.OpenRange(2, 5)
+	is.NoErr(err)
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	is.NoErr(err)
+	is.Equal(string(b), "2345")
+
+	_, err = ro.OpenRange(5, 2)
+	is.Err(err)
+}
+
+func TestOpenRanges(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	item, err := c.Put("ranged", strings.NewReader("0123456789"), 10, nil)
+	is.NoErr(err)
+
+	ro, ok := item.(stow.MultiRangeOpener)
+	is.True(ok)
+	// out of order and overlapping ranges are read as given
+	rc, err := ro.OpenRanges([]stow.Range{{Start: 7, End: 9}, {Start: 0, End: 1}, {Start: 1, End: 3}})
+	is.NoErr(err)
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	is.NoErr(err)
+	is.Equal(string(b), "78901123")
+
+	_, err = ro.OpenRanges([]stow.Range{{Start: 0, End: 1}, {Start: 5, End: 2}})
+	is.Err(err)
+	_, err = ro.OpenRanges(nil)
+	is.Err(err)
+}
+
+func TestOpenReaderAt(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	item, err := c.Put("random", strings.NewReader("0123456789"), 10, nil)
+	is.NoErr(err)
+
+	ra, size, err := stow.OpenReaderAt(item)
+	is.NoErr(err)
+	defer ra.(io.Closer).Close()
+	is.Equal(size, int64(10))
+	p := make([]byte, 3)
+	_, err = ra.ReadAt(p, 6)
+	is.NoErr(err)
+	is.Equal(string(p), "678")
+}
+
+func TestOpenIfModifiedSince(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	item, err := c.Put("polled", strings.NewReader("contents"), 8, nil)
+	is.NoErr(err)
+	lastMod, err := item.LastMod()
+	is.NoErr(err)
+
+	co, ok := item.(stow.ConditionalOpener)
+	is.True(ok)
+	rc, ok, err := co.OpenIfModifiedSince(lastMod)
+	is.NoErr(err)
+	is.False(ok)
+	is.Nil(rc)
+
+	rc, ok, err = co.OpenIfModifiedSince(lastMod.Add(-time.Second))
+	is.NoErr(err)
+	is.True(ok)
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	is.NoErr(err)
+	is.Equal(string(b), "contents")
+}
+
+func TestSetMetadata(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	item, err := c.Item("item1")
+	is.NoErr(err)
+
+	ms, ok := item.(stow.MetadataSetter)
+	is.True(ok)
+	is.NoErr(ms.SetMetadata(map[string]interface{}{"owner": "me"}))
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataUser], map[string]interface{}{"owner": "me"})
+
+	// nil leaves the metadata alone
+	is.NoErr(ms.SetMetadata(nil))
+	item, err = c.Item("item1")
+	is.NoErr(err)
+	md, err = item.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataUser], map[string]interface{}{"owner": "me"})
+
+	// an empty map clears it
+	is.NoErr(item.(stow.MetadataSetter).SetMetadata(map[string]interface{}{}))
+	md, err = item.Metadata()
+	is.NoErr(err)
+	is.Nil(md[local.MetadataUser])
+	_, err = os.Stat(item.ID() + local.MetadataFileExt)
+	is.True(os.IsNotExist(err))
+}
+
+func TestSetMetadataKeepsETag(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	for _, contentETag := range []string{"false", "true"} {
+		cfg := stow.ConfigMap{
+			local.ConfigKeyPath:     testDir,
+			local.ConfigContentETag: contentETag,
+		}
+		l, err := stow.Dial(local.Kind, cfg)
+		is.NoErr(err)
+		c, err := l.Container("three")
+		is.NoErr(err)
+		item, err := c.Put("cached", strings.NewReader("contents"), 8, map[string]interface{}{"v": "1"})
+		is.NoErr(err)
+		etag, err := item.ETag()
+		is.NoErr(err)
+		lastMod, err := item.LastMod()
+		is.NoErr(err)
+
+		// give a rewrite of the file a later modification time
+		time.Sleep(20 * time.Millisecond)
+		is.NoErr(item.(stow.MetadataSetter).SetMetadata(map[string]interface{}{"v": "2"}))
+		item, err = c.Item("cached")
+		is.NoErr(err)
+		md, err := item.Metadata()
+		is.NoErr(err)
+		is.Equal(md[local.MetadataUser], map[string]interface{}{"v": "2"})
+		newETag, err := item.ETag()
+		is.NoErr(err)
+		is.Equal(newETag, etag)
+		newLastMod, err := item.LastMod()
+		is.NoErr(err)
+		is.True(newLastMod.Equal(lastMod))
+	}
+}
+
+func TestContentType(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	for _, tt := range []struct {
+		name, content, contentType string
+		md                         map[string]interface{}
+	}{
+		{"page.html", "text", "text/html; charset=utf-8", nil},
+		{"noext", "<html><body></body></html>", "text/html; charset=utf-8", nil},
+		{"empty", "", "text/plain; charset=utf-8", nil},
+		{"given.html", "text", "application/x-custom", map[string]interface{}{stow.MetadataContentType: "application/x-custom"}},
+	} {
+		item, err := c.Put(tt.name, strings.NewReader(tt.content), int64(len(tt.content)), tt.md)
+		is.NoErr(err)
+		ct, ok := item.(stow.ContentTyper)
+		is.True(ok)
+		contentType, err := ct.ContentType()
+		is.NoErr(err)
+		is.Equal(contentType, tt.contentType)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	_, err = c.Put("item4", strings.NewReader("item4"), 5, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+
+	var items []stow.Item
+	err = stow.Walk(c, stow.NoPrefix, 10, func(item stow.Item, err error) error {
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+		return nil
+	})
+	is.NoErr(err)
+	is.Equal(len(items), 4)
+	d, ok := items[3].(stow.Deleter)
+	is.True(ok)
+	is.NoErr(d.Delete())
+	for _, name := range []string{"item4", "item4" + local.MetadataFileExt} {
+		_, err = os.Stat(filepath.Join(testDir, "three", name))
+		is.True(os.IsNotExist(err))
+	}
+}
+
+func TestPath(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	item, err := c.Item("item1")
+	is.NoErr(err)
+
+	pi, ok := item.(local.PathItem)
+	is.True(ok)
+	is.True(filepath.IsAbs(pi.Path()))
+	is.Equal(pi.Path(), filepath.Join(testDir, "three", "item1"))
+	b, err := ioutil.ReadFile(pi.Path())
+	is.NoErr(err)
+	is.Equal(string(b), "3.1")
+}
+
+func TestTypedMetadata(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	_, err = c.Put("typed", strings.NewReader("typed"), 5, map[string]interface{}{
+		"owner":   "me",
+		"count":   42,
+		"ratio":   0.5,
+		"version": "7",
+		"nested":  map[string]interface{}{"a": "b"},
+	})
+	is.NoErr(err)
+	item, err := c.Item("typed")
+	is.NoErr(err)
+
+	tm, ok := item.(stow.TypedMetadata)
+	is.True(ok)
+	s, ok := tm.StringMetadata("owner")
+	is.True(ok)
+	is.Equal(s, "me")
+	_, ok = tm.StringMetadata("count")
+	is.False(ok)
+	n, ok := tm.IntMetadata("count")
+	is.True(ok)
+	is.Equal(n, int64(42))
+	n, ok = tm.IntMetadata("version")
+	is.True(ok)
+	is.Equal(n, int64(7))
+	_, ok = tm.IntMetadata("ratio")
+	is.False(ok)
+	_, ok = tm.IntMetadata("nope")
+	is.False(ok)
+
+	var v struct {
+		Owner  string            `json:"owner"`
+		Count  int               `json:"count"`
+		Nested map[string]string `json:"nested"`
+	}
+	is.NoErr(tm.UnmarshalUserMetadata(&v))
+	is.Equal(v.Owner, "me")
+	is.Equal(v.Count, 42)
+	is.Equal(v.Nested["a"], "b")
+}
+
+func TestRawUserMetadata(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	for _, index := range []string{"false", "true"} {
+		cfg := stow.ConfigMap{
+			local.ConfigKeyPath:   testDir,
+			local.ConfigMetaIndex: index,
+		}
+		l, err := stow.Dial(local.Kind, cfg)
+		is.NoErr(err)
+		c, err := l.Container("three")
+		is.NoErr(err)
+
+		// metadata files hold any JSON object, which is returned as it is
+		raw := `{"z": 1, "id": 12345678901234567890, "list": [1, "two"]}`
+		is.NoErr(ioutil.WriteFile(filepath.Join(testDir, "three", "item1"+local.MetadataFileExt), []byte(raw), 0644))
+		item, err := c.Item("item1")
+		is.NoErr(err)
+		b, err := item.(stow.RawMetadata).RawUserMetadata()
+		is.NoErr(err)
+		is.Equal(string(b), raw)
+		var v struct {
+			ID   uint64        `json:"id"`
+			List []interface{} `json:"list"`
+		}
+		is.NoErr(item.(stow.TypedMetadata).UnmarshalUserMetadata(&v))
+		is.Equal(v.ID, uint64(12345678901234567890))
+		is.Equal(v.List, []interface{}{1.0, "two"})
+		md, err := item.Metadata()
+		is.NoErr(err)
+		is.Equal(md[local.MetadataUser].(map[string]interface{})["list"], []interface{}{1.0, "two"})
+
+		// so are indexed entries
+		item, err = c.Put("item2", strings.NewReader("3.2"), 3, map[string]interface{}{"a": "b"})
+		is.NoErr(err)
+		b, err = item.(stow.RawMetadata).RawUserMetadata()
+		is.NoErr(err)
+		var got map[string]interface{}
+		is.NoErr(json.Unmarshal(b, &got))
+		is.Equal(got, map[string]interface{}{"a": "b"})
+
+		// items without metadata have none
+		item, err = c.Put("item3", strings.NewReader("3.3"), 3, nil)
+		is.NoErr(err)
+		b, err = item.(stow.RawMetadata).RawUserMetadata()
+		is.NoErr(err)
+		is.Nil(b)
+		is.NoErr(item.(stow.TypedMetadata).UnmarshalUserMetadata(&v))
+	}
+}
+
+func TestItemContainer(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	l, err := stow.Dial(local.Kind, stow.ConfigMap{"path": testDir})
+	is.NoErr(err)
+	three, err := l.Container("three")
+	is.NoErr(err)
+	nested, err := three.Put("nested/item", strings.NewReader("nested"), 6, nil)
+	is.NoErr(err)
+
+	item, err := three.Item("item1")
+	is.NoErr(err)
+	c, err := item.(stow.ContainerLinker).Container()
+	is.NoErr(err)
+	is.Equal(c.ID(), three.ID())
+
+	// items got by URL belong to the container holding their file
+	for _, want := range []stow.Item{item, nested} {
+		item, err := l.ItemByURL(want.URL())
+		is.NoErr(err)
+		c, err := item.(stow.ContainerLinker).Container()
+		is.NoErr(err)
+		is.Equal(c.ID(), three.ID())
+		found, err := c.Item(want.Name())
+		is.NoErr(err)
+		is.Equal(found.ID(), want.ID())
+	}
+
+	// files at the root of the location belong to the location directory
+	item, err = l.ItemByURL(&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(testDir, "rootitem"))})
+	is.NoErr(err)
+	c, err = item.(stow.ContainerLinker).Container()
+	is.NoErr(err)
+	is.NoErr(c.RemoveItem(item.ID()))
+	_, err = os.Stat(filepath.Join(testDir, "rootitem"))
+	is.True(os.IsNotExist(err))
+}
+
+func TestTags(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	l, err := stow.Dial(local.Kind, stow.ConfigMap{"path": testDir})
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	item, err := c.Put("tagged", strings.NewReader("tagged"), 6, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+
+	tags, err := stow.ItemTags(item)
+	is.NoErr(err)
+	is.Equal(len(tags), 0)
+	is.NoErr(item.(stow.Tagger).SetTags(map[string]string{"class": "archive"}))
+	tags, err = stow.ItemTags(item)
+	is.NoErr(err)
+	is.Equal(tags, map[string]string{"class": "archive"})
+
+	// tags are kept apart from the metadata, and are not items
+	item, err = c.Item("tagged")
+	is.NoErr(err)
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataUser], map[string]interface{}{"a": "b"})
+	items, _, err := c.Items(stow.NoPrefix, stow.CursorStart, 10)
+	is.NoErr(err)
+	is.Equal(len(items), 4)
+
+	// tags follow moved items, and are removed with them
+	moved, err := stow.MoveItem(c, item.ID(), "moved")
+	is.NoErr(err)
+	tags, err = moved.(stow.Tagger).Tags()
+	is.NoErr(err)
+	is.Equal(tags, map[string]string{"class": "archive"})
+	is.NoErr(c.RemoveItem(moved.ID()))
+	_, err = os.Stat(moved.ID() + local.TagsFileExt)
+	is.True(os.IsNotExist(err))
+
+	// putting an item replaces its tags
+	item, err = c.Put("tagged", strings.NewReader("tagged"), 6, nil)
+	is.NoErr(err)
+	is.NoErr(item.(stow.Tagger).SetTags(map[string]string{"class": "archive"}))
+	item, err = c.Put("tagged", strings.NewReader("again"), 5, nil)
+	is.NoErr(err)
+	tags, err = item.(stow.Tagger).Tags()
+	is.NoErr(err)
+	is.Equal(len(tags), 0)
+}
+
+func TestMaxOpenFiles(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	_, err = stow.Dial(local.Kind, stow.ConfigMap{"path": testDir, local.ConfigMaxOpenFiles: "0"})
+	is.Err(err)
+
+	cfg := stow.ConfigMap{"path": testDir, local.ConfigMaxOpenFiles: "1"}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	item1, err := c.Item("item1")
+	is.NoErr(err)
+	item2, err := c.Item("item2")
+	is.NoErr(err)
+
+	rc, err := item1.Open()
+	is.NoErr(err)
+	opened := make(chan io.ReadCloser)
+	go func() {
+		rc, err := item2.Open()
+		if err != nil {
+			t.Error(err)
+		}
+		opened <- rc
+	}()
+	select {
+	case <-opened:
+		t.Fatal("opened more files than allowed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	is.NoErr(rc.Close())
+	// closing again does not release another slot
+	rc.Close()
+	rc2 := <-opened
+	// the files written are limited separately
+	_, err = c.Put("unblocked", strings.NewReader("unblocked"), 9, nil)
+	is.NoErr(err)
+	is.NoErr(rc2.Close())
+
+	w, err := stow.PutWriter(c, "writing", 7, nil)
+	is.NoErr(err)
+	putDone := make(chan struct{})
+	go func() {
+		_, err := c.Put("blocked", strings.NewReader("blocked"), 7, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		close(putDone)
+	}()
+	select {
+	case <-putDone:
+		t.Fatal("put while all files were open for writing")
+	case <-time.After(50 * time.Millisecond):
+	}
+	_, err = w.Write([]byte("writing"))
+	is.NoErr(err)
+	is.NoErr(w.Close())
+	<-putDone
+}
+
+func TestMaxOpenFilesCopy(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	cfg := stow.ConfigMap{"path": testDir, local.ConfigMaxOpenFiles: "1"}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	item, err := c.Item("item1")
+	is.NoErr(err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := stow.CopyItem(c, "copy", item)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		is.NoErr(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("copy within the location did not return")
+	}
+	copied, err := c.Item("copy")
+	is.NoErr(err)
+	rc, err := copied.Open()
+	is.NoErr(err)
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	is.NoErr(err)
+	is.Equal(string(b), "3.1")
+}
+
+func TestSignedURL(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	item, err := c.Item("item1")
+	is.NoErr(err)
+	_, err = stow.SignedURL(item, time.Minute)
+	is.True(errors.Is(err, stow.ErrNotSupported))
+}
+
+func TestTransparentDecompress(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:               testDir,
+		local.ConfigTransparentDecompress: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container(filepath.Join(testDir, "one"))
+	is.NoErr(err)
+
+	contents := `{"logical":true}`
+	_, err = c.Put("a.json", strings.NewReader(contents), int64(len(contents)), map[string]interface{}{
+		stow.MetadataCompression: "gzip",
+	})
+	is.NoErr(err)
+	_, err = os.Stat(filepath.Join(testDir, "one", "a.json.gz"))
+	is.NoErr(err)
+	_, err = c.Put("b.json", strings.NewReader("{}"), 2, nil)
+	is.NoErr(err)
+
+	items, _, err := c.Items("", stow.CursorStart, 10)
+	is.NoErr(err)
+	names := make(map[string]bool)
+	for _, item := range items {
+		names[item.Name()] = true
+	}
+	is.True(names["a.json"])
+	is.True(names["b.json"])
+	is.False(names["a.json.gz"])
+
+	item, err := c.Item("a.json")
+	is.NoErr(err)
+	is.Equal(item.Name(), "a.json")
+	size, err := item.Size()
+	is.NoErr(err)
+	is.Equal(size, int64(len(contents)))
+	r, err := item.Open()
+	is.NoErr(err)
+	b, err := ioutil.ReadAll(r)
+	is.NoErr(err)
+	is.NoErr(r.Close())
+	is.Equal(string(b), contents)
+	ct, err := item.(interface{ ContentType() (string, error) }).ContentType()
+	is.NoErr(err)
+	is.Equal(ct, "application/json")
+
+	// putting a plain file replaces the gzipped one
+	_, err = c.Put("a.json", strings.NewReader("[]"), 2, nil)
+	is.NoErr(err)
+	_, err = os.Stat(filepath.Join(testDir, "one", "a.json.gz"))
+	is.True(os.IsNotExist(err))
+	is.NoErr(c.RemoveItem("a.json"))
+	_, err = c.Item("a.json")
+	is.True(errors.Is(err, stow.ErrNotFound))
+}
+
+func TestTransparentDecompressMovePutIfNotExists(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:               testDir,
+		local.ConfigTransparentDecompress: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container(filepath.Join(testDir, "one"))
+	is.NoErr(err)
+	dir := filepath.Join(testDir, "one")
+	gzipped := map[string]interface{}{stow.MetadataCompression: "gzip"}
+
+	_, err = c.Put("a.json", strings.NewReader("{}"), 2, gzipped)
+	is.NoErr(err)
+	_, err = c.(stow.ConditionalPutter).PutIfNotExists("a.json", strings.NewReader("[]"), 2, nil)
+	is.True(errors.Is(err, stow.ErrAlreadyExists))
+	_, err = os.Stat(filepath.Join(dir, "a.json"))
+	is.True(os.IsNotExist(err))
+
+	_, err = c.(stow.ConditionalPutter).PutIfNotExists("c.json", strings.NewReader("[]"), 2, gzipped)
+	is.NoErr(err)
+	_, err = os.Stat(filepath.Join(dir, "c.json.gz"))
+	is.NoErr(err)
+	_, err = c.(stow.ConditionalPutter).PutIfNotExists("c.json", strings.NewReader("[]"), 2, gzipped)
+	is.True(errors.Is(err, stow.ErrAlreadyExists))
+
+	// the gzipped file moves to the gzipped file of the new name,
+	// replacing the plain file of that name
+	_, err = c.Put("b.json", strings.NewReader("old"), 3, nil)
+	is.NoErr(err)
+	moved, err := c.(stow.Mover).Move("a.json", "b.json")
+	is.NoErr(err)
+	is.Equal(moved.Name(), "b.json")
+	_, err = os.Stat(filepath.Join(dir, "b.json.gz"))
+	is.NoErr(err)
+	_, err = os.Stat(filepath.Join(dir, "b.json"))
+	is.True(os.IsNotExist(err))
+	item, err := c.Item("b.json")
+	is.NoErr(err)
+	r, err := item.Open()
+	is.NoErr(err)
+	b, err := ioutil.ReadAll(r)
+	is.NoErr(err)
+	is.NoErr(r.Close())
+	is.Equal(string(b), "{}")
+	_, err = c.Item("a.json")
+	is.True(errors.Is(err, stow.ErrNotFound))
+}
+
+func TestTransparentDecompressZstd(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:               testDir,
+		local.ConfigTransparentDecompress: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container(filepath.Join(testDir, "one"))
+	is.NoErr(err)
+	dir := filepath.Join(testDir, "one")
+
+	// the streamed contents leave out the size from the frame header
+	contents := `{"logical":true}`
+	_, err = c.Put("a.json", strings.NewReader(contents), int64(len(contents)), map[string]interface{}{
+		stow.MetadataCompression: "zstd",
+	})
+	is.NoErr(err)
+	_, err = os.Stat(filepath.Join(dir, "a.json.zst"))
+	is.NoErr(err)
+	enc, err := zstd.NewWriter(nil)
+	is.NoErr(err)
+	err = ioutil.WriteFile(filepath.Join(dir, "b.json.zst"), enc.EncodeAll([]byte("[1,2]"), nil), 0644)
+	is.NoErr(err)
+	is.NoErr(enc.Close())
+
+	for name, contents := range map[string]string{"a.json": contents, "b.json": "[1,2]"} {
+		item, err := c.Item(name)
+		is.NoErr(err)
+		is.Equal(item.Name(), name)
+		size, err := item.Size()
+		is.NoErr(err)
+		is.Equal(size, int64(len(contents)))
+		r, err := item.Open()
+		is.NoErr(err)
+		b, err := ioutil.ReadAll(r)
+		is.NoErr(err)
+		is.NoErr(r.Close())
+		is.Equal(string(b), contents)
+	}
+
+	// a gzipped file holds the item instead of the zstd one, which
+	// putting it replaces
+	_, err = c.Put("a.json", strings.NewReader("{}"), 2, map[string]interface{}{
+		stow.MetadataCompression: "gzip",
+	})
+	is.NoErr(err)
+	_, err = os.Stat(filepath.Join(dir, "a.json.zst"))
+	is.True(os.IsNotExist(err))
+	err = ioutil.WriteFile(filepath.Join(dir, "b.json.gz"), gzipped(t, "[]"), 0644)
+	is.NoErr(err)
+	items, _, err := c.Items("", stow.CursorStart, 10)
+	is.NoErr(err)
+	names := make(map[string]int)
+	for _, item := range items {
+		names[item.Name()]++
+	}
+	is.Equal(names, map[string]int{"a.json": 1, "b.json": 1})
+	item, err := c.Item("b.json")
+	is.NoErr(err)
+	size, err := item.Size()
+	is.NoErr(err)
+	is.Equal(size, int64(2))
+}
+
+func gzipped(t *testing.T, contents string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(contents)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
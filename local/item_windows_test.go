@@ -0,0 +1,72 @@
+package local_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+	"github.com/graymeta/stow/local"
+)
+
+func TestSlashIDs(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	_, err = c.Put("sub/item", strings.NewReader("sub"), 3, nil)
+	is.NoErr(err)
+
+	items, _, err := c.Items(stow.NoPrefix, stow.CursorStart, 10)
+	is.NoErr(err)
+	for _, item := range items {
+		is.False(strings.Contains(item.ID(), `\`))
+		got, err := c.Item(item.ID())
+		is.NoErr(err)
+		is.Equal(got.ID(), item.ID())
+		is.Equal(got.Name(), item.Name())
+	}
+
+	// both separators are accepted
+	for _, id := range []string{"sub/item", `sub\item`} {
+		item, err := c.Item(id)
+		is.NoErr(err)
+		is.Equal(item.Name(), "sub/item")
+	}
+}
+
+func TestLongPath(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	name := strings.Repeat("directory-name/", 20) + strings.Repeat("n", 100)
+	is.True(len(testDir)+len(name) > 260)
+	item, err := c.Put(name, strings.NewReader("deep"), 4, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+	is.Equal(item.Name(), name)
+
+	item, err = c.Item(name)
+	is.NoErr(err)
+	rc, err := item.Open()
+	is.NoErr(err)
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	is.NoErr(err)
+	is.Equal(string(b), "deep")
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataUser], map[string]interface{}{"a": "b"})
+}
@@ -0,0 +1,26 @@
+package local
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// cloneFile makes dst share the contents of src without copying them,
+// which is not supported on macOS.
+func cloneFile(dst, src *os.File) error {
+	return errors.New("cloning files is not supported")
+}
+
+// sameDevice gets whether both files are on the same device.
+func sameDevice(a, b os.FileInfo) bool {
+	sa, okA := a.Sys().(*syscall.Stat_t)
+	sb, okB := b.Sys().(*syscall.Stat_t)
+	return okA && okB && sa.Dev == sb.Dev
+}
+
+// isCrossDevice gets whether err is the error of a rename
+// between two devices.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
@@ -0,0 +1,27 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package local
+
+import (
+	"errors"
+	"os"
+)
+
+// cloneFile makes dst share the contents of src without copying them,
+// which is not supported on this platform.
+func cloneFile(dst, src *os.File) error {
+	return errors.New("cloning files is not supported")
+}
+
+// sameDevice gets whether both files are on the same device, which
+// is not told on this platform, so files are never linked there.
+func sameDevice(a, b os.FileInfo) bool {
+	return false
+}
+
+// isCrossDevice gets whether err is the error of a rename between two
+// devices, which is not told on this platform.
+func isCrossDevice(err error) bool {
+	return false
+}
@@ -0,0 +1,28 @@
+package local
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// cloneFile makes dst share the contents of src without copying them,
+// which is not supported on Windows.
+func cloneFile(dst, src *os.File) error {
+	return errors.New("cloning files is not supported")
+}
+
+// sameDevice gets whether both files are on the same device, which
+// cannot be told on Windows, so files are never linked there.
+func sameDevice(a, b os.FileInfo) bool {
+	return false
+}
+
+// errorNotSameDevice is the ERROR_NOT_SAME_DEVICE Windows error.
+const errorNotSameDevice syscall.Errno = 17
+
+// isCrossDevice gets whether err is the error of a rename
+// between two devices.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, errorNotSameDevice)
+}
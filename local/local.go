@@ -1,9 +1,15 @@
 package local
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/graymeta/stow"
 )
@@ -11,7 +17,217 @@ import (
 // ConfigKeys are the supported configuration items for
 // local storage.
 const (
+	// ConfigKeyPath is the path of the directory of the location. If it
+	// does not exist, but its parent directory does, it is created.
 	ConfigKeyPath = "path"
+
+	// ConfigContentETag is an optional config value that makes item ETags
+	// the MD5 hash of the file contents instead of the last modified time.
+	// Its default value is "false", to enable set it to "true".
+	ConfigContentETag = "content_etag"
+
+	// ConfigAtomicPut is an optional config value that controls whether Put
+	// writes to a temporary file which is renamed into place once complete,
+	// so that readers never see a partially written file.
+	// Its default value is "true", to disable set it to "false".
+	ConfigAtomicPut = "atomic_put"
+
+	// ConfigPreserveOwnership is an optional config value that makes Put
+	// change the owner of new files to the uid and gid found in the
+	// metadata, such as the metadata of another local Item. Changing the
+	// owner usually requires running as root.
+	// Its default value is "false", to enable set it to "true".
+	ConfigPreserveOwnership = "preserve_ownership"
+
+	// ConfigFollowSymlinks is an optional config value that makes items
+	// describe the targets of symlinks rather than the symlinks themselves,
+	// and makes Items descend into symlinked directories. Each directory is
+	// only visited once, so symlink loops are not followed forever.
+	// Its default value is "false", to enable set it to "true".
+	ConfigFollowSymlinks = "follow_symlinks"
+
+	// ConfigMkdirAll is an optional config value that makes CreateContainer
+	// create any missing parent directories of nested container names
+	// such as "a/b/c".
+	// Its default value is "false", to enable set it to "true".
+	ConfigMkdirAll = "mkdir_all"
+
+	// ConfigIgnoreGlobs is an optional config value holding a comma
+	// separated list of glob patterns, such as "*.swp,.DS_Store", for
+	// files that Items skips. The patterns are matched against the names
+	// of items and their last path element. Directories are still walked
+	// unless their own name matches. Metadata files are always skipped.
+	ConfigIgnoreGlobs = "ignore_globs"
+
+	// ConfigFsync is an optional config value that makes Put sync the
+	// contents of new files, and the directories holding them, to disk
+	// before returning, so that they survive a crash or power failure.
+	// Syncing waits for the disk, which makes each Put much slower,
+	// especially for many small files.
+	// Its default value is "false", to enable set it to "true".
+	ConfigFsync = "fsync"
+
+	// ConfigDefaultMode is an optional config value holding the octal
+	// permission bits, such as "0600", of the files created by Put when
+	// the metadata does not give a mode. New directories get the same
+	// bits, plus the execute bit wherever the read bit is set.
+	// By default files and directories are created with the modes
+	// 0666 and 0777, less the umask.
+	ConfigDefaultMode = "default_mode"
+
+	// ConfigEagerStat is an optional config value that makes Items fill
+	// in the size and metadata of the items it returns from the directory
+	// listing, so that calling Size, Metadata and the like does not stat
+	// each file again. Those values are then as of the call to Items.
+	// Its default value is "false", to enable set it to "true".
+	ConfigEagerStat = "eager_stat"
+
+	// ConfigCopyBufferSize is an optional config value holding the size
+	// in bytes of the buffers that Put copies contents through, such as
+	// "4194304". Contents read from files are still copied inside the
+	// kernel where the platform supports it. Sizes that are not
+	// between 1 and 1GB fail validation, and so Dial.
+	// Its default value is 1MB.
+	ConfigCopyBufferSize = "copy_buffer_size"
+
+	// ConfigReflink is an optional config value that makes Put avoid
+	// copying the contents of a file on the same device, such as an
+	// opened local Item, when it reads all of it. The file is cloned
+	// where the filesystem supports it, and hardlinked otherwise.
+	// Hardlinked files share their contents, permissions and owner with
+	// the file they were put from, so the metadata does not change them.
+	// Other readers are copied as usual.
+	// Its default value is "false", to enable set it to "true".
+	ConfigReflink = "reflink"
+
+	// ConfigSortItems is an optional config value that makes Items list
+	// items sorted by their slash separated names, so that listings are
+	// the same on all platforms and filesystems. All of the entries of
+	// the container are held in memory to sort them.
+	// Its default value is "false", to enable set it to "true".
+	ConfigSortItems = "sort_items"
+
+	// ConfigReadXattrs is an optional config value that makes items
+	// include the extended attributes of their files in their metadata,
+	// under MetadataXattr, and makes Put set the extended attributes
+	// given in the metadata under the same key. Extended attributes are
+	// only supported on Linux and macOS, and are left out of the metadata
+	// when the filesystem does not support them.
+	// Its default value is "false", to enable set it to "true".
+	ConfigReadXattrs = "read_xattrs"
+
+	// ConfigTempDir is an optional config value holding the path of a
+	// directory that atomic puts write their temporary files to, such
+	// as a directory on faster scratch storage. Files can only be renamed
+	// into place from the same device, so when the directory is on
+	// another device than the destination, which is always assumed on
+	// Windows, the temporary file is written next to the destination
+	// instead and a warning is logged.
+	// By default temporary files are written next to the destination.
+	ConfigTempDir = "temp_dir"
+
+	// ConfigMetaIndex is an optional config value that makes Put store
+	// the user metadata of all of the items of a container in a single
+	// index file, MetaIndexFile, at the root of the container, instead of
+	// a metadata file next to each file. Updates of the index are
+	// serialized with a lock on the container directory, which is only
+	// taken within the process on platforms other than Linux and macOS.
+	// Items without indexed metadata fall back to their metadata files,
+	// which MigrateToMetaIndex moves into the index. Items got by URL
+	// use the index of the directory holding their file.
+	// Its default value is "false", to enable set it to "true".
+	ConfigMetaIndex = "meta_index"
+
+	// ConfigVirtualContainers is an optional config value holding a JSON
+	// object that maps the names of virtual containers to the directory
+	// they are rooted at, relative to the path, and a glob pattern that
+	// the names of their items must match, in the same way as the ignore
+	// globs, for example:
+	//
+	//	{"parquet": {"path": "data", "include": "*.parquet"}}
+	//
+	// Location.Container gets virtual containers by their name, which
+	// is also their ID. Their items only include the matching files,
+	// and putting items with other names fails. Virtual containers are
+	// not listed by Location.Containers, and cannot be removed.
+	ConfigVirtualContainers = "virtual_containers"
+
+	// ConfigSparse is an optional config value that makes Put keep the
+	// holes of sparse files it copies from, such as opened local Items,
+	// so that they take up no more space than their source. Holes are
+	// only found on Linux, on filesystems that report them; elsewhere
+	// files are copied as usual.
+	// Its default value is "false", to enable set it to "true".
+	ConfigSparse = "sparse"
+
+	// ConfigAllowSymlinkCreate is an optional config value that makes
+	// Put create a symlink, instead of a file with the contents of the
+	// reader, when the metadata sets MetadataIsSymlink to true and gives
+	// the target in MetadataLink, such as the metadata of another local
+	// Item. Relative targets are relative to the directory of the
+	// symlink. Targets outside of the container are rejected.
+	// Its default value is "false", to enable set it to "true".
+	ConfigAllowSymlinkCreate = "allow_symlink_create"
+
+	// ConfigCaseInsensitive is an optional config value that makes
+	// Container.Item find the file of an item by its name ignoring case
+	// when there is no file with the exact name, such as for items
+	// created on a case-insensitive filesystem. The directories are only
+	// scanned when the exact name is missing, and getting the item fails
+	// if more than one file matches. Other methods use the exact name.
+	// Its default value is "false", to enable set it to "true".
+	ConfigCaseInsensitive = "case_insensitive"
+
+	// ConfigRecordMD5 is an optional config value that makes Put record
+	// the MD5 checksum of the contents of the files it writes in their
+	// user metadata, under stow.MetadataContentMD5, so that stow.Scrub
+	// can later find files whose contents were corrupted. The checksum
+	// is computed by reading the file back once it is written.
+	// Its default value is "false", to enable set it to "true".
+	ConfigRecordMD5 = "record_md5"
+
+	// ConfigMaxOpenFiles is an optional config value holding the most
+	// files that the items of the location hold open for reading at
+	// once, such as "256", so that reading many items concurrently does
+	// not exhaust the file descriptors of the process. Opening an item
+	// waits until one of the readers of the others is closed. The files
+	// written by Put, PutWriter and Append are limited in the same way,
+	// but separately, so that putting from an opened item does not wait
+	// for its own reader to be closed, and up to twice as many files are
+	// open at once.
+	// By default the number of open files is not limited.
+	ConfigMaxOpenFiles = "max_open_files"
+
+	// ConfigDirsAsContainers is an optional config value that makes the
+	// directories inside containers nested containers, rather than
+	// parts of the names of items. Items only lists the files directly
+	// inside the directory of a container, and Location.Containers
+	// recurses into the directories, listing them with names that are
+	// their slash separated paths, such as "photos/2020", by which
+	// Location.Container gets them.
+	// Its default value is "false", to enable set it to "true".
+	ConfigDirsAsContainers = "dirs_as_containers"
+
+	// ConfigTransparentDecompress is an optional config value that
+	// makes compressed files, whose names end with ".gz" for gzip or
+	// ".zst" for zstd, items named without the extension, whose
+	// contents are decompressed when they are opened and whose size is
+	// the uncompressed size. If both "a.json" and "a.json.gz" exist,
+	// only the plain file is listed, and a gzipped file is listed
+	// instead of a zstd one. Put compresses the contents into a ".gz" or
+	// ".zst" file if the metadata holds stow.MetadataCompression with
+	// the value "gzip" or "zstd", and otherwise removes any compressed
+	// file with the same name.
+	// Its default value is "false", to enable set it to "true".
+	ConfigTransparentDecompress = "transparent_decompress"
+)
+
+const (
+	// defaultCopyBufferSize is the copy buffer size used unless
+	// ConfigCopyBufferSize is set.
+	defaultCopyBufferSize = 1 << 20
+	// maxCopyBufferSize is the largest valid copy buffer size.
+	maxCopyBufferSize = 1 << 30
 )
 
 // Kind is the kind of Location this package provides.
@@ -23,10 +239,38 @@ const (
 
 func init() {
 	validatefn := func(config stow.Config) error {
-		_, ok := config.Config(ConfigKeyPath)
+		path, ok := config.Config(ConfigKeyPath)
 		if !ok {
 			return errors.New("missing path config")
 		}
+		if err := validatePath(path); err != nil {
+			return err
+		}
+		if v, ok := config.Config(ConfigIgnoreGlobs); ok {
+			if _, err := parseGlobs(v); err != nil {
+				return err
+			}
+		}
+		if v, ok := config.Config(ConfigDefaultMode); ok {
+			if _, err := parseMode(v); err != nil {
+				return err
+			}
+		}
+		if v, ok := config.Config(ConfigVirtualContainers); ok {
+			if _, err := parseVirtualContainers(v); err != nil {
+				return err
+			}
+		}
+		if v, ok := config.Config(ConfigCopyBufferSize); ok {
+			if _, err := parseBufferSize(v); err != nil {
+				return err
+			}
+		}
+		if v, ok := config.Config(ConfigMaxOpenFiles); ok {
+			if _, err := parseMaxOpenFiles(v); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 	makefn := func(config stow.Config) (stow.Location, error) {
@@ -35,18 +279,251 @@ func init() {
 			return nil, errors.New("missing path config")
 		}
 		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			// validatePath accepts paths that can be created
+			if err = os.Mkdir(path, 0777); err == nil {
+				info, err = os.Stat(path)
+			}
+		}
 		if err != nil {
 			return nil, err
 		}
 		if !info.IsDir() {
 			return nil, errors.New("path must be directory")
 		}
-		return &location{
-			config: config,
-		}, nil
+		l := &location{
+			config:         config,
+			atomicPut:      true,
+			copyBufferSize: defaultCopyBufferSize,
+		}
+		if v, ok := config.Config(ConfigContentETag); ok && v == "true" {
+			l.contentETag = true
+		}
+		if v, ok := config.Config(ConfigAtomicPut); ok && v == "false" {
+			l.atomicPut = false
+		}
+		if v, ok := config.Config(ConfigPreserveOwnership); ok && v == "true" {
+			l.preserveOwnership = true
+		}
+		if v, ok := config.Config(ConfigFollowSymlinks); ok && v == "true" {
+			l.followSymlinks = true
+		}
+		if v, ok := config.Config(ConfigMkdirAll); ok && v == "true" {
+			l.mkdirAll = true
+		}
+		if v, ok := config.Config(ConfigDefaultMode); ok {
+			if l.defaultMode, err = parseMode(v); err != nil {
+				return nil, err
+			}
+		}
+		if v, ok := config.Config(ConfigEagerStat); ok && v == "true" {
+			l.eagerStat = true
+		}
+		if v, ok := config.Config(ConfigReflink); ok && v == "true" {
+			l.reflink = true
+		}
+		if v, ok := config.Config(ConfigSortItems); ok && v == "true" {
+			l.sortItems = true
+		}
+		if v, ok := config.Config(ConfigReadXattrs); ok && v == "true" {
+			l.readXattrs = true
+		}
+		if v, ok := config.Config(ConfigTempDir); ok {
+			l.tempDir = v
+		}
+		if v, ok := config.Config(ConfigMetaIndex); ok && v == "true" {
+			l.metaIndex = true
+		}
+		if v, ok := config.Config(ConfigFsync); ok && v == "true" {
+			l.fsync = true
+		}
+		if v, ok := config.Config(ConfigSparse); ok && v == "true" {
+			l.sparse = true
+		}
+		if v, ok := config.Config(ConfigAllowSymlinkCreate); ok && v == "true" {
+			l.allowSymlinkCreate = true
+		}
+		if v, ok := config.Config(ConfigCaseInsensitive); ok && v == "true" {
+			l.caseInsensitive = true
+		}
+		if v, ok := config.Config(ConfigRecordMD5); ok && v == "true" {
+			l.recordMD5 = true
+		}
+		if v, ok := config.Config(ConfigDirsAsContainers); ok && v == "true" {
+			l.dirsAsContainers = true
+		}
+		if v, ok := config.Config(ConfigTransparentDecompress); ok && v == "true" {
+			l.transparentDecompress = true
+		}
+		if v, ok := config.Config(ConfigIgnoreGlobs); ok {
+			if l.ignoreGlobs, err = parseGlobs(v); err != nil {
+				return nil, err
+			}
+		}
+		if v, ok := config.Config(ConfigVirtualContainers); ok {
+			if l.virtualContainers, err = parseVirtualContainers(v); err != nil {
+				return nil, err
+			}
+		}
+		if v, ok := config.Config(ConfigCopyBufferSize); ok {
+			if l.copyBufferSize, err = parseBufferSize(v); err != nil {
+				return nil, err
+			}
+		}
+		if v, ok := config.Config(ConfigMaxOpenFiles); ok {
+			n, err := parseMaxOpenFiles(v)
+			if err != nil {
+				return nil, err
+			}
+			l.openFiles = make(openFiles, n)
+			l.writeFiles = make(openFiles, n)
+		}
+		l.copyBuffers.New = func() interface{} {
+			buf := make([]byte, l.copyBufferSize)
+			return &buf
+		}
+		return l, nil
 	}
 	kindfn := func(u *url.URL) bool {
 		return u.Scheme == "file"
 	}
 	stow.Register(Kind, makefn, kindfn, validatefn)
+	stow.RegisterConfigKeys(Kind, []string{ConfigKeyPath}, []string{
+		ConfigContentETag,
+		ConfigAtomicPut,
+		ConfigPreserveOwnership,
+		ConfigFollowSymlinks,
+		ConfigMkdirAll,
+		ConfigIgnoreGlobs,
+		ConfigFsync,
+		ConfigDefaultMode,
+		ConfigEagerStat,
+		ConfigCopyBufferSize,
+		ConfigReflink,
+		ConfigSortItems,
+		ConfigReadXattrs,
+		ConfigTempDir,
+		ConfigMetaIndex,
+		ConfigVirtualContainers,
+		ConfigSparse,
+		ConfigAllowSymlinkCreate,
+		ConfigCaseInsensitive,
+		ConfigRecordMD5,
+		ConfigMaxOpenFiles,
+		ConfigDirsAsContainers,
+		ConfigTransparentDecompress,
+	})
+}
+
+// validatePath checks that the path is a directory, or that it
+// can be created as one in an existing directory.
+func validatePath(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		parent, perr := os.Stat(filepath.Dir(filepath.Clean(path)))
+		if perr != nil || !parent.IsDir() {
+			return fmt.Errorf("path %s does not exist and cannot be created", path)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("bad path %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return errors.New("path must be directory")
+	}
+	return nil
+}
+
+// parseGlobs parses a comma separated list of glob patterns.
+func parseGlobs(s string) ([]string, error) {
+	var globs []string
+	for _, glob := range strings.Split(s, ",") {
+		glob = strings.TrimSpace(glob)
+		if glob == "" {
+			continue
+		}
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("bad ignore glob %q: %w", glob, err)
+		}
+		globs = append(globs, glob)
+	}
+	return globs, nil
+}
+
+// virtualContainer is the configuration of a virtual container.
+type virtualContainer struct {
+	// Path is the directory the container is rooted at.
+	Path string `json:"path"`
+	// Include is the glob pattern that the names of items match.
+	Include string `json:"include"`
+}
+
+// parseVirtualContainers parses the JSON configuration of the
+// virtual containers.
+func parseVirtualContainers(s string) (map[string]virtualContainer, error) {
+	var vcs map[string]virtualContainer
+	if err := json.Unmarshal([]byte(s), &vcs); err != nil {
+		return nil, fmt.Errorf("bad virtual containers: %w", err)
+	}
+	for name, vc := range vcs {
+		if vc.Path == "" {
+			return nil, fmt.Errorf("missing path of virtual container %s", name)
+		}
+		if vc.Include == "" {
+			return nil, fmt.Errorf("missing include glob of virtual container %s", name)
+		}
+		if _, err := path.Match(vc.Include, ""); err != nil {
+			return nil, fmt.Errorf("bad include glob %q of virtual container %s: %w", vc.Include, name, err)
+		}
+	}
+	return vcs, nil
+}
+
+// parseMode parses octal permission bits.
+func parseMode(s string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("bad default mode %q", s)
+	}
+	return os.FileMode(m), nil
+}
+
+// parseBufferSize parses a buffer size in bytes.
+func parseBufferSize(s string) (int, error) {
+	size, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("bad copy buffer size %q: %w", s, err)
+	}
+	if size <= 0 || size > maxCopyBufferSize {
+		return 0, fmt.Errorf("bad copy buffer size %q: must be between 1 and %d", s, maxCopyBufferSize)
+	}
+	return size, nil
+}
+
+// parseMaxOpenFiles parses the ConfigMaxOpenFiles value, which must
+// be a positive number.
+func parseMaxOpenFiles(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("bad max open files %q: %w", s, err)
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("bad max open files %q: must be positive", s)
+	}
+	return n, nil
+}
+
+// wrapErr replaces the error err points to, if any, with a *stow.Error
+// for the operation on the named container or item, unless it already
+// is one.
+func wrapErr(err *error, op, name string) {
+	if *err == nil {
+		return
+	}
+	var serr *stow.Error
+	if errors.As(*err, &serr) {
+		return
+	}
+	*err = &stow.Error{Op: op, Kind: Kind, Name: name, Err: *err}
 }
@@ -4,7 +4,10 @@ import (
 	"errors"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/graymeta/stow"
 )
@@ -12,31 +15,214 @@ import (
 type location struct {
 	// config is the configuration for this location.
 	config stow.Config
+	// contentETag indicates whether item ETags are content hashes.
+	contentETag bool
+	// atomicPut indicates whether Put writes through a temporary file.
+	atomicPut bool
+	// preserveOwnership indicates whether Put changes file owners.
+	preserveOwnership bool
+	// followSymlinks indicates whether symlinks are followed.
+	followSymlinks bool
+	// mkdirAll indicates whether CreateContainer makes parent directories.
+	mkdirAll bool
+	// defaultMode is the mode of new files, when not zero.
+	defaultMode os.FileMode
+	// eagerStat indicates whether Items fills in the file info of
+	// items from the directory listing.
+	eagerStat bool
+	// copyBufferSize is the size of the buffers in copyBuffers.
+	copyBufferSize int
+	// copyBuffers is a pool of *[]byte buffers that Put copies
+	// contents through.
+	copyBuffers sync.Pool
+	// reflink indicates whether Put clones or hardlinks files on
+	// the same device instead of copying them.
+	reflink bool
+	// sortItems indicates whether Items sorts items by name.
+	sortItems bool
+	// readXattrs indicates whether items include the extended
+	// attributes of their files in their metadata.
+	readXattrs bool
+	// tempDir is the directory atomic puts stage files in,
+	// if not empty.
+	tempDir string
+	// tempDirWarning logs that tempDir is on another device
+	// than a destination, once.
+	tempDirWarning sync.Once
+	// metaIndex indicates whether user metadata is stored in
+	// the metadata index of the container.
+	metaIndex bool
+	// fsync indicates whether Put syncs files to disk.
+	fsync bool
+	// sparse indicates whether Put keeps the holes of the
+	// sparse files it copies from.
+	sparse bool
+	// allowSymlinkCreate indicates whether Put creates the
+	// symlinks described by the metadata.
+	allowSymlinkCreate bool
+	// caseInsensitive indicates whether Container.Item finds
+	// files by name ignoring case when the exact name is missing.
+	caseInsensitive bool
+	// recordMD5 indicates whether Put records the checksums of
+	// the contents of files in their user metadata.
+	recordMD5 bool
+	// openFiles limits the number of files held open by Open, if
+	// not nil.
+	openFiles openFiles
+	// writeFiles limits the number of files held open for writing
+	// by Put, PutWriter and Append, if not nil.
+	writeFiles openFiles
+	// dirsAsContainers indicates whether the directories inside
+	// containers are nested containers.
+	dirsAsContainers bool
+	// transparentDecompress indicates whether compressed files are
+	// items named without the extension, which are decompressed when
+	// opened.
+	transparentDecompress bool
+	// ignoreGlobs are the patterns of the files that Items skips.
+	ignoreGlobs []string
+	// virtualContainers are the virtual containers by name.
+	virtualContainers map[string]virtualContainer
+	// indexes caches the metadata indexes of the containers.
+	indexes indexCache
+}
+
+// dirMode gets the mode of new directories, which is the default mode
+// with the execute bit added wherever the read bit is set, or 0777 if
+// there is no default mode.
+func (l *location) dirMode() os.FileMode {
+	if l.defaultMode == 0 {
+		return 0777
+	}
+	return l.defaultMode | (l.defaultMode&0444)>>2
+}
+
+// ignored gets whether the file or directory with the specified
+// name, relative to its container, matches any of the ignore globs.
+// Each glob is matched against the whole name and its last element.
+func (l *location) ignored(name string) bool {
+	for _, glob := range l.ignoreGlobs {
+		if matchGlob(glob, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob gets whether the name, or its last element,
+// matches the glob.
+func matchGlob(glob, name string) bool {
+	name = filepath.ToSlash(name)
+	if ok, _ := path.Match(glob, name); ok {
+		return true
+	}
+	ok, _ := path.Match(glob, path.Base(name))
+	return ok
 }
 
 func (l *location) Close() error {
 	return nil // nothing to close
 }
 
-func (l *location) ItemByURL(u *url.URL) (stow.Item, error) {
-	dir, _ := filepath.Split(u.Path)
-	return &item{
-		path:          u.Path,
-		contPrefixLen: len(dir),
-	}, nil
+// Ping checks that the configured path is a directory that files
+// can be written to, by creating and removing a temporary file.
+func (l *location) Ping() (err error) {
+	defer wrapErr(&err, "ping", "")
+
+	path, ok := l.config.Config(ConfigKeyPath)
+	if !ok {
+		return errors.New("missing " + ConfigKeyPath + " configuration")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("path must be directory")
+	}
+	f, err := createTemp(path)
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// ItemByURL gets the item for a file:// URL, such as one got from
+// the URL method of an item. The container of the item is the
+// directory holding the file, which must be inside the location path.
+func (l *location) ItemByURL(u *url.URL) (_ stow.Item, err error) {
+	defer wrapErr(&err, "get", u.String())
+
+	if u.Scheme != "file" {
+		return nil, errors.New("unexpected url scheme " + u.Scheme)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, errors.New("unexpected url host " + u.Host)
+	}
+	root, ok := l.config.Config(ConfigKeyPath)
+	if !ok {
+		return nil, errors.New("missing " + ConfigKeyPath + " configuration")
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	// u.Path holds the decoded path of the url
+	path := filepath.Clean(filepath.FromSlash(u.Path))
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return nil, err
+	}
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, errors.New("url path is outside " + root)
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, stow.NotFound(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, errors.New("unexpected directory")
+	}
+	dir := filepath.Dir(path)
+	c := &container{
+		name:     filepath.Base(dir),
+		path:     dir,
+		location: l,
+		byURL:    true,
+	}
+	return c.newItem(path, existingMeta(path)), nil
 }
 
-func (l *location) RemoveContainer(id string) error {
+func (l *location) RemoveContainer(id string) (err error) {
+	defer wrapErr(&err, "remove container", id)
+
+	if _, ok := l.virtualContainers[id]; ok {
+		return errors.New("cannot remove virtual container " + id)
+	}
 	return os.RemoveAll(id)
 }
 
-func (l *location) CreateContainer(name string) (stow.Container, error) {
+func (l *location) CreateContainer(name string) (_ stow.Container, err error) {
+	defer wrapErr(&err, "create container", name)
+
 	path, ok := l.config.Config(ConfigKeyPath)
 	if !ok {
 		return nil, errors.New("missing " + ConfigKeyPath + " configuration")
 	}
 	fullpath := filepath.Join(path, name)
-	if err := os.Mkdir(fullpath, 0777); err != nil {
+	if l.mkdirAll {
+		if err := os.MkdirAll(filepath.Dir(fullpath), l.dirMode()); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.Mkdir(fullpath, l.dirMode()); err != nil {
 		return nil, err
 	}
 	abspath, err := filepath.Abs(fullpath)
@@ -44,17 +230,25 @@ func (l *location) CreateContainer(name string) (stow.Container, error) {
 		return nil, err
 	}
 	return &container{
-		name: name,
-		path: abspath,
+		name:     name,
+		path:     abspath,
+		location: l,
 	}, nil
 }
 
-func (l *location) Containers(prefix string, cursor string, count int) ([]stow.Container, string, error) {
+func (l *location) Containers(prefix string, cursor string, count int) (_ []stow.Container, _ string, err error) {
+	defer wrapErr(&err, "list containers", prefix)
+
 	path, ok := l.config.Config(ConfigKeyPath)
 	if !ok {
 		return nil, "", errors.New("missing " + ConfigKeyPath + " configuration")
 	}
-	files, err := filepath.Glob(filepath.Join(path, prefix+"*"))
+	var files []string
+	if l.dirsAsContainers {
+		files, err = l.nestedDirs(path, prefix)
+	} else {
+		files, err = filepath.Glob(filepath.Join(path, prefix+"*"))
+	}
 	if err != nil {
 		return nil, "", err
 	}
@@ -63,8 +257,9 @@ func (l *location) Containers(prefix string, cursor string, count int) ([]stow.C
 
 	if prefix == stow.NoPrefix && cursor == stow.CursorStart {
 		allContainer := container{
-			name: "All",
-			path: path,
+			name:     "All",
+			path:     path,
+			location: l,
 		}
 
 		cs = append(cs, &allContainer)
@@ -101,11 +296,44 @@ func (l *location) Containers(prefix string, cursor string, count int) ([]stow.C
 	return cs, cursor, err
 }
 
-func (l *location) Container(id string) (stow.Container, error) {
+// nestedDirs gets the paths of all of the directories inside root,
+// in lexical order, whose slash separated paths relative to root
+// start with the prefix. Ignored directories are skipped, along with
+// the directories inside them.
+func (l *location) nestedDirs(root, prefix string) ([]string, error) {
+	var dirs []string
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if l.ignored(rel) {
+			return filepath.SkipDir
+		}
+		if strings.HasPrefix(filepath.ToSlash(rel), prefix) {
+			dirs = append(dirs, p)
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+func (l *location) Container(id string) (_ stow.Container, err error) {
+	defer wrapErr(&err, "get container", id)
+
 	path, ok := l.config.Config(ConfigKeyPath)
 	if !ok {
 		return nil, errors.New("missing " + ConfigKeyPath + " configuration")
 	}
+	if vc, ok := l.virtualContainers[id]; ok {
+		return l.virtualContainer(path, id, vc)
+	}
 	var fullPath string
 	if filepath.IsAbs(id) {
 		fullPath = id
@@ -116,7 +344,7 @@ func (l *location) Container(id string) (stow.Container, error) {
 	containers, err := l.filesToContainers(path, fullPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, stow.ErrNotFound
+			return nil, stow.NotFound(err)
 		}
 		return nil, err
 	}
@@ -126,6 +354,35 @@ func (l *location) Container(id string) (stow.Container, error) {
 	return containers[0], nil
 }
 
+// virtualContainer gets the virtual container with the specified name,
+// whose directory must exist.
+func (l *location) virtualContainer(root, name string, vc virtualContainer) (stow.Container, error) {
+	dir := filepath.FromSlash(vc.Path)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return nil, stow.NotFound(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, errors.New("virtual container path must be directory")
+	}
+	abspath, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &container{
+		name:     name,
+		path:     abspath,
+		location: l,
+		include:  vc.Include,
+	}, nil
+}
+
 // filesToContainers takes a list of files and turns it into a
 // stow.ContainerList.
 func (l *location) filesToContainers(root string, files ...string) ([]stow.Container, error) {
@@ -150,9 +407,13 @@ func (l *location) filesToContainers(root string, files ...string) ([]stow.Conta
 		if err != nil {
 			return nil, err
 		}
+		if l.dirsAsContainers {
+			name = filepath.ToSlash(name)
+		}
 		cs = append(cs, &container{
-			name: name,
-			path: path,
+			name:     name,
+			path:     path,
+			location: l,
 		})
 	}
 	return cs, nil
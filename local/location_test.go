@@ -1,8 +1,14 @@
 package local_test
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/cheekybits/is"
@@ -228,6 +234,290 @@ func TestContainersPaging(t *testing.T) {
 
 	// bad cursor
 	_, _, err = l.Containers("container-", "made-up-cursor", 10)
-	is.Equal(err, stow.ErrBadCursor)
+	is.True(errors.Is(err, stow.ErrBadCursor))
 
 }
+
+func TestFollowSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	// link a file and a directory into z-links, and make a loop back to it
+	is.NoErr(os.Symlink(filepath.Join(testDir, "z-links", "symtarget"), filepath.Join(testDir, "z-links", "file")))
+	is.NoErr(os.Symlink(filepath.Join(testDir, "three"), filepath.Join(testDir, "z-links", "dir")))
+	is.NoErr(os.Symlink(filepath.Join(testDir, "z-links"), filepath.Join(testDir, "z-links", "loop")))
+
+	names := func(cfg stow.ConfigMap) []string {
+		l, err := stow.Dial(local.Kind, cfg)
+		is.NoErr(err)
+		c, err := l.Container("z-links")
+		is.NoErr(err)
+		items, _, err := c.Items(stow.NoPrefix, stow.CursorStart, 100)
+		is.NoErr(err)
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name())
+		}
+		return names
+	}
+
+	is.Equal(names(stow.ConfigMap{local.ConfigKeyPath: testDir}),
+		[]string{"dir", "file", "hardlink", "hardtarget", "loop", "symlink", "symtarget"})
+	// the symlink made by setup has a relative target, so it is
+	// broken and left out when following symlinks
+	is.Equal(names(stow.ConfigMap{local.ConfigKeyPath: testDir, local.ConfigFollowSymlinks: "true"}),
+		[]string{"dir/item1", "dir/item2", "dir/item3", "file", "hardlink", "hardtarget", "symtarget"})
+
+	cfg := stow.ConfigMap{local.ConfigKeyPath: testDir, local.ConfigFollowSymlinks: "true"}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("z-links")
+	is.NoErr(err)
+	item, err := c.Item("file")
+	is.NoErr(err)
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.False(md[local.MetadataIsSymlink])
+	is.Equal(md[local.MetadataSize], int64(len("symlink target")))
+}
+
+func TestCreateNestedContainer(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	l, err := stow.Dial(local.Kind, stow.ConfigMap{local.ConfigKeyPath: testDir})
+	is.NoErr(err)
+	_, err = l.CreateContainer("a/b/c")
+	is.Err(err)
+
+	l, err = stow.Dial(local.Kind, stow.ConfigMap{
+		local.ConfigKeyPath:  testDir,
+		local.ConfigMkdirAll: "true",
+	})
+	is.NoErr(err)
+	c, err := l.CreateContainer("a/b/c")
+	is.NoErr(err)
+	is.Equal(c.Name(), "a/b/c")
+	isDir(is, filepath.Join(testDir, "a", "b", "c"))
+
+	// existing containers and files are still errors
+	_, err = l.CreateContainer("a/b/c")
+	is.Err(err)
+	_, err = l.CreateContainer("rootitem")
+	is.Err(err)
+	_, err = l.CreateContainer("rootitem/d")
+	is.Err(err)
+}
+
+func TestItemByURL(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	_, err = c.Put("with space", strings.NewReader("spaced"), 6, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+	item, err := c.Item("with space")
+	is.NoErr(err)
+
+	// round trip through the string form of the url
+	u, err := url.Parse(item.URL().String())
+	is.NoErr(err)
+	i, err := l.ItemByURL(u)
+	is.NoErr(err)
+	is.Equal(i.ID(), item.ID())
+	is.Equal(i.Name(), "with space")
+	md, err := i.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataUser], map[string]interface{}{"a": "b"})
+
+	// unclean paths are cleaned
+	i, err = l.ItemByURL(&url.URL{
+		Scheme: "file",
+		Path:   filepath.ToSlash(filepath.Join(testDir, "one")) + "/../three/./item1",
+	})
+	is.NoErr(err)
+	is.Equal(i.Name(), "item1")
+
+	_, err = l.ItemByURL(&url.URL{
+		Scheme: "file",
+		Path:   filepath.ToSlash(filepath.Join(testDir, "three", "nope")),
+	})
+	is.True(errors.Is(err, stow.ErrNotFound))
+
+	// paths outside the location are rejected
+	_, err = l.ItemByURL(&url.URL{
+		Scheme: "file",
+		Path:   filepath.ToSlash(filepath.Join(testDir, "..", "three", "item1")),
+	})
+	is.Err(err)
+	_, err = l.ItemByURL(&url.URL{
+		Scheme: "s3",
+		Path:   filepath.ToSlash(filepath.Join(testDir, "three", "item1")),
+	})
+	is.Err(err)
+}
+
+func TestPing(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	root := filepath.Join(testDir, "root")
+	is.NoErr(os.Mkdir(root, 0777))
+	l, err := stow.Dial(local.Kind, stow.ConfigMap{local.ConfigKeyPath: root})
+	is.NoErr(err)
+
+	_, ok := l.(stow.Pinger)
+	is.True(ok)
+	is.NoErr(stow.Ping(l))
+	// the temporary file is removed
+	f, err := os.Open(root)
+	is.NoErr(err)
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	is.NoErr(err)
+	is.Equal(len(names), 0)
+
+	is.NoErr(os.Remove(root))
+	is.Err(stow.Ping(l))
+}
+
+func TestValidate(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	for path, valid := range map[string]bool{
+		testDir:                                  true,
+		filepath.Join(testDir, "new"):            true, // can be created
+		filepath.Join(testDir, "missing", "new"): false,
+		filepath.Join(testDir, "rootitem"):       false,
+	} {
+		cfg := stow.ConfigMap{local.ConfigKeyPath: path}
+		err := stow.Validate(local.Kind, cfg)
+		is.Equal(err == nil, valid)
+	}
+	_, err = stow.Dial(local.Kind, stow.ConfigMap{local.ConfigKeyPath: filepath.Join(testDir, "rootitem")})
+	is.Err(err)
+	is.True(strings.Contains(err.Error(), "path must be directory"))
+
+	// dialing a path that can be created creates it
+	_, err = stow.Dial(local.Kind, stow.ConfigMap{local.ConfigKeyPath: filepath.Join(testDir, "new")})
+	is.NoErr(err)
+	isDir(is, filepath.Join(testDir, "new"))
+	_, err = stow.Dial(local.Kind, stow.ConfigMap{local.ConfigKeyPath: filepath.Join(testDir, "missing", "new")})
+	is.Err(err)
+}
+
+func TestVirtualContainers(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:           testDir,
+		local.ConfigVirtualContainers: `{"logs": {"path": "two", "include": "*.log"}}`,
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	two, err := l.Container("two")
+	is.NoErr(err)
+	for _, name := range []string{"a.log", "b.txt", "sub/c.log"} {
+		_, err = two.Put(name, strings.NewReader(name), int64(len(name)), nil)
+		is.NoErr(err)
+	}
+
+	c, err := l.Container("logs")
+	is.NoErr(err)
+	is.Equal(c.ID(), "logs")
+	items, _, err := c.Items(stow.NoPrefix, stow.CursorStart, 10)
+	is.NoErr(err)
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Name())
+	}
+	is.Equal(names, []string{"a.log", "sub/c.log"})
+	_, err = c.Item("b.txt")
+	is.True(errors.Is(err, stow.ErrNotFound))
+
+	_, err = c.Put("d.log", strings.NewReader("d"), 1, nil)
+	is.NoErr(err)
+	_, err = c.Put("d.txt", strings.NewReader("d"), 1, nil)
+	is.Err(err)
+	_, err = os.Stat(filepath.Join(testDir, "two", "d.txt"))
+	is.True(os.IsNotExist(err))
+	is.Err(l.RemoveContainer("logs"))
+
+	cfg[local.ConfigVirtualContainers] = `{"logs": {"path": "two", "include": "[.log"}}`
+	_, err = stow.Dial(local.Kind, cfg)
+	is.Err(err)
+}
+
+func TestDirsAsContainers(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:          testDir,
+		local.ConfigDirsAsContainers: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	two, err := l.Container("two")
+	is.NoErr(err)
+	for _, name := range []string{"a", "sub/b", "sub/deeper/c"} {
+		_, err = two.Put(name, strings.NewReader(name), int64(len(name)), nil)
+		is.NoErr(err)
+	}
+
+	cs, _, err := l.Containers("two", stow.CursorStart, 10)
+	is.NoErr(err)
+	var names []string
+	for _, c := range cs {
+		names = append(names, c.Name())
+	}
+	is.Equal(names, []string{"two", "two/sub", "two/sub/deeper"})
+
+	expected := map[string][]string{
+		"two":            {"a"},
+		"two/sub":        {"b"},
+		"two/sub/deeper": {"c"},
+	}
+	for id, want := range expected {
+		c, err := l.Container(id)
+		is.NoErr(err)
+		is.Equal(c.Name(), id)
+		items, _, err := c.Items(stow.NoPrefix, stow.CursorStart, 10)
+		is.NoErr(err)
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name())
+		}
+		sort.Strings(names)
+		is.Equal(names, want)
+	}
+}
+
+func TestConfigKeys(t *testing.T) {
+	is := is.New(t)
+	is.Equal(stow.RequiredConfigKeys(local.Kind), []string{local.ConfigKeyPath})
+	keys := stow.ConfigKeys(local.Kind)
+	is.Equal(keys[0], local.ConfigKeyPath)
+	is.True(len(keys) > 1)
+}
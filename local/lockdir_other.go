@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package local
+
+import "os"
+
+// lockDir takes a lock on the directory at path, which is not
+// supported on this platform, so updates are only serialized
+// within the process.
+func lockDir(path string) (func(), error) {
+	return func() {}, nil
+}
+
+// lockFile takes a lock on the opened file, which is not supported
+// on this platform, so updates are only serialized within the process.
+func lockFile(f *os.File) (func(), error) {
+	return func() {}, nil
+}
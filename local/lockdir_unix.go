@@ -0,0 +1,40 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package local
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockDir takes an exclusive lock on the directory at path, waiting
+// for other processes to release it. The returned func releases it.
+func lockDir(path string) (func(), error) {
+	d, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lockFile(d)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		d.Close()
+	}, nil
+}
+
+// lockFile takes an exclusive advisory lock on the opened file,
+// waiting for other processes, or other opened files of this one,
+// to release it. The returned func releases it.
+func lockFile(f *os.File) (func(), error) {
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		return nil, err
+	}
+	return func() {
+		unix.Flock(int(f.Fd()), unix.LOCK_UN)
+	}, nil
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package local
+
+// longPath gets the path unchanged, as only Windows limits
+// the length of paths.
+func longPath(path string) string {
+	return path
+}
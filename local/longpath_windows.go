@@ -0,0 +1,25 @@
+package local
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxPath is the MAX_PATH limit on the length of paths on Windows.
+const maxPath = 260
+
+// longPath gets the extended-length form of path, prefixed with
+// `\\?\`, if it is an absolute path too long for MAX_PATH, so that
+// deep trees and long item names can be written and read. Extended
+// paths are not cleaned by Windows, so the path is cleaned first.
+func longPath(path string) string {
+	if len(path) < maxPath || !filepath.IsAbs(path) || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+	path = filepath.Clean(path)
+	if strings.HasPrefix(path, `\\`) {
+		// a UNC path, \\server\share\...
+		return `\\?\UNC\` + path[2:]
+	}
+	return `\\?\` + path
+}
@@ -0,0 +1,339 @@
+package local
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/graymeta/stow"
+)
+
+// MetaIndexFile is the name of the file at the root of a container
+// that holds the user metadata of all of its items when
+// ConfigMetaIndex is enabled. It ends with MetadataFileExt, so it is
+// never listed as an item.
+const MetaIndexFile = "._index" + MetadataFileExt
+
+// indexLock serializes index updates within this process, on top of
+// the file lock that serializes them across processes.
+var indexLock sync.Mutex
+
+// metaIndex maps the slash separated names of items, relative to the
+// container, to their user metadata.
+type metaIndex map[string]map[string]interface{}
+
+// indexCache holds the parsed metadata index files of a location, so
+// that getting the metadata of each listed item does not read and
+// parse the whole index again.
+type indexCache struct {
+	lock    sync.Mutex
+	indexes map[string]*cachedIndex
+}
+
+// cachedIndex is a parsed metadata index file, with the JSON encoding
+// of the user metadata of each item, which is current as long as the
+// index file is the same file with the same size and modification
+// time.
+type cachedIndex struct {
+	info    os.FileInfo
+	entries map[string]json.RawMessage
+}
+
+// get gets the cached index of the index file at path, if it has the
+// file info.
+func (ic *indexCache) get(path string, info os.FileInfo) (map[string]json.RawMessage, bool) {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+	cached, ok := ic.indexes[path]
+	if !ok || !os.SameFile(cached.info, info) || cached.info.Size() != info.Size() ||
+		!cached.info.ModTime().Equal(info.ModTime()) {
+		return nil, false
+	}
+	return cached.entries, true
+}
+
+// put caches the index read from the index file at path, which had
+// the file info.
+func (ic *indexCache) put(path string, info os.FileInfo, entries map[string]json.RawMessage) {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+	if ic.indexes == nil {
+		ic.indexes = make(map[string]*cachedIndex)
+	}
+	ic.indexes[path] = &cachedIndex{info: info, entries: entries}
+}
+
+// forget drops the cached index of the index file at path.
+func (ic *indexCache) forget(path string) {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+	delete(ic.indexes, path)
+}
+
+// indexKey gets the key of the file at path in the metadata index
+// of the container.
+func (c *container) indexKey(path string) (string, error) {
+	rel, err := filepath.Rel(c.path, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// readIndex reads the metadata index of the container, which is
+// empty if there is no index file yet.
+func (c *container) readIndex() (metaIndex, error) {
+	b, err := ioutil.ReadFile(filepath.Join(c.path, MetaIndexFile))
+	if os.IsNotExist(err) {
+		return metaIndex{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	idx := metaIndex{}
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return nil, fmt.Errorf("bad metadata index: %w", err)
+	}
+	return idx, nil
+}
+
+// updateIndex calls fn with the metadata index of the container and
+// writes it back, holding a lock on the container directory throughout
+// so that concurrent updates are not lost. The index file is replaced
+// atomically, so readers never see a partly written index.
+func (c *container) updateIndex(fn func(idx metaIndex) error) error {
+	indexLock.Lock()
+	defer indexLock.Unlock()
+	unlock, err := lockDir(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to lock metadata index: %w", err)
+	}
+	defer unlock()
+	idx, err := c.readIndex()
+	if err != nil {
+		return err
+	}
+	if err := fn(idx); err != nil {
+		return err
+	}
+	j, err := json.MarshalIndent(idx, "", "    ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(c.path, MetaIndexFile)
+	defer c.location.indexes.forget(path)
+	return writeFileAtomic(path, j, 0644)
+}
+
+// indexEntries gets the JSON encoding of the user metadata of each item
+// in the metadata index of the container, which is nil if there is no
+// index file yet. The index file is only parsed again once it changes.
+func (c *container) indexEntries() (map[string]json.RawMessage, error) {
+	path := filepath.Join(c.path, MetaIndexFile)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if entries, ok := c.location.indexes.get(path, info); ok {
+		return entries, nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// the info of the opened file, which may have been replaced since
+	info, err = f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("bad metadata index: %w", err)
+	}
+	c.location.indexes.put(path, info, entries)
+	return entries, nil
+}
+
+// writeMeta writes the user metadata for the file at path into the
+// metadata index of the container if it is enabled, removing any
+// metadata file left from before, or else into the metadata file of
+// the file. The path of the metadata file is returned, or an empty
+// string if none was written.
+func (c *container) writeMeta(path string, metadata map[string]interface{}) (string, error) {
+	if !c.location.metaIndex {
+		return writeMeta(path, metadata)
+	}
+	key, err := c.indexKey(path)
+	if err != nil {
+		return "", err
+	}
+	md := userMetadata(metadata)
+	err = c.updateIndex(func(idx metaIndex) error {
+		if len(md) == 0 {
+			delete(idx, key)
+		} else {
+			idx[key] = md
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := os.Remove(path + MetadataFileExt); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return "", nil
+}
+
+// indexedMeta gets the user metadata of the file at path from the
+// metadata index of the container, or nil if it has none.
+func (c *container) indexedMeta(path string) (map[string]interface{}, error) {
+	b, err := c.indexedRawMeta(path)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	var md map[string]interface{}
+	if err := json.Unmarshal(b, &md); err != nil {
+		return nil, fmt.Errorf("bad metadata index: %w", err)
+	}
+	return md, nil
+}
+
+// indexedRawMeta gets the JSON encoding of the user metadata of the
+// file at path in the metadata index of the container, or nil if it
+// has none.
+func (c *container) indexedRawMeta(path string) (json.RawMessage, error) {
+	key, err := c.indexKey(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := c.indexEntries()
+	if err != nil {
+		return nil, err
+	}
+	return entries[key], nil
+}
+
+// removeIndexed removes the file at path from the metadata index of
+// the container, if it is in it.
+func (c *container) removeIndexed(path string) error {
+	key, err := c.indexKey(path)
+	if err != nil {
+		return err
+	}
+	entries, err := c.indexEntries()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[key]; !ok {
+		return nil
+	}
+	return c.updateIndex(func(idx metaIndex) error {
+		delete(idx, key)
+		return nil
+	})
+}
+
+// moveIndexed moves the metadata indexed for the file at src to the
+// file at dst, replacing any metadata indexed for dst.
+func (c *container) moveIndexed(src, dst string) error {
+	srcKey, err := c.indexKey(src)
+	if err != nil {
+		return err
+	}
+	dstKey, err := c.indexKey(dst)
+	if err != nil {
+		return err
+	}
+	return c.updateIndex(func(idx metaIndex) error {
+		if md, ok := idx[srcKey]; ok {
+			idx[dstKey] = md
+			delete(idx, srcKey)
+		} else {
+			delete(idx, dstKey)
+		}
+		return nil
+	})
+}
+
+// MigrateToMetaIndex moves the user metadata of the items of a local
+// container from their metadata files into the metadata index of the
+// container, so that it can be used with ConfigMetaIndex enabled.
+// Metadata that is already indexed is kept, and the metadata files are
+// removed once the index is written. Metadata files of missing files
+// are left alone.
+func MigrateToMetaIndex(c stow.Container) error {
+	lc, ok := c.(*container)
+	if !ok {
+		return errors.New("not a local container")
+	}
+	var sidecars []string
+	err := filepath.Walk(lc.path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(p, MetadataFileExt) || p == filepath.Join(lc.path, MetaIndexFile) {
+			return nil
+		}
+		if _, err := os.Lstat(strings.TrimSuffix(p, MetadataFileExt)); err != nil {
+			return nil
+		}
+		sidecars = append(sidecars, p)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(sidecars) == 0 {
+		return nil
+	}
+	err = lc.updateIndex(func(idx metaIndex) error {
+		for _, sidecar := range sidecars {
+			path := strings.TrimSuffix(sidecar, MetadataFileExt)
+			key, err := lc.indexKey(path)
+			if err != nil {
+				return err
+			}
+			if _, ok := idx[key]; ok {
+				continue
+			}
+			b, err := ioutil.ReadFile(sidecar)
+			if err != nil {
+				return err
+			}
+			var md map[string]interface{}
+			if err := json.Unmarshal(b, &md); err != nil {
+				return fmt.Errorf("bad metadata file %s: %w", sidecar, err)
+			}
+			if len(md) > 0 {
+				idx[key] = md
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, sidecar := range sidecars {
+		if err := os.Remove(sidecar); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
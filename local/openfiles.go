@@ -0,0 +1,82 @@
+package local
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// readFile is a file opened for reading, either an *os.File or, if
+// the number of open files is limited, a *limitedFile.
+type readFile interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+	Stat() (os.FileInfo, error)
+}
+
+// openFiles limits the number of files held open for reading or for
+// writing. The nil value does not limit them.
+type openFiles chan struct{}
+
+// acquire waits for a free slot, and gets a func releasing it,
+// which may be called more than once.
+func (o openFiles) acquire() func() {
+	if o == nil {
+		return func() {}
+	}
+	o <- struct{}{}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-o })
+	}
+}
+
+// open opens the file at path for reading, holding a slot until
+// the file is closed.
+func (o openFiles) open(path string) (readFile, error) {
+	release := o.acquire()
+	f, err := os.Open(longPath(path))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if o == nil {
+		return f, nil
+	}
+	return &limitedFile{File: f, release: release}, nil
+}
+
+// limitedFile is a file holding a slot of the open files, which is
+// released when it is closed.
+type limitedFile struct {
+	*os.File
+	release func()
+}
+
+func (f *limitedFile) Close() error {
+	err := f.File.Close()
+	f.release()
+	return err
+}
+
+// osFile gets the *os.File read by r, if it is one or a *limitedFile.
+func osFile(r io.Reader) (*os.File, bool) {
+	switch f := r.(type) {
+	case *os.File:
+		return f, true
+	case *limitedFile:
+		return f.File, true
+	}
+	return nil, false
+}
+
+// openFile opens the file of the item for reading, holding a slot
+// of the open files of its location, if they are limited.
+func (i *item) openFile() (readFile, error) {
+	if i.container == nil {
+		return openFiles(nil).open(i.path)
+	}
+	return i.container.location.openFiles.open(i.path)
+}
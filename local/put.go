@@ -0,0 +1,713 @@
+package local
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/graymeta/stow"
+)
+
+// tempPrefix is the prefix of the temporary files written by Put.
+const tempPrefix = ".stow-tmp-"
+
+// writeFile writes the contents of r to the file at path, and checks
+// that size bytes were written. The permissions, and optionally the
+// owner, of the file are set from the metadata when present.
+// Unless atomic puts are disabled, the contents are written to a
+// temporary file in the same directory, or the temp dir, which is only
+// renamed into place once complete. On error the temporary file is removed.
+// If fsync is enabled, the file and its directory are synced to disk
+// before returning.
+// If reflinks are enabled, files on the same device are cloned or
+// hardlinked instead of being copied.
+// If symlink creation is enabled and the metadata describes a symlink,
+// the symlink is created and r is not read.
+// The file info of the written file is returned, along with its
+// content hash if content ETags are enabled and the contents were
+// copied through a buffer, or nil if the file was linked.
+func (c *container) writeFile(path string, r io.Reader, size int64, metadata map[string]interface{}) (*writtenFile, error) {
+	if target, ok, err := c.symlinkTarget(path, metadata); ok || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, c.writeSymlink(path, target)
+	}
+	release := c.location.writeFiles.acquire()
+	defer release()
+	if src, ok := c.linkSource(r, size); ok {
+		err := c.linkFile(path, src, metadata)
+		if err != errNotLinked {
+			return nil, err
+		}
+	}
+	r, h := c.hashContents(r)
+	if !c.location.atomicPut {
+		f, err := os.Create(longPath(path))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		err = c.copyFile(f, r, size)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.finishFile(f, metadata); err != nil {
+			return nil, err
+		}
+		written := newWrittenFile(f, path, h)
+		return written, c.syncDir(filepath.Dir(path))
+	}
+	dir, err := c.stagingDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	f, err := createTemp(longPath(dir))
+	if err != nil {
+		return nil, err
+	}
+	tmp := f.Name()
+	var written *writtenFile
+	err = c.copyFile(f, r, size)
+	if err == nil {
+		err = c.finishFile(f, metadata)
+	}
+	if err == nil {
+		written = newWrittenFile(f, path, h)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, longPath(path))
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return written, c.syncDir(filepath.Dir(path))
+}
+
+// writtenFile is what writing a file found out about it, so that
+// the item put does not stat or read the file again.
+type writtenFile struct {
+	// info is the file info of the file.
+	info os.FileInfo
+	// hash is the hex encoded MD5 hash of the contents, if known.
+	hash string
+}
+
+// newWrittenFile gets the writtenFile of f, which was written to be
+// the file at path, with the hash of its contents if h is not nil.
+// It is nil if f cannot be stat'ed.
+func newWrittenFile(f *os.File, path string, h hash.Hash) *writtenFile {
+	info, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	// the file may have been written with a temporary name
+	w := &writtenFile{info: fileinfo{FileInfo: info, name: filepath.Base(path)}}
+	if h != nil {
+		w.hash = hex.EncodeToString(h.Sum(nil))
+	}
+	return w
+}
+
+// hashContents wraps r to hash the contents read from it, if content
+// ETags are enabled. Files, on their own or wrapped by PutContext, are
+// not wrapped, so that copyFile can still copy them as files.
+func (c *container) hashContents(r io.Reader) (io.Reader, hash.Hash) {
+	if !c.location.contentETag {
+		return r, nil
+	}
+	if cr, ok := r.(*ctxReader); ok {
+		if _, ok := osFile(cr.r); ok {
+			return r, nil
+		}
+	}
+	if _, ok := osFile(r); ok {
+		return r, nil
+	}
+	h := md5.New()
+	if r == nil {
+		// nothing is read from empty contents
+		return r, h
+	}
+	return io.TeeReader(r, h), h
+}
+
+// stagingDir gets the directory to write the temporary file for a
+// file in dir to: the temp dir if there is one on the same device
+// as dir, and dir otherwise.
+func (c *container) stagingDir(dir string) (string, error) {
+	tempDir := c.location.tempDir
+	if tempDir == "" {
+		return dir, nil
+	}
+	tempInfo, err := os.Stat(tempDir)
+	if err != nil {
+		return "", fmt.Errorf("bad temp dir: %w", err)
+	}
+	if !tempInfo.IsDir() {
+		return "", errors.New("temp dir must be directory")
+	}
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !sameDevice(tempInfo, dirInfo) {
+		c.location.tempDirWarning.Do(func() {
+			log.Printf("stow/local: temp dir %s is not on the same device as %s, writing temporary files next to the destination", tempDir, dir)
+		})
+		return dir, nil
+	}
+	return tempDir, nil
+}
+
+// writeNewFile writes the contents of r to the file at path like
+// writeFile, but fails with stow.ErrAlreadyExists if the file exists.
+func (c *container) writeNewFile(path string, r io.Reader, size int64, metadata map[string]interface{}) (*writtenFile, error) {
+	if target, ok, err := c.symlinkTarget(path, metadata); ok || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		err = os.Symlink(target, path)
+		if os.IsExist(err) {
+			return nil, stow.ErrAlreadyExists
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, c.syncDir(filepath.Dir(path))
+	}
+	release := c.location.writeFiles.acquire()
+	defer release()
+	f, err := os.OpenFile(longPath(path), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if os.IsExist(err) {
+		return nil, stow.ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	r, h := c.hashContents(r)
+	var written *writtenFile
+	err = c.copyFile(f, r, size)
+	if err == nil {
+		err = c.finishFile(f, metadata)
+	}
+	if err == nil {
+		written = newWrittenFile(f, path, h)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(longPath(path))
+		return nil, err
+	}
+	return written, c.syncDir(filepath.Dir(path))
+}
+
+// symlinkTarget gets the target of the symlink to create at path, if
+// symlink creation is enabled and the metadata describes a symlink.
+// Targets that resolve to outside of the container are rejected.
+func (c *container) symlinkTarget(path string, metadata map[string]interface{}) (string, bool, error) {
+	if !c.location.allowSymlinkCreate {
+		return "", false, nil
+	}
+	isSymlink, _ := metadata[MetadataIsSymlink].(bool)
+	if s, ok := metadata[MetadataIsSymlink].(string); ok {
+		isSymlink = s == "true"
+	}
+	target, _ := metadata[MetadataLink].(string)
+	if !isSymlink || target == "" {
+		return "", false, nil
+	}
+	resolved := target
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(filepath.Dir(path), resolved)
+	}
+	rel, err := filepath.Rel(c.path, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false, fmt.Errorf("symlink target %s is outside of the container", target)
+	}
+	return target, true, nil
+}
+
+// writeSymlink creates a symlink to target at path, replacing any
+// existing file. Unless atomic puts are disabled, the symlink is
+// created with a temporary name, which is renamed into place.
+func (c *container) writeSymlink(path, target string) error {
+	dir := filepath.Dir(path)
+	if !c.location.atomicPut {
+		if err := os.Remove(longPath(path)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Symlink(target, longPath(path)); err != nil {
+			return err
+		}
+		return c.syncDir(dir)
+	}
+	staging, err := c.stagingDir(dir)
+	if err != nil {
+		return err
+	}
+	tmp, err := symlinkTemp(longPath(staging), target)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, longPath(path)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return c.syncDir(dir)
+}
+
+// errNotLinked is returned by linkFile when the file could be neither
+// cloned nor hardlinked, and needs to be copied.
+var errNotLinked = errors.New("not linked")
+
+// linkSource gets the file that r reads, on its own or wrapped by
+// PutContext, if reflinks are enabled and r reads all of a regular
+// file on the same device as the container. Empty files are not
+// linked.
+func (c *container) linkSource(r io.Reader, size int64) (*os.File, bool) {
+	if !c.location.reflink || size == 0 {
+		return nil, false
+	}
+	if cr, ok := r.(*ctxReader); ok {
+		if cr.ctx.Err() != nil {
+			return nil, false
+		}
+		r = cr.r
+	}
+	src, ok := osFile(r)
+	if !ok {
+		return nil, false
+	}
+	if pos, err := src.Seek(0, io.SeekCurrent); err != nil || pos != 0 {
+		return nil, false
+	}
+	info, err := src.Stat()
+	if err != nil || !info.Mode().IsRegular() || (size >= 0 && info.Size() != size) {
+		return nil, false
+	}
+	dir, err := os.Stat(c.path)
+	if err != nil || !sameDevice(info, dir) {
+		return nil, false
+	}
+	return src, true
+}
+
+// linkFile puts src at path by cloning it into a temporary file, or
+// failing that by hardlinking it to a temporary name, which is then
+// renamed into place. The temporary file is made in the staging
+// directory, like the temporary files of atomic puts. The attributes
+// of clones are set from the metadata, but hardlinks keep those of src.
+// If src could not be linked errNotLinked is returned.
+func (c *container) linkFile(path string, src *os.File, metadata map[string]interface{}) error {
+	dir := filepath.Dir(path)
+	staging, err := c.stagingDir(dir)
+	if err != nil {
+		return err
+	}
+	f, err := createTemp(longPath(staging))
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	err = cloneFile(f, src)
+	if err == nil {
+		err = c.finishFile(f, metadata)
+	} else {
+		// fall back to a hardlink
+		os.Remove(tmp)
+		tmp, err = linkTemp(longPath(staging), longPath(src.Name()))
+		if err != nil {
+			f.Close()
+			return errNotLinked
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, longPath(path))
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return c.syncDir(dir)
+}
+
+// copyChunkSize is the number of bytes copied between context
+// checks when a file is copied for PutContext.
+const copyChunkSize = 8 << 20
+
+// copyFile copies r into f, and checks that size bytes were copied
+// unless size is negative, which means the size is unknown.
+// Nothing is read when size is zero, so that any reader, even a nil
+// one, makes an empty file.
+// When r is a file, on its own or wrapped by PutContext, the copy is
+// left to io.Copy without any adapters hiding the file, so that
+// platforms such as Linux can copy the data inside the kernel, and if
+// sparse files are enabled the holes of a sparse file are kept.
+// Other readers are copied through a buffer from the pool of the
+// location.
+func (c *container) copyFile(f *os.File, r io.Reader, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	var (
+		n   int64
+		err error
+	)
+	src, isFile := osFile(r)
+	cr, isCtx := r.(*ctxReader)
+	if isCtx {
+		src, isFile = osFile(cr.r)
+	}
+	switch {
+	case isFile && c.location.sparse && isSparse(src):
+		ctx := context.Background()
+		if isCtx {
+			ctx = cr.ctx
+		}
+		n, err = copySparse(ctx, f, src)
+	case isFile && isCtx:
+		n, err = copyFileContext(cr.ctx, f, src)
+	case isFile:
+		n, err = io.Copy(f, src)
+	default:
+		buf := c.location.copyBuffers.Get().(*[]byte)
+		// hide f.ReadFrom, which would copy through its own buffer
+		n, err = io.CopyBuffer(writerOnly{f}, r, *buf)
+		c.location.copyBuffers.Put(buf)
+	}
+	if err != nil {
+		return err
+	}
+	if size >= 0 && n != size {
+		return errors.New("bad size")
+	}
+	return nil
+}
+
+// writerOnly hides all methods but Write of the io.Writer it wraps.
+type writerOnly struct {
+	io.Writer
+}
+
+// copyFileContext copies src into f in chunks of copyChunkSize,
+// failing with the context error once the context is done.
+func copyFileContext(ctx context.Context, f, src *os.File) (int64, error) {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, err := io.CopyN(f, src, copyChunkSize)
+		total += n
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// finishFile sets the attributes of the written file f from the
+// metadata, and syncs its contents to disk if fsync is enabled.
+func (c *container) finishFile(f *os.File, metadata map[string]interface{}) error {
+	if err := c.setAttrs(f, metadata); err != nil {
+		return err
+	}
+	if !c.location.fsync {
+		return nil
+	}
+	return f.Sync()
+}
+
+// syncDir syncs the directory at path to disk if fsync is enabled,
+// so that new and renamed files in it survive a crash.
+// Directories cannot be synced on Windows, where it does nothing.
+func (c *container) syncDir(path string) error {
+	if !c.location.fsync || runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// setAttrs sets the permissions of f from the MetadataMode or
+// MetadataPerm metadata, or to the default mode if there is one, its
+// extended attributes from the MetadataXattr metadata if they are
+// enabled, and its owner from the uid and gid metadata if ownership
+// is preserved. Missing metadata is ignored.
+func (c *container) setAttrs(f *os.File, metadata map[string]interface{}) error {
+	if xattrs, ok := metadata[MetadataXattr].(map[string]interface{}); ok && c.location.readXattrs {
+		if err := writeXattrs(f, xattrs); err != nil {
+			return err
+		}
+	}
+	perm, ok := metadataPerm(metadata)
+	if !ok && c.location.defaultMode != 0 {
+		perm, ok = c.location.defaultMode, true
+	}
+	if ok {
+		if err := f.Chmod(perm); err != nil {
+			return err
+		}
+	}
+	if !c.location.preserveOwnership {
+		return nil
+	}
+	uid, uidOK := metadataInt(metadata["uid"])
+	gid, gidOK := metadataInt(metadata["gid"])
+	if !uidOK || !gidOK {
+		return nil
+	}
+	return f.Chown(int(uid), int(gid))
+}
+
+// metadataPerm gets the permission bits described by the MetadataMode
+// or MetadataPerm metadata.
+func metadataPerm(metadata map[string]interface{}) (os.FileMode, bool) {
+	if mode, ok := metadata[MetadataMode].(string); ok {
+		m, err := strconv.ParseUint(mode, 8, 32)
+		if err == nil {
+			return os.FileMode(m).Perm(), true
+		}
+	}
+	perm, ok := metadata[MetadataPerm].(string)
+	if !ok || len(perm) < 9 {
+		return 0, false
+	}
+	var m os.FileMode
+	for i, c := range perm[len(perm)-9:] {
+		switch {
+		case c == '-':
+		case c == rune("rwxrwxrwx"[i]):
+			m |= 1 << uint(8-i)
+		default:
+			return 0, false
+		}
+	}
+	return m, true
+}
+
+// metadataInt gets an integer metadata value, which may have been
+// decoded from JSON or written out as a string.
+func metadataInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	case string:
+		i, err := strconv.ParseInt(n, 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// withContentMD5 gets a copy of the metadata with the MD5 checksum of
+// the contents of the file at path under stow.MetadataContentMD5, if
+// recording checksums is enabled. Symlinks are left as they are.
+func (c *container) withContentMD5(path string, metadata map[string]interface{}) (map[string]interface{}, error) {
+	if !c.location.recordMD5 {
+		return metadata, nil
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode()&os.ModeSymlink == os.ModeSymlink {
+		return metadata, nil
+	}
+	sum, err := md5File(path)
+	if err != nil {
+		return nil, err
+	}
+	md := make(map[string]interface{}, len(metadata)+1)
+	for k, v := range metadata {
+		md[k] = v
+	}
+	md[stow.MetadataContentMD5] = sum
+	return md, nil
+}
+
+// createTemp creates a new temporary file in dir. Unlike
+// ioutil.TempFile the file is created with the same permissions
+// as os.Create.
+func createTemp(dir string) (*os.File, error) {
+	for try := 0; ; try++ {
+		name := filepath.Join(dir, tempPrefix+strconv.FormatUint(uint64(rand.Int63()), 36))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) && try < 10000 {
+			continue
+		}
+		return f, err
+	}
+}
+
+// linkTemp makes a hardlink to the file at path with a new
+// temporary name in dir.
+func linkTemp(dir, path string) (string, error) {
+	for try := 0; ; try++ {
+		name := filepath.Join(dir, tempPrefix+strconv.FormatUint(uint64(rand.Int63()), 36))
+		err := os.Link(path, name)
+		if os.IsExist(err) && try < 10000 {
+			continue
+		}
+		return name, err
+	}
+}
+
+// symlinkTemp makes a symlink to target with a new temporary
+// name in dir.
+func symlinkTemp(dir, target string) (string, error) {
+	for try := 0; ; try++ {
+		name := filepath.Join(dir, tempPrefix+strconv.FormatUint(uint64(rand.Int63()), 36))
+		err := os.Symlink(target, name)
+		if os.IsExist(err) && try < 10000 {
+			continue
+		}
+		return name, err
+	}
+}
+
+// isTemp gets whether the file at path is a temporary file
+// written by Put.
+func isTemp(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), tempPrefix)
+}
+
+// ctxReader is an io.Reader that fails with the context error
+// once the context is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// ctxReadCloser is a ctxReader that closes the underlying reader.
+type ctxReadCloser struct {
+	ctxReader
+	io.Closer
+}
+
+// fileWriter writes the contents of an item put with PutWriter to
+// a temporary file.
+type fileWriter struct {
+	container *container
+	name      string
+	path      string
+	size      int64
+	metadata  map[string]interface{}
+	f         *os.File
+	release   func() // releases the open file slot of f
+	n         int64
+	closed    bool
+}
+
+func (w *fileWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	n, err := w.f.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// Close renames the temporary file into place, and writes the
+// metadata of the item. The temporary file is removed if the
+// wrong number of bytes was written, or anything fails.
+func (w *fileWriter) Close() (err error) {
+	defer wrapErr(&err, "put", w.name)
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	w.closed = true
+	defer w.release()
+	tmp := w.f.Name()
+	if w.size >= 0 && w.n != w.size {
+		err = errors.New("bad size")
+	}
+	if err == nil {
+		err = w.container.finishFile(w.f, w.metadata)
+	}
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, longPath(w.path))
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := w.container.syncDir(filepath.Dir(w.path)); err != nil {
+		return err
+	}
+	if err := removeTags(w.path); err != nil {
+		return err
+	}
+	md, err := w.container.withContentMD5(w.path, w.metadata)
+	if err != nil {
+		return err
+	}
+	if _, err := w.container.writeMeta(w.path, md); err != nil {
+		return fmt.Errorf("failed to save meta data: %w", err)
+	}
+	return nil
+}
+
+// CloseWithError abandons the put, removing the temporary file.
+func (w *fileWriter) CloseWithError(error) (err error) {
+	defer wrapErr(&err, "put", w.name)
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.release()
+	err = w.f.Close()
+	if rerr := os.Remove(w.f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
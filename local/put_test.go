@@ -0,0 +1,781 @@
+package local_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+	"github.com/graymeta/stow/local"
+)
+
+type failingReader struct {
+	r io.Reader
+}
+
+func (f failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("failed")
+	}
+	return n, err
+}
+
+func TestAtomicPut(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	// a failed put leaves the existing item and no temporary files
+	_, err = c.Put("item1", failingReader{strings.NewReader("partial")}, 10, nil)
+	is.Err(err)
+	b, err := ioutil.ReadFile(filepath.Join(testDir, "three", "item1"))
+	is.NoErr(err)
+	is.Equal(string(b), "3.1")
+	files, err := ioutil.ReadDir(filepath.Join(testDir, "three"))
+	is.NoErr(err)
+	is.Equal(len(files), 3)
+
+	// a bad size is also rejected
+	_, err = c.Put("item1", strings.NewReader("short"), 10, nil)
+	is.Err(err)
+	b, err = ioutil.ReadFile(filepath.Join(testDir, "three", "item1"))
+	is.NoErr(err)
+	is.Equal(string(b), "3.1")
+
+	_, err = c.Put("item1", strings.NewReader("replaced"), 8, nil)
+	is.NoErr(err)
+	b, err = ioutil.ReadFile(filepath.Join(testDir, "three", "item1"))
+	is.NoErr(err)
+	is.Equal(string(b), "replaced")
+}
+
+func TestNonAtomicPut(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:   testDir,
+		local.ConfigAtomicPut: "false",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	_, err = c.Put("item1", strings.NewReader("short"), 10, nil)
+	is.Err(err)
+	b, err := ioutil.ReadFile(filepath.Join(testDir, "three", "item1"))
+	is.NoErr(err)
+	is.Equal(string(b), "short")
+}
+
+func TestPutNested(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	for _, atomic := range []string{"true", "false"} {
+		cfg := stow.ConfigMap{"path": testDir, local.ConfigAtomicPut: atomic}
+		l, err := stow.Dial(local.Kind, cfg)
+		is.NoErr(err)
+		c, err := l.CreateContainer("nested-" + atomic)
+		is.NoErr(err)
+
+		_, err = c.Put("a/b/put.txt", strings.NewReader("put"), 3, nil)
+		is.NoErr(err)
+		_, err = c.(stow.ConditionalPutter).PutIfNotExists("a/c/new.txt", strings.NewReader("new"), 3, nil)
+		is.NoErr(err)
+		w, err := c.(stow.WriterPutter).PutWriter("d/e/written.txt", 7, nil)
+		is.NoErr(err)
+		_, err = io.WriteString(w, "written")
+		is.NoErr(err)
+		is.NoErr(w.Close())
+		_, f, err := c.(interface {
+			CreateItem(name string) (stow.Item, io.WriteCloser, error)
+		}).CreateItem("f/g/created.txt")
+		is.NoErr(err)
+		is.NoErr(f.Close())
+
+		items, _, err := c.Items(stow.NoPrefix, stow.CursorStart, 10)
+		is.NoErr(err)
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name())
+		}
+		sort.Strings(names)
+		is.Equal(names, []string{"a/b/put.txt", "a/c/new.txt", "d/e/written.txt", "f/g/created.txt"})
+	}
+}
+
+func TestPutKnownInfo(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	for _, contentETag := range []string{"true", "false"} {
+		cfg := stow.ConfigMap{"path": testDir, local.ConfigContentETag: contentETag}
+		l, err := stow.Dial(local.Kind, cfg)
+		is.NoErr(err)
+		c, err := l.Container("three")
+		is.NoErr(err)
+		for _, put := range []func(string) (stow.Item, error){
+			func(name string) (stow.Item, error) {
+				return c.Put(name, strings.NewReader("known"), 5, nil)
+			},
+			func(name string) (stow.Item, error) {
+				return c.(stow.ConditionalPutter).PutIfNotExists(name, strings.NewReader("known"), 5, nil)
+			},
+		} {
+			item, err := put("known-" + contentETag)
+			is.NoErr(err)
+			// the item does not stat or read the file again
+			is.NoErr(os.Remove(filepath.Join(testDir, "three", "known-"+contentETag)))
+			size, err := item.Size()
+			is.NoErr(err)
+			is.Equal(size, int64(5))
+			etag, err := item.ETag()
+			is.NoErr(err)
+			is.NotEqual(etag, "")
+			if contentETag == "true" {
+				is.Equal(etag, "c90ae688b2a3b1fd0751fd743eb385cd")
+			}
+		}
+	}
+}
+
+func TestPutContext(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	cp, ok := c.(stow.ContextPutter)
+	is.True(ok)
+	ctx, cancel := context.WithCancel(context.Background())
+	item, err := cp.PutContext(ctx, "ctx", strings.NewReader("contents"), 8, nil)
+	is.NoErr(err)
+
+	co, ok := item.(stow.ContextOpener)
+	is.True(ok)
+	rc, err := co.OpenContext(ctx)
+	is.NoErr(err)
+	defer rc.Close()
+	cancel()
+	_, err = ioutil.ReadAll(rc)
+	is.True(errors.Is(err, context.Canceled))
+
+	_, err = cp.PutContext(ctx, "ctx", strings.NewReader("contents"), 8, nil)
+	is.True(errors.Is(err, context.Canceled))
+
+	// cancelling part way through a put
+	ctx, cancel = context.WithCancel(context.Background())
+	r := &cancelReader{r: strings.NewReader("contents"), cancel: cancel}
+	_, err = cp.PutContext(ctx, "ctx2", r, 8, nil)
+	is.True(errors.Is(err, context.Canceled))
+	_, err = c.Item("ctx2")
+	is.True(errors.Is(err, stow.ErrNotFound))
+}
+
+// cancelReader reads one byte at a time, and cancels
+// after the first read.
+type cancelReader struct {
+	r      io.Reader
+	cancel func()
+}
+
+func (c *cancelReader) Read(p []byte) (int, error) {
+	defer c.cancel()
+	return c.r.Read(p[:1])
+}
+
+func TestPutPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	item, err := c.Put("mode", strings.NewReader("x"), 1, map[string]interface{}{local.MetadataMode: "600"})
+	is.NoErr(err)
+	info, err := os.Stat(item.ID())
+	is.NoErr(err)
+	is.Equal(info.Mode().Perm(), os.FileMode(0600))
+
+	item, err = c.Put("perm", strings.NewReader("x"), 1, map[string]interface{}{local.MetadataPerm: "-rwxr-x---"})
+	is.NoErr(err)
+	info, err = os.Stat(item.ID())
+	is.NoErr(err)
+	is.Equal(info.Mode().Perm(), os.FileMode(0750))
+
+	// copying a local item keeps its permissions
+	md, err := item.Metadata()
+	is.NoErr(err)
+	copied, err := c.Put("copied", strings.NewReader("x"), 1, md)
+	is.NoErr(err)
+	info, err = os.Stat(copied.ID())
+	is.NoErr(err)
+	is.Equal(info.Mode().Perm(), os.FileMode(0750))
+}
+
+func TestPutUnknownSize(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	pr, pw := io.Pipe()
+	go func() {
+		io.WriteString(pw, "streamed ")
+		io.WriteString(pw, "contents")
+		pw.Close()
+	}()
+	item, err := c.Put("streamed", pr, -1, nil)
+	is.NoErr(err)
+	size, err := item.Size()
+	is.NoErr(err)
+	is.Equal(size, int64(len("streamed contents")))
+}
+
+func TestPutFile(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	src, err := c.Item("item1")
+	is.NoErr(err)
+
+	for _, name := range []string{"put", "putcontext"} {
+		f, err := os.Open(src.ID())
+		is.NoErr(err)
+		if name == "put" {
+			_, err = c.Put(name, f, 3, nil)
+		} else {
+			cp := c.(stow.ContextPutter)
+			_, err = cp.PutContext(context.Background(), name, f, 3, nil)
+		}
+		f.Close()
+		is.NoErr(err)
+		b, err := ioutil.ReadFile(filepath.Join(testDir, "three", name))
+		is.NoErr(err)
+		is.Equal(string(b), "3.1")
+	}
+}
+
+// benchmarkSize is the size of the file put by the Put benchmarks.
+// Raise it to compare the copies on multi-GB files.
+const benchmarkSize = 256 << 20
+
+// hiddenFile hides the *os.File it wraps from io.Copy, forcing
+// a copy through a user space buffer.
+type hiddenFile struct {
+	r io.Reader
+}
+
+func (h hiddenFile) Read(p []byte) (int, error) {
+	return h.r.Read(p)
+}
+
+func benchmarkPut(b *testing.B, config stow.ConfigMap, wrap func(f *os.File) io.Reader) {
+	testDir, err := ioutil.TempDir("", "stowbench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(testDir)
+	srcPath := filepath.Join(testDir, "src")
+	src, err := os.Create(srcPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := src.Truncate(benchmarkSize); err != nil {
+		b.Fatal(err)
+	}
+	src.Close()
+	cfg := stow.ConfigMap{local.ConfigKeyPath: testDir}
+	for k, v := range config {
+		cfg[k] = v
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	c, err := l.CreateContainer("dst")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(benchmarkSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f, err := os.Open(srcPath)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, err = c.Put("file", wrap(f), benchmarkSize, nil)
+		f.Close()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPutFile(b *testing.B) {
+	benchmarkPut(b, nil, func(f *os.File) io.Reader {
+		return f
+	})
+}
+
+func BenchmarkPutReader(b *testing.B) {
+	benchmarkPut(b, nil, func(f *os.File) io.Reader {
+		return hiddenFile{r: f}
+	})
+}
+
+func BenchmarkPutBufferSize(b *testing.B) {
+	for _, size := range []string{"32768", "1048576"} {
+		b.Run(size, func(b *testing.B) {
+			benchmarkPut(b, stow.ConfigMap{local.ConfigCopyBufferSize: size}, func(f *os.File) io.Reader {
+				return hiddenFile{r: f}
+			})
+		})
+	}
+}
+
+func TestPutCopyBufferSize(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	for _, size := range []string{"1", "7", "abc", "0"} {
+		cfg := stow.ConfigMap{
+			local.ConfigKeyPath:        testDir,
+			local.ConfigCopyBufferSize: size,
+		}
+		if size == "abc" || size == "0" {
+			is.Err(stow.Validate(local.Kind, cfg))
+			_, err = stow.Dial(local.Kind, cfg)
+			is.Err(err)
+			continue
+		}
+		is.NoErr(stow.Validate(local.Kind, cfg))
+		l, err := stow.Dial(local.Kind, cfg)
+		is.NoErr(err)
+		c, err := l.Container("three")
+		is.NoErr(err)
+		name := "buffered-" + size
+		_, err = c.Put(name, hiddenFile{r: strings.NewReader("buffered contents")}, 17, nil)
+		is.NoErr(err)
+		b, err := ioutil.ReadFile(filepath.Join(testDir, "three", name))
+		is.NoErr(err)
+		is.Equal(string(b), "buffered contents")
+	}
+}
+
+func TestPutIfNotExists(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	cp, ok := c.(stow.ConditionalPutter)
+	is.True(ok)
+	_, err = cp.PutIfNotExists("item1", strings.NewReader("new"), 3, nil)
+	is.True(errors.Is(err, stow.ErrAlreadyExists))
+	b, err := ioutil.ReadFile(filepath.Join(testDir, "three", "item1"))
+	is.NoErr(err)
+	is.Equal(string(b), "3.1")
+
+	item, err := cp.PutIfNotExists("sub/new", strings.NewReader("new"), 3, nil)
+	is.NoErr(err)
+	is.Equal(item.Name(), "sub/new")
+	_, err = cp.PutIfNotExists("sub/new", strings.NewReader("newer"), 5, nil)
+	is.True(errors.Is(err, stow.ErrAlreadyExists))
+
+	// a failed put does not leave the file behind
+	_, err = cp.PutIfNotExists("failed", failingReader{r: strings.NewReader("fail")}, 4, nil)
+	is.Err(err)
+	_, err = os.Stat(filepath.Join(testDir, "three", "failed"))
+	is.True(os.IsNotExist(err))
+}
+
+func TestPutFsync(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	for _, atomic := range []string{"true", "false"} {
+		cfg := stow.ConfigMap{
+			local.ConfigKeyPath:   testDir,
+			local.ConfigAtomicPut: atomic,
+			local.ConfigFsync:     "true",
+		}
+		l, err := stow.Dial(local.Kind, cfg)
+		is.NoErr(err)
+		c, err := l.Container("three")
+		is.NoErr(err)
+		name := "synced-" + atomic
+		_, err = c.Put(name, strings.NewReader("synced"), 6, nil)
+		is.NoErr(err)
+		b, err := ioutil.ReadFile(filepath.Join(testDir, "three", name))
+		is.NoErr(err)
+		is.Equal(string(b), "synced")
+	}
+}
+
+func TestPutDefaultMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:     testDir,
+		local.ConfigDefaultMode: "0640",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.CreateContainer("secrets")
+	is.NoErr(err)
+	info, err := os.Stat(filepath.Join(testDir, "secrets"))
+	is.NoErr(err)
+	is.Equal(info.Mode().Perm(), os.FileMode(0750))
+
+	item, err := c.Put("sub/secret", strings.NewReader("x"), 1, nil)
+	is.NoErr(err)
+	info, err = os.Stat(item.ID())
+	is.NoErr(err)
+	is.Equal(info.Mode().Perm(), os.FileMode(0640))
+	info, err = os.Stat(filepath.Join(testDir, "secrets", "sub"))
+	is.NoErr(err)
+	is.Equal(info.Mode().Perm(), os.FileMode(0750))
+
+	// the metadata mode wins
+	item, err = c.Put("mode", strings.NewReader("x"), 1, map[string]interface{}{local.MetadataMode: "600"})
+	is.NoErr(err)
+	info, err = os.Stat(item.ID())
+	is.NoErr(err)
+	is.Equal(info.Mode().Perm(), os.FileMode(0600))
+
+	_, err = stow.Dial(local.Kind, stow.ConfigMap{
+		local.ConfigKeyPath:     testDir,
+		local.ConfigDefaultMode: "999",
+	})
+	is.Err(err)
+}
+
+func TestPutEmpty(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.CreateContainer("empty")
+	is.NoErr(err)
+
+	readers := map[string]io.Reader{
+		"bytes":  bytes.NewReader(nil),
+		"nil":    nil,
+		"unread": strings.NewReader("not read"),
+	}
+	for name, r := range readers {
+		_, err = c.Put(name, r, 0, nil)
+		is.NoErr(err)
+		item, err := c.Item(name)
+		is.NoErr(err)
+		size, err := item.Size()
+		is.NoErr(err)
+		is.Equal(size, int64(0))
+	}
+	items, _, err := c.Items(stow.NoPrefix, stow.CursorStart, 10)
+	is.NoErr(err)
+	is.Equal(len(items), len(readers))
+}
+
+func TestPutWriter(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	dir := filepath.Join(testDir, "three")
+
+	w, err := stow.PutWriter(c, "written", 7, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+	_, err = io.WriteString(w, "wri")
+	is.NoErr(err)
+	// nothing is put until the writer is closed
+	_, err = c.Item("written")
+	is.True(errors.Is(err, stow.ErrNotFound))
+	_, err = io.WriteString(w, "tten")
+	is.NoErr(err)
+	is.NoErr(w.Close())
+	is.Err(w.Close())
+	item, err := c.Item("written")
+	is.NoErr(err)
+	b, err := ioutil.ReadFile(item.ID())
+	is.NoErr(err)
+	is.Equal(string(b), "written")
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataUser], map[string]interface{}{"a": "b"})
+
+	// a bad size is rejected, keeping the existing item
+	w, err = stow.PutWriter(c, "written", 10, nil)
+	is.NoErr(err)
+	_, err = io.WriteString(w, "short")
+	is.NoErr(err)
+	is.Err(w.Close())
+	b, err = ioutil.ReadFile(item.ID())
+	is.NoErr(err)
+	is.Equal(string(b), "written")
+
+	// abandoned puts leave no temporary files
+	w, err = stow.PutWriter(c, "abandoned", -1, nil)
+	is.NoErr(err)
+	_, err = io.WriteString(w, "abandoned")
+	is.NoErr(err)
+	is.NoErr(w.(interface{ CloseWithError(error) error }).CloseWithError(errors.New("abandoned")))
+	_, err = c.Item("abandoned")
+	is.True(errors.Is(err, stow.ErrNotFound))
+	files, err := ioutil.ReadDir(dir)
+	is.NoErr(err)
+	is.Equal(len(files), 5) // the 3 items, and the written item and its metadata
+}
+
+func TestPutRecordMD5(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:   testDir,
+		local.ConfigRecordMD5: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.CreateContainer("scrubbed")
+	is.NoErr(err)
+
+	item, err := c.Put("kept", strings.NewReader("kept"), 4, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataUser], map[string]interface{}{
+		"a":                     "b",
+		stow.MetadataContentMD5: "4d8b6084f3d167b76cac66a22a91be02",
+	})
+	rotten, err := c.(stow.ConditionalPutter).PutIfNotExists("rotten", strings.NewReader("fresh"), 5, nil)
+	is.NoErr(err)
+	is.NoErr(ioutil.WriteFile(rotten.ID(), []byte("rotten"), 0666))
+
+	results := make(map[string]stow.ScrubStatus)
+	err = stow.Scrub(c, stow.NoPrefix, 2, func(res stow.ScrubResult) {
+		results[res.Item.Name()] = res.Status
+	})
+	is.NoErr(err)
+	is.Equal(results, map[string]stow.ScrubStatus{
+		"kept":   stow.ScrubOK,
+		"rotten": stow.ScrubMismatch,
+	})
+}
+
+func TestSetMetadataRecordMD5(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:   testDir,
+		local.ConfigRecordMD5: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.CreateContainer("scrubbed")
+	is.NoErr(err)
+
+	item, err := c.Put("kept", strings.NewReader("kept"), 4, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+	is.NoErr(item.(stow.MetadataSetter).SetMetadata(map[string]interface{}{"c": "d"}))
+	got, err := c.Item("kept")
+	is.NoErr(err)
+	for _, item := range []stow.Item{item, got} {
+		md, err := item.Metadata()
+		is.NoErr(err)
+		is.Equal(md[local.MetadataUser], map[string]interface{}{
+			"c":                     "d",
+			stow.MetadataContentMD5: "4d8b6084f3d167b76cac66a22a91be02",
+		})
+	}
+	results := make(map[string]stow.ScrubStatus)
+	err = stow.Scrub(c, stow.NoPrefix, 1, func(res stow.ScrubResult) {
+		results[res.Item.Name()] = res.Status
+	})
+	is.NoErr(err)
+	is.Equal(results, map[string]stow.ScrubStatus{"kept": stow.ScrubOK})
+}
+
+func TestAppendTruncate(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+
+	l, err := stow.Dial(local.Kind, stow.ConfigMap{local.ConfigKeyPath: testDir})
+	is.NoErr(err)
+	c, err := l.Container(filepath.Join(testDir, "one"))
+	is.NoErr(err)
+
+	record := strings.Repeat("x", 100<<10) + "\n"
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := stow.Append(c, "logs/app.log", strings.NewReader(record))
+			is.NoErr(err)
+		}()
+	}
+	wg.Wait()
+	item, err := c.Item("logs/app.log")
+	is.NoErr(err)
+	size, err := item.Size()
+	is.NoErr(err)
+	is.Equal(size, int64(8*len(record)))
+	b, err := ioutil.ReadFile(filepath.Join(testDir, "one", "logs", "app.log"))
+	is.NoErr(err)
+	is.Equal(string(b), strings.Repeat(record, 8))
+
+	is.NoErr(stow.Truncate(c, "logs/app.log", 10))
+	b, err = ioutil.ReadFile(filepath.Join(testDir, "one", "logs", "app.log"))
+	is.NoErr(err)
+	is.Equal(string(b), "xxxxxxxxxx")
+
+	err = stow.Truncate(c, "missing", 0)
+	is.True(errors.Is(err, stow.ErrNotFound))
+}
+
+func TestAppendTruncateRecordMD5(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:   testDir,
+		local.ConfigRecordMD5: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.CreateContainer("scrubbed")
+	is.NoErr(err)
+
+	_, err = c.Put("log", strings.NewReader("abc"), 3, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+	_, err = stow.Append(c, "log", strings.NewReader("def"))
+	is.NoErr(err)
+	item, err := c.Item("log")
+	is.NoErr(err)
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataUser], map[string]interface{}{
+		"a":                     "b",
+		stow.MetadataContentMD5: "e80b5017098950fc58aad83c8c14978e",
+	})
+	is.NoErr(stow.Truncate(c, "log", 1))
+	item, err = c.Item("log")
+	is.NoErr(err)
+	md, err = item.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataUser], map[string]interface{}{
+		"a":                     "b",
+		stow.MetadataContentMD5: "0cc175b9c0f1b6a831c399e269772661",
+	})
+	results := make(map[string]stow.ScrubStatus)
+	err = stow.Scrub(c, stow.NoPrefix, 1, func(res stow.ScrubResult) {
+		results[res.Item.Name()] = res.Status
+	})
+	is.NoErr(err)
+	is.Equal(results, map[string]stow.ScrubStatus{"log": stow.ScrubOK})
+}
+
+func TestAppendGzipped(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:               testDir,
+		local.ConfigTransparentDecompress: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container(filepath.Join(testDir, "one"))
+	is.NoErr(err)
+
+	md := map[string]interface{}{stow.MetadataCompression: "gzip"}
+	_, err = c.Put("a.json", strings.NewReader("{}"), 2, md)
+	is.NoErr(err)
+	_, err = stow.Append(c, "a.json", strings.NewReader("more"))
+	is.True(stow.IsNotSupported(err))
+	err = stow.Truncate(c, "a.json", 0)
+	is.True(stow.IsNotSupported(err))
+	_, err = os.Stat(filepath.Join(testDir, "one", "a.json"))
+	is.True(os.IsNotExist(err))
+	items, _, err := c.Items(stow.NoPrefix, stow.CursorStart, 10)
+	is.NoErr(err)
+	is.Equal(len(items), 1)
+	is.Equal(items[0].Name(), "a.json")
+}
@@ -0,0 +1,304 @@
+//go:build !windows
+// +build !windows
+
+package local_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+	"github.com/graymeta/stow/local"
+)
+
+func TestPutOwnership(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing owners requires root")
+	}
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:           testDir,
+		local.ConfigPreserveOwnership: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	item, err := c.Put("owned", strings.NewReader("x"), 1, map[string]interface{}{"uid": uint32(1), "gid": 2.0})
+	is.NoErr(err)
+	info, err := os.Stat(item.ID())
+	is.NoErr(err)
+	stat := info.Sys().(*syscall.Stat_t)
+	is.Equal(stat.Uid, uint32(1))
+	is.Equal(stat.Gid, uint32(2))
+}
+
+func TestPutReflink(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath: testDir,
+		local.ConfigReflink: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("two")
+	is.NoErr(err)
+	src := filepath.Join(testDir, "three", "item1")
+
+	f, err := os.Open(src)
+	is.NoErr(err)
+	item, err := c.Put("linked", f, 3, nil)
+	f.Close()
+	is.NoErr(err)
+	b, err := ioutil.ReadFile(item.ID())
+	is.NoErr(err)
+	is.Equal(string(b), "3.1")
+	srcInfo, err := os.Stat(src)
+	is.NoErr(err)
+	info, err := os.Stat(item.ID())
+	is.NoErr(err)
+	md, err := item.Metadata()
+	is.NoErr(err)
+	// the file is either cloned or hardlinked
+	is.Equal(os.SameFile(srcInfo, info), md[local.MetadataIsHardlink])
+
+	// partly read files are copied
+	f, err = os.Open(src)
+	is.NoErr(err)
+	_, err = f.Seek(1, io.SeekStart)
+	is.NoErr(err)
+	item, err = c.Put("copied", f, 2, nil)
+	f.Close()
+	is.NoErr(err)
+	b, err = ioutil.ReadFile(item.ID())
+	is.NoErr(err)
+	is.Equal(string(b), ".1")
+	info, err = os.Stat(item.ID())
+	is.NoErr(err)
+	is.False(os.SameFile(srcInfo, info))
+}
+
+// stagingReader is an io.Reader that records the files in
+// a directory when it is first read.
+type stagingReader struct {
+	r     io.Reader
+	dir   string
+	names []string
+	read  bool
+}
+
+func (r *stagingReader) Read(p []byte) (int, error) {
+	if !r.read {
+		r.read = true
+		f, err := os.Open(r.dir)
+		if err != nil {
+			return 0, err
+		}
+		r.names, err = f.Readdirnames(-1)
+		f.Close()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return r.r.Read(p)
+}
+
+func TestPutTempDir(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	tempDir := filepath.Join(testDir, "staging")
+	is.NoErr(os.Mkdir(tempDir, 0777))
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath: testDir,
+		local.ConfigTempDir: tempDir,
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	r := &stagingReader{r: strings.NewReader("staged"), dir: tempDir}
+	item, err := c.Put("staged", r, 6, nil)
+	is.NoErr(err)
+	is.Equal(len(r.names), 1)
+	b, err := ioutil.ReadFile(item.ID())
+	is.NoErr(err)
+	is.Equal(string(b), "staged")
+	infos, err := ioutil.ReadDir(tempDir)
+	is.NoErr(err)
+	is.Equal(len(infos), 0)
+
+	// a missing temp dir fails
+	is.NoErr(os.Remove(tempDir))
+	_, err = c.Put("staged", strings.NewReader("staged"), 6, nil)
+	is.Err(err)
+}
+
+func TestPutReflinkTempDir(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	tempDir := filepath.Join(testDir, "staging")
+	is.NoErr(os.Mkdir(tempDir, 0777))
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath: testDir,
+		local.ConfigReflink: "true",
+		local.ConfigTempDir: tempDir,
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("two")
+	is.NoErr(err)
+	src := filepath.Join(testDir, "three", "item1")
+
+	f, err := os.Open(src)
+	is.NoErr(err)
+	item, err := c.Put("linked", f, 3, nil)
+	f.Close()
+	is.NoErr(err)
+	b, err := ioutil.ReadFile(item.ID())
+	is.NoErr(err)
+	is.Equal(string(b), "3.1")
+	infos, err := ioutil.ReadDir(tempDir)
+	is.NoErr(err)
+	is.Equal(len(infos), 0)
+
+	// linked files are staged in the temp dir too, so a missing
+	// one fails
+	is.NoErr(os.Remove(tempDir))
+	f, err = os.Open(src)
+	is.NoErr(err)
+	_, err = c.Put("linked", f, 3, nil)
+	f.Close()
+	is.Err(err)
+}
+
+func TestPutSparse(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("holes are only kept on Linux")
+	}
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath: testDir,
+		local.ConfigSparse:  "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	// a file of 4MB with data only after a hole of 2MB
+	const size = 4 << 20
+	src := filepath.Join(testDir, "sparse")
+	f, err := os.Create(src)
+	is.NoErr(err)
+	_, err = f.WriteAt([]byte("data"), 2<<20)
+	is.NoErr(err)
+	is.NoErr(f.Truncate(size))
+	is.NoErr(f.Close())
+	allocated := func(path string) int64 {
+		info, err := os.Stat(path)
+		is.NoErr(err)
+		return info.Sys().(*syscall.Stat_t).Blocks * 512
+	}
+	if allocated(src) >= size {
+		t.Skip("the filesystem does not support sparse files")
+	}
+
+	f, err = os.Open(src)
+	is.NoErr(err)
+	item, err := c.Put("sparse", f, size, nil)
+	f.Close()
+	is.NoErr(err)
+	b, err := ioutil.ReadFile(item.ID())
+	is.NoErr(err)
+	is.Equal(len(b), size)
+	is.Equal(string(b[2<<20:2<<20+4]), "data")
+	is.True(allocated(item.ID()) < size)
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataAllocatedSize], allocated(item.ID()))
+	is.Equal(md[local.MetadataSize], int64(size))
+}
+
+func TestPutSymlink(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:            testDir,
+		local.ConfigAllowSymlinkCreate: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+	dir := filepath.Join(testDir, "three")
+
+	// copying a symlink item makes a symlink
+	is.NoErr(os.Symlink("item1", filepath.Join(dir, "link")))
+	src, err := c.Item("link")
+	is.NoErr(err)
+	md, err := src.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataIsSymlink], true)
+	item, err := c.Put("copied", strings.NewReader("ignored"), 7, md)
+	is.NoErr(err)
+	target, err := os.Readlink(item.ID())
+	is.NoErr(err)
+	is.Equal(target, "item1")
+	b, err := ioutil.ReadFile(item.ID())
+	is.NoErr(err)
+	is.Equal(string(b), "3.1")
+
+	// existing files are replaced, but not by PutIfNotExists
+	symlink := map[string]interface{}{local.MetadataIsSymlink: true, local.MetadataLink: "item2"}
+	_, err = c.Put("item3", nil, 0, symlink)
+	is.NoErr(err)
+	target, err = os.Readlink(filepath.Join(dir, "item3"))
+	is.NoErr(err)
+	is.Equal(target, "item2")
+	_, err = c.(stow.ConditionalPutter).PutIfNotExists("item3", nil, 0, symlink)
+	is.True(errors.Is(err, stow.ErrAlreadyExists))
+
+	// targets outside of the container are rejected
+	for _, target := range []string{"../two/item1", "/etc/passwd"} {
+		_, err = c.Put("escaped", nil, 0, map[string]interface{}{local.MetadataIsSymlink: true, local.MetadataLink: target})
+		is.Err(err)
+		_, err = os.Lstat(filepath.Join(dir, "escaped"))
+		is.True(os.IsNotExist(err))
+	}
+
+	// without the config, the contents are written
+	l, err = stow.Dial(local.Kind, stow.ConfigMap{local.ConfigKeyPath: testDir})
+	is.NoErr(err)
+	c, err = l.Container("three")
+	is.NoErr(err)
+	item, err = c.Put("written", strings.NewReader("written"), 7, symlink)
+	is.NoErr(err)
+	info, err := os.Lstat(item.ID())
+	is.NoErr(err)
+	is.True(info.Mode().IsRegular())
+}
@@ -0,0 +1,77 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// The whence values of lseek that find the data and holes of a file.
+const (
+	seekData = 3
+	seekHole = 4
+)
+
+// isSparse gets whether fewer bytes are allocated on disk
+// for f than its size.
+func isSparse(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Blocks*512 < info.Size()
+}
+
+// copySparse copies the rest of src into the new file f, copying only
+// the data of src and leaving its holes as holes in f. It fails with
+// the context error once the context is done.
+func copySparse(ctx context.Context, f, src *os.File) (int64, error) {
+	start, err := src.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	info, err := src.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	for off := start; off < size; {
+		if err := ctx.Err(); err != nil {
+			return off - start, err
+		}
+		data, err := src.Seek(off, seekData)
+		if errors.Is(err, syscall.ENXIO) {
+			// only a hole is left
+			break
+		}
+		if err != nil {
+			return off - start, err
+		}
+		hole, err := src.Seek(data, seekHole)
+		if err != nil {
+			return off - start, err
+		}
+		if _, err := src.Seek(data, io.SeekStart); err != nil {
+			return off - start, err
+		}
+		if _, err := f.Seek(data-start, io.SeekStart); err != nil {
+			return off - start, err
+		}
+		n, err := io.CopyN(f, src, hole-data)
+		if err != nil {
+			return data - start + n, err
+		}
+		off = hole
+	}
+	// extend f over any trailing hole
+	if err := f.Truncate(size - start); err != nil {
+		return 0, err
+	}
+	if _, err := src.Seek(size, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return size - start, nil
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package local
+
+import (
+	"context"
+	"os"
+)
+
+// isSparse gets whether f is a sparse file. Holes are only found on
+// Linux, so it is always false.
+func isSparse(f *os.File) bool {
+	return false
+}
+
+// copySparse is never called, as no file is sparse.
+func copySparse(ctx context.Context, f, src *os.File) (int64, error) {
+	return copyFileContext(ctx, f, src)
+}
@@ -0,0 +1,159 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/graymeta/stow"
+)
+
+// watchBuffer is the number of events buffered by Watch.
+const watchBuffer = 64
+
+// Watch watches the directory of the container and all of its
+// subdirectories, sending an event for every change to the files of
+// items. Metadata files, temporary files and ignored files are left
+// out. Files found in new directories are reported as created, so a
+// file may be reported as created more than once. Errors of the
+// underlying watcher, such as event queue overflows, are dropped.
+func (c *container) Watch() (_ <-chan stow.Event, _ func(), err error) {
+	defer wrapErr(&err, "watch", c.name)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, err
+	}
+	w := &watch{
+		container: c,
+		watcher:   watcher,
+		events:    make(chan stow.Event, watchBuffer),
+		done:      make(chan struct{}),
+	}
+	if err := w.add(c.path, false); err != nil {
+		watcher.Close()
+		return nil, nil, err
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w.events, w.cancel, nil
+}
+
+// watch is a running Watch of a container.
+type watch struct {
+	container *container
+	watcher   *fsnotify.Watcher
+	events    chan stow.Event
+	done      chan struct{}
+	stopOnce  sync.Once
+	wg        sync.WaitGroup
+}
+
+// cancel stops the watch, and returns once the events
+// channel is closed.
+func (w *watch) cancel() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+		w.watcher.Close()
+	})
+	w.wg.Wait()
+}
+
+func (w *watch) run() {
+	defer w.wg.Done()
+	defer close(w.events)
+	for {
+		select {
+		case <-w.done:
+			return
+		case ev, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handle(ev)
+		case _, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// handle translates a filesystem event into item events.
+func (w *watch) handle(ev fsnotify.Event) {
+	path := ev.Name
+	if w.skipped(path) {
+		return
+	}
+	switch {
+	case ev.Op&fsnotify.Create == fsnotify.Create:
+		info, err := os.Lstat(path)
+		if err != nil {
+			return // already gone
+		}
+		if info.IsDir() {
+			w.add(path, true)
+			return
+		}
+		w.send(path, stow.EventCreate)
+	case ev.Op&fsnotify.Write == fsnotify.Write:
+		w.send(path, stow.EventModify)
+	case ev.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		w.send(path, stow.EventDelete)
+	}
+}
+
+// add watches the directory at path and its subdirectories. If
+// report is true, the files found in them are reported as created.
+func (w *watch) add(path string, report bool) error {
+	return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if p != path && os.IsNotExist(err) {
+				return nil // removed while walking
+			}
+			return err
+		}
+		if p != path && w.skipped(p) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return w.watcher.Add(p)
+		}
+		if report {
+			w.send(p, stow.EventCreate)
+		}
+		return nil
+	})
+}
+
+// skipped gets whether changes to the file at path are left out.
+func (w *watch) skipped(path string) bool {
+	if isSidecar(path) || isTemp(path) {
+		return true
+	}
+	name, err := filepath.Rel(w.container.path, path)
+	if err != nil {
+		return true
+	}
+	return w.container.location.ignored(name)
+}
+
+// send sends an event for the file at path, unless the file is
+// not an item of the container or the watch was cancelled.
+func (w *watch) send(path string, op stow.EventOp) {
+	if !w.container.includedPath(path) {
+		return
+	}
+	id, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	select {
+	case w.events <- stow.Event{ID: filepath.ToSlash(id), Op: op}:
+	case <-w.done:
+	}
+}
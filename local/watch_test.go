@@ -0,0 +1,71 @@
+package local_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+	"github.com/graymeta/stow/local"
+)
+
+func TestWatch(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{"path": testDir}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	w, ok := c.(stow.Watcher)
+	is.True(ok)
+	events, cancel, err := w.Watch()
+	is.NoErr(err)
+	defer cancel()
+	// next gets the next event for the item, skipping any others
+	next := func(id string) stow.Event {
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case ev := <-events:
+				is.False(strings.HasSuffix(ev.ID, local.MetadataFileExt))
+				if ev.ID == id {
+					return ev
+				}
+			case <-timeout:
+				t.Fatalf("no event for %s", id)
+			}
+		}
+	}
+
+	item, err := c.Put("item4", strings.NewReader("item4"), 5, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+	is.Equal(next(item.ID()).Op, stow.EventCreate)
+	f, err := os.OpenFile(filepath.Join(testDir, "three", "item4"), os.O_WRONLY|os.O_APPEND, 0)
+	is.NoErr(err)
+	_, err = f.WriteString("more")
+	f.Close()
+	is.NoErr(err)
+	is.Equal(next(item.ID()).Op, stow.EventModify)
+	is.NoErr(c.RemoveItem(item.ID()))
+	is.Equal(next(item.ID()).Op, stow.EventDelete)
+
+	// new directories are watched too
+	item, err = c.Put("sub/dir/item", strings.NewReader("item"), 4, nil)
+	is.NoErr(err)
+	is.Equal(next(item.ID()).Op, stow.EventCreate)
+	is.NoErr(c.RemoveItem(item.ID()))
+	is.Equal(next(item.ID()).Op, stow.EventDelete)
+
+	cancel()
+	for range events {
+		// drain until closed
+	}
+	cancel() // cancelling again is harmless
+}
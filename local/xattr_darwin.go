@@ -0,0 +1,6 @@
+package local
+
+import "golang.org/x/sys/unix"
+
+// errNoXattr is the error of reading a missing extended attribute.
+const errNoXattr = unix.ENOATTR
@@ -0,0 +1,26 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package local
+
+import (
+	"os"
+
+	"github.com/graymeta/stow"
+)
+
+// readXattrs gets the extended attributes of the file at path, which
+// are not supported on this platform, so nil is returned.
+func readXattrs(path string, follow bool) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+// writeXattrs sets the extended attributes of the file f, which are
+// not supported on this platform, so an error matching
+// stow.ErrNotSupported is returned if there are any.
+func writeXattrs(f *os.File, xattrs map[string]interface{}) error {
+	if len(xattrs) == 0 {
+		return nil
+	}
+	return stow.NotSupported("extended attributes")
+}
@@ -0,0 +1,88 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package local
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// readXattrs gets the extended attributes of the file at path, or of
+// the symlink itself unless follow is true. If the filesystem does not
+// support extended attributes, nil is returned.
+func readXattrs(path string, follow bool) (map[string]interface{}, error) {
+	list, get := unix.Llistxattr, unix.Lgetxattr
+	if follow {
+		list, get = unix.Listxattr, unix.Getxattr
+	}
+	names, err := xattrBuffer(func(dest []byte) (int, error) {
+		return list(path, dest)
+	})
+	if errors.Is(err, unix.ENOTSUP) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	xattrs := make(map[string]interface{})
+	for _, name := range strings.Split(string(names), "\x00") {
+		if name == "" {
+			continue
+		}
+		value, err := xattrBuffer(func(dest []byte) (int, error) {
+			return get(path, name, dest)
+		})
+		if errors.Is(err, errNoXattr) {
+			continue // removed since it was listed
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading extended attribute %s: %w", name, err)
+		}
+		xattrs[name] = string(value)
+	}
+	return xattrs, nil
+}
+
+// xattrBuffer calls fn with a buffer large enough for the value it
+// reads, by asking for the size first, and retrying if the value
+// grew in the meantime.
+func xattrBuffer(fn func(dest []byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := fn(nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		dest := make([]byte, size)
+		n, err := fn(dest)
+		if errors.Is(err, unix.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return dest[:n], nil
+	}
+}
+
+// writeXattrs sets the extended attributes of the file f to the
+// string values of xattrs.
+func writeXattrs(f *os.File, xattrs map[string]interface{}) error {
+	for name, v := range xattrs {
+		value, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("extended attribute %s must be a string", name)
+		}
+		if err := unix.Fsetxattr(int(f.Fd()), name, []byte(value), 0); err != nil {
+			return fmt.Errorf("writing extended attribute %s: %w", name, err)
+		}
+	}
+	return nil
+}
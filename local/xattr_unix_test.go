@@ -0,0 +1,57 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package local_test
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+	"github.com/graymeta/stow/local"
+)
+
+func TestXattrs(t *testing.T) {
+	is := is.New(t)
+	testDir, teardown, err := setup()
+	is.NoErr(err)
+	defer teardown()
+	cfg := stow.ConfigMap{
+		local.ConfigKeyPath:    testDir,
+		local.ConfigReadXattrs: "true",
+	}
+	l, err := stow.Dial(local.Kind, cfg)
+	is.NoErr(err)
+	c, err := l.Container("three")
+	is.NoErr(err)
+
+	xattrs := map[string]interface{}{"user.mime": "text/plain"}
+	_, err = c.Put("tagged", strings.NewReader("tagged"), 6, map[string]interface{}{
+		local.MetadataXattr: xattrs,
+	})
+	if errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.EPERM) {
+		t.Skip("extended attributes are not supported")
+	}
+	is.NoErr(err)
+	item, err := c.Item("tagged")
+	is.NoErr(err)
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md[local.MetadataXattr], xattrs)
+	// extended attributes are not user metadata
+	is.Nil(md[local.MetadataUser])
+
+	// they are left out unless enabled
+	l, err = stow.Dial(local.Kind, stow.ConfigMap{local.ConfigKeyPath: testDir})
+	is.NoErr(err)
+	c, err = l.Container("three")
+	is.NoErr(err)
+	item, err = c.Item("tagged")
+	is.NoErr(err)
+	md, err = item.Metadata()
+	is.NoErr(err)
+	is.Nil(md[local.MetadataXattr])
+}
@@ -1,6 +1,7 @@
 package stow_test
 
 import (
+	"errors"
 	"net/url"
 
 	"github.com/graymeta/stow"
@@ -8,6 +9,9 @@ import (
 
 func init() {
 	validatefn := func(config stow.Config) error {
+		if v, ok := config.Config("invalid"); ok {
+			return errors.New(v)
+		}
 		return nil
 	}
 	makefn := func(config stow.Config) (stow.Location, error) {
@@ -0,0 +1,84 @@
+package stow
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Mirror puts an Item with the specified name into all of the target
+// Containers at once, reading r a single time and streaming it to each
+// of them without buffering the contents.
+// The Items are returned in the order of the targets.
+// If putting to any of the targets fails, the Items that were put to
+// the other targets are removed, and a *multierror.Error holding the
+// errors is returned.
+func Mirror(name string, r io.Reader, size int64, metadata map[string]interface{}, targets ...Container) ([]Item, error) {
+	var (
+		items   = make([]Item, len(targets))
+		errs    = make([]error, len(targets))
+		writers = make([]io.Writer, len(targets))
+		pipes   = make([]*io.PipeWriter, len(targets))
+		wg      sync.WaitGroup
+	)
+	for i, target := range targets {
+		pr, pw := io.Pipe()
+		writers[i] = pw
+		pipes[i] = pw
+		wg.Add(1)
+		go func(i int, target Container) {
+			defer wg.Done()
+			items[i], errs[i] = target.Put(name, pr, size, metadata)
+			if errs[i] == nil {
+				errs[i] = ensureRead(pr)
+			}
+			// unblock the writer if the target stopped reading early
+			pr.CloseWithError(fmt.Errorf("putting to %s stopped", target.Name()))
+		}(i, target)
+	}
+	_, copyErr := io.Copy(io.MultiWriter(writers...), r)
+	for _, pw := range pipes {
+		pw.CloseWithError(copyErr)
+	}
+	wg.Wait()
+
+	var merr *multierror.Error
+	for i, err := range errs {
+		if err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("putting to %s: %w", targets[i].Name(), err))
+		}
+	}
+	if merr == nil {
+		if copyErr != nil {
+			merr = multierror.Append(merr, copyErr)
+		} else {
+			return items, nil
+		}
+	}
+	// roll back the puts that succeeded
+	for i, item := range items {
+		if errs[i] != nil || item == nil {
+			continue
+		}
+		if err := DeleteItem(targets[i], item); err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("removing %s from %s: %w", item.ID(), targets[i].Name(), err))
+		}
+	}
+	return nil, merr
+}
+
+// ensureRead checks that a Put read all of the contents from the
+// pipe, by reading any that are left.
+func ensureRead(pr *io.PipeReader) error {
+	n, err := io.Copy(ioutil.Discard, pr)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("%d bytes were not read", n)
+	}
+	return nil
+}
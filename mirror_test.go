@@ -0,0 +1,58 @@
+package stow_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+	"github.com/hashicorp/go-multierror"
+)
+
+// countingReader counts how often it is read.
+type countingReader struct {
+	r     *strings.Reader
+	reads int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads++
+	return c.r.Read(p)
+}
+
+func TestMirror(t *testing.T) {
+	is := is.New(t)
+	a, b := newTestContainer("a"), newTestContainer("b")
+	contents := strings.Repeat("mirrored ", 10000)
+	r := &countingReader{r: strings.NewReader(contents)}
+	items, err := stow.Mirror("item", r, int64(len(contents)), map[string]interface{}{"k": "v"}, a, b)
+	is.NoErr(err)
+	is.Equal(len(items), 2)
+	for _, c := range []*testContainer{a, b} {
+		is.Equal(string(c.items["item"].data), contents)
+		is.Equal(c.items["item"].metadata["k"], "v")
+	}
+	// the reader was consumed once
+	is.Equal(r.r.Len(), 0)
+}
+
+func TestMirrorRollback(t *testing.T) {
+	is := is.New(t)
+	a, b := newTestContainer("a"), newTestContainer("b")
+	failing := &errorContainer{testContainer: newTestContainer("failing")}
+	contents := strings.Repeat("mirrored ", 10000)
+	_, err := stow.Mirror("item", strings.NewReader(contents), int64(len(contents)), nil, a, failing, b)
+	merr, ok := err.(*multierror.Error)
+	is.True(ok)
+	failed := false
+	for _, err := range merr.Errors {
+		if errors.Is(err, errPut) {
+			failed = true
+		}
+	}
+	is.True(failed)
+	is.Equal(len(a.items), 0)
+	is.Equal(len(b.items), 0)
+	is.True(strings.Contains(err.Error(), "putting to failing"))
+}
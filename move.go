@@ -0,0 +1,37 @@
+package stow
+
+// Mover represents a Container that can rename its Items without
+// copying their contents.
+type Mover interface {
+	// Move renames the Item with the specified ID to dstName within
+	// the Container, replacing any Item with that name, and gets the
+	// moved Item.
+	// If the Item cannot be moved natively an error satisfying
+	// IsNotSupported is returned.
+	Move(srcID, dstName string) (Item, error)
+}
+
+// MoveItem renames the Item with the specified ID to dstName within
+// the Container.
+// If the Container is a Mover it is used, otherwise the Item is
+// copied with CopyItem and then removed.
+func MoveItem(c Container, srcID, dstName string) (Item, error) {
+	if m, ok := c.(Mover); ok {
+		item, err := m.Move(srcID, dstName)
+		if !IsNotSupported(err) {
+			return item, err
+		}
+	}
+	src, err := c.Item(srcID)
+	if err != nil {
+		return nil, err
+	}
+	item, err := CopyItem(c, dstName, src)
+	if err != nil {
+		return nil, err
+	}
+	if err := DeleteItem(c, src); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
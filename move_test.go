@@ -0,0 +1,67 @@
+package stow_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// moverContainer is a testContainer that only moves the
+// Items named "native".
+type moverContainer struct {
+	*testContainer
+	moved int
+}
+
+func (c *moverContainer) Move(srcID, dstName string) (stow.Item, error) {
+	if srcID != "native" {
+		return nil, stow.NotSupported("move")
+	}
+	c.moved++
+	item, err := c.Item(srcID)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.items, srcID)
+	moved := item.(*testItem)
+	moved.name = dstName
+	c.items[dstName] = moved
+	return moved, nil
+}
+
+func TestMoveItem(t *testing.T) {
+	is := is.New(t)
+	c := &moverContainer{testContainer: newTestContainer("c")}
+	for _, name := range []string{"native", "copied"} {
+		_, err := c.Put(name, strings.NewReader(name), int64(len(name)), map[string]interface{}{"key": name})
+		is.NoErr(err)
+	}
+
+	for _, name := range []string{"native", "copied"} {
+		item, err := stow.MoveItem(c, name, "moved/"+name)
+		is.NoErr(err)
+		is.Equal(item.Name(), "moved/"+name)
+		_, err = c.Item(name)
+		is.Equal(err, stow.ErrNotFound)
+		item, err = c.Item("moved/" + name)
+		is.NoErr(err)
+		rc, err := item.Open()
+		is.NoErr(err)
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		is.NoErr(err)
+		is.Equal(string(b), name)
+		md, err := item.Metadata()
+		is.NoErr(err)
+		is.Equal(md["key"], name)
+	}
+	is.Equal(c.moved, 1)
+
+	_, err := stow.MoveItem(c, "nope", "moved/nope")
+	is.Equal(err, stow.ErrNotFound)
+}
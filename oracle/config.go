@@ -77,6 +77,7 @@ func init() {
 	}
 
 	stow.Register(Kind, makefn, kindfn, validatefn)
+	stow.RegisterConfigKeys(Kind, []string{ConfigUsername, ConfigPassword, ConfigAuthEndpoint}, nil)
 }
 
 func newSwiftClient(cfg stow.Config) (*swift.Connection, error) {
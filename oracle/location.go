@@ -66,7 +66,10 @@ func (l *location) Container(id string) (stow.Container, error) {
 	_, _, err := l.client.Container(id)
 	// TODO: grab info + headers
 	if err != nil {
-		return nil, stow.ErrNotFound
+		if err == swift.ContainerNotFound {
+			return nil, stow.NotFound(err)
+		}
+		return nil, err
 	}
 
 	c := &container{
@@ -0,0 +1,132 @@
+package stow
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Order is the order of the Items listed by an OrderedLister.
+type Order int
+
+const (
+	// NameAsc lists Items by name, in ascending order.
+	NameAsc Order = iota
+	// NameDesc lists Items by name, in descending order.
+	NameDesc
+	// ModTimeAsc lists Items by last modified time, in ascending
+	// order, with Items modified at the same time ordered by name.
+	ModTimeAsc
+	// ModTimeDesc lists Items in the reverse order of ModTimeAsc.
+	ModTimeDesc
+)
+
+// OrderedLister represents a Container that can list its Items
+// in other orders than by ascending name.
+type OrderedLister interface {
+	// ItemsOrdered gets a page of Items with the specified prefix like
+	// Items, in the specified order. Cursors are only valid for the
+	// order they were got with.
+	ItemsOrdered(prefix string, order Order, cursor string, count int) ([]Item, string, error)
+}
+
+// orderedPageSize is the number of Items ItemsOrdered gets per
+// request when it lists all of the Items of a Container.
+const orderedPageSize = 1000
+
+// ItemsOrdered gets a page of the Items of the Container with the
+// specified prefix, in the specified order.
+// The Container is used as an OrderedLister if possible. Otherwise
+// Items are listed with Items for NameAsc, and for other orders all
+// of the Items with the prefix are listed and held in memory to sort
+// them, for every page, so listing large Containers is slow and takes
+// a lot of memory.
+func ItemsOrdered(c Container, prefix string, order Order, cursor string, count int) ([]Item, string, error) {
+	if ol, ok := c.(OrderedLister); ok {
+		return ol.ItemsOrdered(prefix, order, cursor, count)
+	}
+	if order == NameAsc {
+		return c.Items(prefix, cursor, count)
+	}
+	var items []Item
+	err := Walk(c, prefix, orderedPageSize, func(item Item, err error) error {
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if err := SortItems(items, order); err != nil {
+		return nil, "", err
+	}
+	if cursor != CursorStart {
+		i := 0
+		for i < len(items) && items[i].ID() != cursor {
+			i++
+		}
+		if i == len(items) {
+			return nil, "", ErrBadCursor
+		}
+		items = items[i:]
+	}
+	cursor = "" // end
+	if len(items) > count {
+		cursor = items[count].ID()
+		items = items[:count]
+	}
+	return items, cursor, nil
+}
+
+// SortItems sorts the Items in the specified order, getting the
+// last modified time of each Item at most once.
+func SortItems(items []Item, order Order) error {
+	type sortItem struct {
+		item    Item
+		name    string
+		modTime time.Time
+	}
+	sorted := make([]sortItem, len(items))
+	for i, item := range items {
+		sorted[i] = sortItem{item: item, name: item.Name()}
+		if order == ModTimeAsc || order == ModTimeDesc {
+			t, err := item.LastMod()
+			if err != nil {
+				return err
+			}
+			sorted[i].modTime = t
+		}
+	}
+	var less func(a, b sortItem) bool
+	switch order {
+	case NameAsc:
+		less = func(a, b sortItem) bool { return a.name < b.name }
+	case NameDesc:
+		less = func(a, b sortItem) bool { return a.name > b.name }
+	case ModTimeAsc:
+		less = func(a, b sortItem) bool {
+			if !a.modTime.Equal(b.modTime) {
+				return a.modTime.Before(b.modTime)
+			}
+			return a.name < b.name
+		}
+	case ModTimeDesc:
+		less = func(a, b sortItem) bool {
+			if !a.modTime.Equal(b.modTime) {
+				return a.modTime.After(b.modTime)
+			}
+			return a.name > b.name
+		}
+	default:
+		return NotSupported("order " + strconv.Itoa(int(order)))
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	for i, s := range sorted {
+		items[i] = s.item
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package stow_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestItemsOrdered(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	now := time.Now()
+	for i, name := range []string{"b", "d", "a", "c", "e"} {
+		item, err := c.Put(name, strings.NewReader(name), 1, nil)
+		is.NoErr(err)
+		// a and c are modified at the same time
+		item.(*testItem).lastMod = now.Add(time.Duration(i%3) * time.Minute)
+	}
+	list := func(order stow.Order) []string {
+		var names []string
+		cursor := stow.CursorStart
+		for {
+			items, next, err := stow.ItemsOrdered(c, "", order, cursor, 2)
+			is.NoErr(err)
+			for _, item := range items {
+				names = append(names, item.Name())
+			}
+			if stow.IsCursorEnd(next) {
+				return names
+			}
+			cursor = next
+		}
+	}
+	is.Equal(list(stow.NameAsc), []string{"a", "b", "c", "d", "e"})
+	is.Equal(list(stow.NameDesc), []string{"e", "d", "c", "b", "a"})
+	is.Equal(list(stow.ModTimeAsc), []string{"b", "c", "d", "e", "a"})
+	is.Equal(list(stow.ModTimeDesc), []string{"a", "e", "d", "c", "b"})
+
+	_, _, err := stow.ItemsOrdered(c, "", stow.NameDesc, "missing", 2)
+	is.Equal(err, stow.ErrBadCursor)
+	_, _, err = stow.ItemsOrdered(c, "", stow.Order(-1), stow.CursorStart, 2)
+	is.True(stow.IsNotSupported(err))
+}
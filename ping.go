@@ -0,0 +1,20 @@
+package stow
+
+// Pinger represents a Location that can check that it is
+// reachable and usable, cheaply enough for liveness probes.
+type Pinger interface {
+	// Ping checks the Location, returning an error if it is
+	// not usable.
+	Ping() error
+}
+
+// Ping checks that the Location is reachable and usable.
+// The Location is used as a Pinger if possible, otherwise a single
+// Container is listed.
+func Ping(loc Location) error {
+	if p, ok := loc.(Pinger); ok {
+		return p.Ping()
+	}
+	_, _, err := loc.Containers(NoPrefix, CursorStart, 1)
+	return err
+}
@@ -0,0 +1,38 @@
+package stow_test
+
+import (
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// unreachableLocation is a testLocation that fails to
+// list its Containers.
+type unreachableLocation struct {
+	testLocation
+}
+
+func (l *unreachableLocation) Containers(prefix string, cursor string, count int) ([]stow.Container, string, error) {
+	return nil, "", errDenied
+}
+
+// pingLocation is an unreachableLocation that is a Pinger.
+type pingLocation struct {
+	unreachableLocation
+	pings int
+}
+
+func (l *pingLocation) Ping() error {
+	l.pings++
+	return nil
+}
+
+func TestPing(t *testing.T) {
+	is := is.New(t)
+	is.NoErr(stow.Ping(&testLocation{}))
+	is.Equal(stow.Ping(&unreachableLocation{}), errDenied)
+	p := &pingLocation{}
+	is.NoErr(stow.Ping(p))
+	is.Equal(p.pings, 1)
+}
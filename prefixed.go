@@ -0,0 +1,85 @@
+package stow
+
+import (
+	"io"
+	"strings"
+)
+
+// Prefixed gets a view of a Container that is scoped to the items
+// whose names start with prefix.
+// The prefix is added to the names and IDs passed to the view, and
+// removed from the names of the Items it returns, so that an Item put
+// with a name can be got with the same name.
+// The IDs of Items got from the view are their names relative to the
+// prefix.
+// The wrapped Items only implement the methods of the Item interface.
+func Prefixed(c Container, prefix string) Container {
+	return &prefixedContainer{
+		Container: c,
+		prefix:    prefix,
+	}
+}
+
+type prefixedContainer struct {
+	Container
+	prefix string
+}
+
+func (c *prefixedContainer) ID() string {
+	return c.Container.ID() + "/" + c.prefix
+}
+
+func (c *prefixedContainer) Name() string {
+	return c.Container.Name() + "/" + c.prefix
+}
+
+func (c *prefixedContainer) Item(id string) (Item, error) {
+	item, err := c.Container.Item(c.prefix + id)
+	if err != nil {
+		return nil, err
+	}
+	return c.item(item), nil
+}
+
+func (c *prefixedContainer) Items(prefix, cursor string, count int) ([]Item, string, error) {
+	items, cursor, err := c.Container.Items(c.prefix+prefix, cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+	for i, item := range items {
+		items[i] = c.item(item)
+	}
+	return items, cursor, nil
+}
+
+func (c *prefixedContainer) RemoveItem(id string) error {
+	return c.Container.RemoveItem(c.prefix + id)
+}
+
+func (c *prefixedContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error) {
+	item, err := c.Container.Put(c.prefix+name, r, size, metadata)
+	if err != nil {
+		return nil, err
+	}
+	return c.item(item), nil
+}
+
+func (c *prefixedContainer) item(item Item) Item {
+	return &prefixedItem{
+		Item: item,
+		name: strings.TrimPrefix(item.Name(), c.prefix),
+	}
+}
+
+type prefixedItem struct {
+	Item
+	name string
+}
+
+func (i *prefixedItem) ID() string {
+	return i.name
+}
+
+func (i *prefixedItem) Name() string {
+	return i.name
+}
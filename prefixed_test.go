@@ -0,0 +1,58 @@
+package stow_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestPrefixed(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	_, err := c.Put("other/item", strings.NewReader("other"), 5, nil)
+	is.NoErr(err)
+
+	p := stow.Prefixed(c, "data/")
+	is.Equal(p.Name(), "c/data/")
+
+	item, err := p.Put("a/item1", strings.NewReader("item1"), 5, nil)
+	is.NoErr(err)
+	is.Equal(item.Name(), "a/item1")
+	is.Equal(item.ID(), "a/item1")
+	_, err = c.Item("data/a/item1")
+	is.NoErr(err)
+
+	item, err = p.Item("a/item1")
+	is.NoErr(err)
+	is.Equal(item.Name(), "a/item1")
+	rc, err := item.Open()
+	is.NoErr(err)
+	b, err := ioutil.ReadAll(rc)
+	rc.Close()
+	is.NoErr(err)
+	is.Equal(string(b), "item1")
+
+	_, err = p.Put("b", strings.NewReader("b"), 1, nil)
+	is.NoErr(err)
+
+	items, cursor, err := p.Items(stow.NoPrefix, stow.CursorStart, 10)
+	is.NoErr(err)
+	is.True(stow.IsCursorEnd(cursor))
+	is.Equal(len(items), 2)
+	is.Equal(items[0].Name(), "a/item1")
+	is.Equal(items[1].Name(), "b")
+
+	items, _, err = p.Items("a/", stow.CursorStart, 10)
+	is.NoErr(err)
+	is.Equal(len(items), 1)
+
+	_, err = p.Item("other/item")
+	is.Equal(err, stow.ErrNotFound)
+
+	is.NoErr(p.RemoveItem(items[0].ID()))
+	_, err = c.Item("data/a/item1")
+	is.Equal(err, stow.ErrNotFound)
+}
@@ -0,0 +1,91 @@
+package stow
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// ProgressFunc is called with the number of bytes read so far
+// and the total number of bytes, or -1 if it is unknown.
+type ProgressFunc func(bytesDone, total int64)
+
+// progressInterval is the minimum time between two calls to a
+// ProgressFunc, except for the call made once reading is done.
+const progressInterval = 100 * time.Millisecond
+
+// PutWithProgress puts an Item into the Container like Put, calling
+// onProgress as the contents are read from r. Calls are made at most
+// every 100ms, and once all of the contents were read. No calls are
+// made once PutWithProgress returns.
+func PutWithProgress(c Container, name string, r io.Reader, size int64, metadata map[string]interface{}, onProgress ProgressFunc) (Item, error) {
+	pr := &progressReader{r: r, total: size, fn: onProgress}
+	defer pr.close()
+	return c.Put(name, pr, size, metadata)
+}
+
+// OpenWithProgress opens the Item for reading like Open, calling
+// onProgress as the contents are read. Calls are made at most every
+// 100ms, and once all of the contents were read. No calls are made
+// once the returned io.ReadCloser is closed.
+func OpenWithProgress(item Item, onProgress ProgressFunc) (io.ReadCloser, error) {
+	size, err := item.Size()
+	if err != nil {
+		size = -1
+	}
+	rc, err := item.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &progressReadCloser{
+		progressReader: progressReader{r: rc, total: size, fn: onProgress},
+		c:              rc,
+	}, nil
+}
+
+// progressReader is an io.Reader that reports how much of
+// it was read.
+type progressReader struct {
+	r     io.Reader
+	total int64
+	fn    ProgressFunc
+
+	lock   sync.Mutex // protects the fields below
+	done   int64
+	last   time.Time
+	closed bool
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.done += int64(n)
+	if r.closed {
+		return n, err
+	}
+	if err == io.EOF || time.Since(r.last) >= progressInterval {
+		r.last = time.Now()
+		r.fn(r.done, r.total)
+	}
+	return n, err
+}
+
+// close stops any further calls to the ProgressFunc.
+func (r *progressReader) close() {
+	r.lock.Lock()
+	r.closed = true
+	r.lock.Unlock()
+}
+
+// progressReadCloser is a progressReader that closes the
+// underlying reader.
+type progressReadCloser struct {
+	progressReader
+	c io.Closer
+}
+
+func (r *progressReadCloser) Close() error {
+	r.close()
+	return r.c.Close()
+}
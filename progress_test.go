@@ -0,0 +1,61 @@
+package stow_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// errorContainer is a testContainer whose Put reads
+// a little and then fails.
+type errorContainer struct {
+	*testContainer
+}
+
+var errPut = errors.New("put failed")
+
+func (c *errorContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+	io.CopyN(ioutil.Discard, r, 1)
+	return nil, errPut
+}
+
+func TestPutWithProgress(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	contents := strings.Repeat("x", 1000)
+	var calls [][2]int64
+	_, err := stow.PutWithProgress(c, "item", strings.NewReader(contents), 1000, nil, func(done, total int64) {
+		calls = append(calls, [2]int64{done, total})
+	})
+	is.NoErr(err)
+	is.True(len(calls) > 0)
+	is.Equal(calls[len(calls)-1], [2]int64{1000, 1000})
+
+	_, err = stow.PutWithProgress(&errorContainer{testContainer: c}, "item", strings.NewReader(contents), 1000, nil, func(done, total int64) {})
+	is.Equal(err, errPut)
+}
+
+func TestOpenWithProgress(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	item, err := c.Put("item", strings.NewReader("contents"), 8, nil)
+	is.NoErr(err)
+	var calls [][2]int64
+	rc, err := stow.OpenWithProgress(item, func(done, total int64) {
+		calls = append(calls, [2]int64{done, total})
+	})
+	is.NoErr(err)
+	b := make([]byte, 4)
+	_, err = io.ReadFull(rc, b)
+	is.NoErr(err)
+	is.Equal(calls, [][2]int64{{4, 8}})
+	is.NoErr(rc.Close())
+	// no calls once closed
+	rc.Read(b)
+	is.Equal(len(calls), 1)
+}
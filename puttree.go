@@ -0,0 +1,124 @@
+package stow
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// PutTree puts each regular file in the directory tree at rootDir
+// into the Container, named by its slash separated path relative to
+// rootDir, with the metadata, and gets the number of files put.
+// The specified number of workers put files at once. Symlinks are
+// skipped, PutTreeFollowSymlinks puts the files they point to.
+// The files are put as the *os.File read from, so backends that can
+// link or clone files, like the local backend with reflinks enabled,
+// do so rather than copying the contents.
+// Files that fail to be put, or to be read from the tree, do not stop
+// the others from being put, and a *multierror.Error holding their
+// errors is returned. Failing to read rootDir itself stops the put.
+func PutTree(c Container, rootDir string, workers int, md map[string]interface{}) (int, error) {
+	return putTree(c, rootDir, workers, md, false)
+}
+
+// PutTreeFollowSymlinks puts the files in the directory tree at
+// rootDir into the Container like PutTree, but puts the files that
+// symlinks point to under the names of the symlinks. Symlinks to
+// directories are skipped, so that cycles are never walked.
+func PutTreeFollowSymlinks(c Container, rootDir string, workers int, md map[string]interface{}) (int, error) {
+	return putTree(c, rootDir, workers, md, true)
+}
+
+func putTree(c Container, rootDir string, workers int, md map[string]interface{}, follow bool) (int, error) {
+	info, err := os.Stat(rootDir)
+	if err != nil {
+		return 0, err
+	}
+	if !info.IsDir() {
+		return 0, errors.New("not a directory: " + rootDir)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		lock  sync.Mutex // protects put and merr
+		put   int
+		merr  *multierror.Error
+		names = make(chan string)
+		wg    sync.WaitGroup
+	)
+	fail := func(name string, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		merr = multierror.Append(merr, fmt.Errorf("putting %s: %w", name, err))
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				if err := putTreeFile(c, rootDir, name, md); err != nil {
+					fail(name, err)
+					continue
+				}
+				lock.Lock()
+				put++
+				lock.Unlock()
+			}
+		}()
+	}
+	err = filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+		if path == rootDir {
+			return err
+		}
+		rel, rerr := filepath.Rel(rootDir, path)
+		if rerr != nil {
+			return rerr
+		}
+		name := filepath.ToSlash(rel)
+		if err != nil {
+			// the contents of a directory that cannot be read are skipped
+			fail(name, err)
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !follow {
+				return nil
+			}
+			if info, err = os.Stat(path); err != nil {
+				fail(name, err)
+				return nil
+			}
+		}
+		if info.Mode().IsRegular() {
+			names <- name
+		}
+		return nil
+	})
+	close(names)
+	wg.Wait()
+	if err != nil {
+		return put, err
+	}
+	return put, merr.ErrorOrNil()
+}
+
+// putTreeFile puts the file with the slash separated name relative
+// to rootDir into the Container.
+func putTreeFile(c Container, rootDir, name string, md map[string]interface{}) error {
+	f, err := os.Open(filepath.Join(rootDir, filepath.FromSlash(name)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	_, err = c.Put(name, f, info.Size(), md)
+	return err
+}
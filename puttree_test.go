@@ -0,0 +1,48 @@
+package stow_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestPutTree(t *testing.T) {
+	is := is.New(t)
+	dir, err := ioutil.TempDir("", "stow-tree")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+	is.NoErr(os.MkdirAll(filepath.Join(dir, "a", "b"), 0755))
+	is.NoErr(ioutil.WriteFile(filepath.Join(dir, "top.txt"), []byte("top"), 0644))
+	is.NoErr(ioutil.WriteFile(filepath.Join(dir, "a", "b", "deep.txt"), []byte("deep"), 0644))
+	symlinks := runtime.GOOS != "windows"
+	if symlinks {
+		is.NoErr(os.Symlink(filepath.Join(dir, "top.txt"), filepath.Join(dir, "a", "link.txt")))
+	}
+
+	md := map[string]interface{}{"source": "tree"}
+	c := newTestContainer("c")
+	n, err := stow.PutTree(c, dir, 4, md)
+	is.NoErr(err)
+	is.Equal(n, 2)
+	is.Equal(string(c.items["top.txt"].data), "top")
+	is.Equal(string(c.items["a/b/deep.txt"].data), "deep")
+	is.Equal(c.items["a/b/deep.txt"].metadata["source"], "tree")
+	_, ok := c.items["a/link.txt"]
+	is.False(ok)
+
+	if symlinks {
+		c = newTestContainer("followed")
+		n, err = stow.PutTreeFollowSymlinks(c, dir, 1, md)
+		is.NoErr(err)
+		is.Equal(n, 3)
+		is.Equal(string(c.items["a/link.txt"].data), "top")
+	}
+
+	_, err = stow.PutTree(c, filepath.Join(dir, "missing"), 1, nil)
+	is.True(os.IsNotExist(err))
+}
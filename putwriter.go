@@ -0,0 +1,91 @@
+package stow
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// WriterPutter represents a Container that can put Items by writing
+// their contents, rather than reading them from a reader.
+type WriterPutter interface {
+	// PutWriter starts putting a new Item with the specified name, and
+	// gets a writer for its contents. The Item is only created once the
+	// writer is closed, and Close fails if size is not negative and
+	// not equal to the number of bytes written.
+	// The writer also has a CloseWithError(err error) error method,
+	// like io.PipeWriter, which abandons the put without creating the
+	// Item.
+	PutWriter(name string, size int64, metadata map[string]interface{}) (io.WriteCloser, error)
+}
+
+// PutWriter starts putting a new Item with the specified name into
+// the Container, and gets a writer for its contents. The Item is
+// put once the writer is closed, and Close returns any error of the
+// put. Calling CloseWithError(err error) error on the writer abandons
+// the put instead.
+// The Container is used as a WriterPutter if possible, otherwise the
+// contents are written to Put through a pipe.
+func PutWriter(c Container, name string, size int64, metadata map[string]interface{}) (io.WriteCloser, error) {
+	if p, ok := c.(WriterPutter); ok {
+		return p.PutWriter(name, size, metadata)
+	}
+	pr, pw := io.Pipe()
+	w := &pipePutWriter{
+		PipeWriter: pw,
+		done:       make(chan struct{}),
+	}
+	go func() {
+		defer close(w.done)
+		w.item, w.err = c.Put(name, pr, size, metadata)
+		// fail any further writes once the put is done
+		pr.CloseWithError(errPutDone)
+		if w.err == nil {
+			w.c = c
+		}
+	}()
+	return w, nil
+}
+
+// errPutDone is the error of writes to a pipePutWriter after
+// the put is done.
+var errPutDone = errors.New("put done")
+
+// pipePutWriter writes the contents of an Item to a Put
+// through a pipe.
+type pipePutWriter struct {
+	*io.PipeWriter
+	done chan struct{} // closed once the put is done
+	item Item
+	err  error
+	c    Container // set if the put succeeded
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Close finishes writing the contents, and waits for the put.
+func (w *pipePutWriter) Close() error {
+	w.closeOnce.Do(func() {
+		w.PipeWriter.Close()
+		<-w.done
+		w.closeErr = w.err
+	})
+	return w.closeErr
+}
+
+// CloseWithError abandons the put, making it fail with err, and
+// removes the Item if it was put anyway.
+func (w *pipePutWriter) CloseWithError(err error) error {
+	if err == nil {
+		err = errors.New("put abandoned")
+	}
+	w.closeOnce.Do(func() {
+		w.PipeWriter.CloseWithError(err)
+		<-w.done
+		if w.c != nil {
+			w.closeErr = DeleteItem(w.c, w.item)
+		}
+	})
+	return w.closeErr
+}
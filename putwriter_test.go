@@ -0,0 +1,47 @@
+package stow_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestPutWriter(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+
+	w, err := stow.PutWriter(c, "item", 4, map[string]interface{}{"a": "b"})
+	is.NoErr(err)
+	_, err = io.WriteString(w, "it")
+	is.NoErr(err)
+	_, err = io.WriteString(w, "em")
+	is.NoErr(err)
+	is.NoErr(w.Close())
+	is.NoErr(w.Close())
+	item, err := c.Item("item")
+	is.NoErr(err)
+	is.Equal(string(item.(*testItem).data), "item")
+	md, err := item.Metadata()
+	is.NoErr(err)
+	is.Equal(md["a"], "b")
+
+	// abandoned puts fail
+	w, err = stow.PutWriter(c, "abandoned", -1, nil)
+	is.NoErr(err)
+	_, err = io.WriteString(w, "abandoned")
+	is.NoErr(err)
+	is.NoErr(w.(interface{ CloseWithError(error) error }).CloseWithError(errors.New("abandoned")))
+	_, err = c.Item("abandoned")
+	is.True(errors.Is(err, stow.ErrNotFound))
+
+	// writes fail once the put failed
+	rc := &rejectingContainer{testContainer: newTestContainer("c"), rejected: "item"}
+	w, err = stow.PutWriter(rc, "item", -1, nil)
+	is.NoErr(err)
+	_, err = io.WriteString(w, "item")
+	is.Err(err)
+	is.Err(w.Close())
+}
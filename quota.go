@@ -0,0 +1,179 @@
+package stow
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// QuotaContainer wraps a Container so that Put fails with
+// ErrQuotaExceeded when the total size of its Items would exceed
+// maxBytes. The usage is got with ContainerStat when the Container is
+// wrapped, and then kept up to date by Put and RemoveItem, so changes
+// made in other ways, such as through another Container value, are
+// not accounted for.
+// Putting an Item over an existing one only counts the difference in
+// size. Puts and removals of Items with the same name are made one at
+// a time, so that concurrent overwrites each replace the size of the
+// previous one. Contents of unknown size are counted as they are read,
+// and reading fails with ErrQuotaExceeded once the quota is used up.
+// The wrapped Container is safe for concurrent use, and only
+// implements the methods of the Container interface.
+func QuotaContainer(c Container, maxBytes int64) Container {
+	_, used, err := ContainerStat(c)
+	return &quotaContainer{
+		Container: c,
+		maxBytes:  maxBytes,
+		used:      used,
+		statErr:   err,
+		names:     make(map[string]*nameLock),
+	}
+}
+
+type quotaContainer struct {
+	Container
+	maxBytes int64
+	statErr  error // the error getting the initial usage
+
+	lock  sync.Mutex // protects used and names
+	used  int64
+	names map[string]*nameLock
+}
+
+// nameLock is held while an Item with a name is put or removed.
+type nameLock struct {
+	sync.Mutex
+	refs int // the calls holding or waiting for the lock
+}
+
+// lockName waits for the other calls putting or removing the Item
+// with the specified name, and gets a func unlocking it.
+func (c *quotaContainer) lockName(name string) func() {
+	c.lock.Lock()
+	l, ok := c.names[name]
+	if !ok {
+		l = &nameLock{}
+		c.names[name] = l
+	}
+	l.refs++
+	c.lock.Unlock()
+	l.Lock()
+	return func() {
+		l.Unlock()
+		c.lock.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(c.names, name)
+		}
+		c.lock.Unlock()
+	}
+}
+
+// itemSize gets the size of the Item with the specified ID,
+// or zero if it does not exist.
+func (c *quotaContainer) itemSize(id string) (int64, error) {
+	item, err := c.Container.Item(id)
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return item.Size()
+}
+
+// reserve adds n bytes to the usage, failing with ErrQuotaExceeded
+// if that would exceed the quota.
+func (c *quotaContainer) reserve(n int64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if n > 0 && c.used+n > c.maxBytes {
+		return ErrQuotaExceeded
+	}
+	c.used += n
+	return nil
+}
+
+// release removes n bytes from the usage.
+func (c *quotaContainer) release(n int64) {
+	c.lock.Lock()
+	c.used -= n
+	c.lock.Unlock()
+}
+
+func (c *quotaContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error) {
+	if c.statErr != nil {
+		return nil, c.statErr
+	}
+	unlock := c.lockName(name)
+	defer unlock()
+	old, err := c.itemSize(name)
+	if err != nil {
+		return nil, err
+	}
+	if size >= 0 {
+		if err := c.reserve(size - old); err != nil {
+			return nil, err
+		}
+		item, err := c.Container.Put(name, r, size, metadata)
+		if err != nil {
+			c.release(size - old)
+			return nil, err
+		}
+		return item, nil
+	}
+	c.release(old)
+	qr := &quotaReader{r: r, c: c}
+	item, err := c.Container.Put(name, qr, size, metadata)
+	if err != nil {
+		c.release(qr.n - old)
+		return nil, err
+	}
+	return item, nil
+}
+
+func (c *quotaContainer) RemoveItem(id string) error {
+	if c.statErr != nil {
+		return c.statErr
+	}
+	// the ID may differ from the name that Put locks, such as
+	// an absolute path
+	item, err := c.Container.Item(id)
+	if errors.Is(err, ErrNotFound) {
+		return c.Container.RemoveItem(id)
+	}
+	if err != nil {
+		return err
+	}
+	unlock := c.lockName(item.Name())
+	defer unlock()
+	size, err := c.itemSize(id)
+	if err != nil {
+		return err
+	}
+	if err := c.Container.RemoveItem(id); err != nil {
+		return err
+	}
+	c.release(size)
+	return nil
+}
+
+// quotaReader is an io.Reader that adds the bytes it reads to the
+// usage of a quotaContainer, failing with ErrQuotaExceeded once the
+// quota is used up.
+type quotaReader struct {
+	r io.Reader
+	c *quotaContainer
+	n int64 // bytes read and added to the usage
+}
+
+func (r *quotaReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if qerr := r.c.reserve(int64(n)); qerr != nil {
+			return 0, qerr
+		}
+		r.n += int64(n)
+	}
+	return n, err
+}
@@ -0,0 +1,151 @@
+package stow_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestQuotaContainer(t *testing.T) {
+	is := is.New(t)
+	tc := newTestContainer("c")
+	_, err := tc.Put("existing", strings.NewReader("1234"), 4, nil)
+	is.NoErr(err)
+	c := stow.QuotaContainer(tc, 10)
+
+	_, err = c.Put("a", strings.NewReader("12345"), 5, nil)
+	is.NoErr(err)
+	_, err = c.Put("b", strings.NewReader("12"), 2, nil)
+	is.Equal(err, stow.ErrQuotaExceeded)
+	_, err = tc.Item("b")
+	is.Equal(err, stow.ErrNotFound)
+
+	// replacing an item only counts the difference
+	_, err = c.Put("a", strings.NewReader("123456"), 6, nil)
+	is.NoErr(err)
+	is.NoErr(c.RemoveItem("existing"))
+	_, err = c.Put("b", strings.NewReader("1234"), 4, nil)
+	is.NoErr(err)
+
+	// unknown sizes are counted as they are read
+	is.NoErr(c.RemoveItem("b"))
+	_, err = c.Put("c", strings.NewReader("12345"), -1, nil)
+	is.Equal(err, stow.ErrQuotaExceeded)
+	_, err = c.Put("c", strings.NewReader("1234"), -1, nil)
+	is.NoErr(err)
+	_, err = c.Put("d", strings.NewReader("1"), 1, nil)
+	is.Equal(err, stow.ErrQuotaExceeded)
+}
+
+func TestQuotaContainerConcurrent(t *testing.T) {
+	is := is.New(t)
+	c := stow.QuotaContainer(newTestContainer("c"), 10)
+	var (
+		wg       sync.WaitGroup
+		lock     sync.Mutex
+		accepted int
+	)
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			_, err := c.Put(strings.Repeat("x", n+1), strings.NewReader("1"), 1, nil)
+			if err == stow.ErrQuotaExceeded {
+				return
+			}
+			is.NoErr(err)
+			lock.Lock()
+			accepted++
+			lock.Unlock()
+		}(n)
+	}
+	wg.Wait()
+	is.Equal(accepted, 10)
+}
+
+// slowContainer is a testContainer whose Put takes a while, so that
+// concurrent calls overlap.
+type slowContainer struct {
+	*testContainer
+}
+
+func (c *slowContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+	time.Sleep(time.Millisecond)
+	return c.testContainer.Put(name, r, size, metadata)
+}
+
+func TestQuotaContainerConcurrentOverwrites(t *testing.T) {
+	is := is.New(t)
+	c := stow.QuotaContainer(&slowContainer{testContainer: newTestContainer("c")}, 15)
+	var wg sync.WaitGroup
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// each put replaces the previous one, so all of them fit
+			_, err := c.Put("item", strings.NewReader("0123456789"), 10, nil)
+			is.NoErr(err)
+		}()
+	}
+	wg.Wait()
+	// only the size of the last put is used
+	_, err := c.Put("other", strings.NewReader("01234"), 5, nil)
+	is.NoErr(err)
+	_, err = c.Put("more", strings.NewReader("0"), 1, nil)
+	is.Equal(err, stow.ErrQuotaExceeded)
+}
+
+// pathContainer is a slowContainer whose Items can also be got and
+// removed by IDs that are paths, which differ from their names.
+type pathContainer struct {
+	*slowContainer
+}
+
+func (c *pathContainer) Item(id string) (stow.Item, error) {
+	return c.slowContainer.Item(strings.TrimPrefix(id, "/"))
+}
+
+func (c *pathContainer) RemoveItem(id string) error {
+	time.Sleep(time.Millisecond)
+	return c.slowContainer.RemoveItem(strings.TrimPrefix(id, "/"))
+}
+
+func TestQuotaContainerConcurrentPutRemove(t *testing.T) {
+	is := is.New(t)
+	tc := newTestContainer("c")
+	c := stow.QuotaContainer(&pathContainer{&slowContainer{testContainer: tc}}, 15)
+	_, err := c.Put("item", strings.NewReader("0123456789"), 10, nil)
+	is.NoErr(err)
+	var wg sync.WaitGroup
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			if n%2 == 0 {
+				_, err := c.Put("item", strings.NewReader("0123456789"), 10, nil)
+				is.NoErr(err)
+				return
+			}
+			err := c.RemoveItem("/item")
+			if !errors.Is(err, stow.ErrNotFound) {
+				is.NoErr(err)
+			}
+		}(n)
+	}
+	wg.Wait()
+	// the usage is the size of the item if it is left
+	used := int64(0)
+	if _, err := tc.Item("item"); err == nil {
+		used = 10
+	}
+	_, err = c.Put("other", strings.NewReader(strings.Repeat("0", int(15-used))), 15-used, nil)
+	is.NoErr(err)
+	_, err = c.Put("more", strings.NewReader("0"), 1, nil)
+	is.Equal(err, stow.ErrQuotaExceeded)
+}
@@ -0,0 +1,178 @@
+package stow
+
+import (
+	"context"
+	"io"
+	"net/url"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimited wraps a Location so that calls to it, and to the
+// Containers and Items got from it, are limited to rps calls per
+// second on average, with bursts of up to burst calls. All of them
+// share a single limiter.
+// Listing, getting, putting and removing Containers and Items, and
+// opening Items, each take one token when they start, however much
+// data they move. Calls wait for a token, and PutContext and
+// OpenContext stop waiting with the context error once the context
+// is done.
+// A burst below 1 is taken as 1, as no call could ever get a
+// token otherwise.
+// The wrapped Containers and Items only implement the methods of the
+// Container and Item interfaces, and PutContext and OpenContext.
+func RateLimited(loc Location, rps float64, burst int) Location {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimitedLocation{
+		Location: loc,
+		limiter:  rate.NewLimiter(rate.Limit(rps), burst),
+	}
+}
+
+type rateLimitedLocation struct {
+	Location
+	limiter *rate.Limiter
+}
+
+func (l *rateLimitedLocation) CreateContainer(name string) (Container, error) {
+	if err := l.limiter.Wait(context.Background()); err != nil {
+		return nil, err
+	}
+	c, err := l.Location.CreateContainer(name)
+	if err != nil {
+		return nil, err
+	}
+	return l.container(c), nil
+}
+
+func (l *rateLimitedLocation) Containers(prefix string, cursor string, count int) ([]Container, string, error) {
+	if err := l.limiter.Wait(context.Background()); err != nil {
+		return nil, "", err
+	}
+	cs, next, err := l.Location.Containers(prefix, cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+	for i, c := range cs {
+		cs[i] = l.container(c)
+	}
+	return cs, next, nil
+}
+
+func (l *rateLimitedLocation) Container(id string) (Container, error) {
+	if err := l.limiter.Wait(context.Background()); err != nil {
+		return nil, err
+	}
+	c, err := l.Location.Container(id)
+	if err != nil {
+		return nil, err
+	}
+	return l.container(c), nil
+}
+
+func (l *rateLimitedLocation) RemoveContainer(id string) error {
+	if err := l.limiter.Wait(context.Background()); err != nil {
+		return err
+	}
+	return l.Location.RemoveContainer(id)
+}
+
+func (l *rateLimitedLocation) ItemByURL(u *url.URL) (Item, error) {
+	if err := l.limiter.Wait(context.Background()); err != nil {
+		return nil, err
+	}
+	item, err := l.Location.ItemByURL(u)
+	if err != nil {
+		return nil, err
+	}
+	return &rateLimitedItem{Item: item, limiter: l.limiter}, nil
+}
+
+func (l *rateLimitedLocation) container(c Container) Container {
+	return &rateLimitedContainer{Container: c, limiter: l.limiter}
+}
+
+type rateLimitedContainer struct {
+	Container
+	limiter *rate.Limiter
+}
+
+func (c *rateLimitedContainer) Item(id string) (Item, error) {
+	if err := c.limiter.Wait(context.Background()); err != nil {
+		return nil, err
+	}
+	item, err := c.Container.Item(id)
+	if err != nil {
+		return nil, err
+	}
+	return &rateLimitedItem{Item: item, limiter: c.limiter}, nil
+}
+
+func (c *rateLimitedContainer) Items(prefix, cursor string, count int) ([]Item, string, error) {
+	if err := c.limiter.Wait(context.Background()); err != nil {
+		return nil, "", err
+	}
+	items, next, err := c.Container.Items(prefix, cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+	for i, item := range items {
+		items[i] = &rateLimitedItem{Item: item, limiter: c.limiter}
+	}
+	return items, next, nil
+}
+
+func (c *rateLimitedContainer) RemoveItem(id string) error {
+	if err := c.limiter.Wait(context.Background()); err != nil {
+		return err
+	}
+	return c.Container.RemoveItem(id)
+}
+
+func (c *rateLimitedContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error) {
+	return c.PutContext(context.Background(), name, r, size, metadata)
+}
+
+// PutContext waits for a token until the context is done, and then
+// puts the Item, with the context if the Container is a ContextPutter.
+func (c *rateLimitedContainer) PutContext(ctx context.Context, name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	var (
+		item Item
+		err  error
+	)
+	if cp, ok := c.Container.(ContextPutter); ok {
+		item, err = cp.PutContext(ctx, name, r, size, metadata)
+	} else {
+		item, err = c.Container.Put(name, r, size, metadata)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rateLimitedItem{Item: item, limiter: c.limiter}, nil
+}
+
+type rateLimitedItem struct {
+	Item
+	limiter *rate.Limiter
+}
+
+func (i *rateLimitedItem) Open() (io.ReadCloser, error) {
+	return i.OpenContext(context.Background())
+}
+
+// OpenContext waits for a token until the context is done, and then
+// opens the Item, with the context if the Item is a ContextOpener.
+func (i *rateLimitedItem) OpenContext(ctx context.Context) (io.ReadCloser, error) {
+	if err := i.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	if co, ok := i.Item.(ContextOpener); ok {
+		return co.OpenContext(ctx)
+	}
+	return i.Item.Open()
+}
@@ -0,0 +1,50 @@
+package stow_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestRateLimited(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	loc := stow.RateLimited(&singleLocation{container: c}, 1, 3)
+
+	// the burst is served at once
+	start := time.Now()
+	cc, err := loc.Container("c")
+	is.NoErr(err)
+	item, err := cc.Put("item", strings.NewReader("item"), 4, nil)
+	is.NoErr(err)
+	rc, err := item.Open()
+	is.NoErr(err)
+	rc.Close()
+	is.True(time.Since(start) < 500*time.Millisecond)
+
+	// the limiter is empty, so waiting past the deadline fails
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = cc.(stow.ContextPutter).PutContext(ctx, "other", strings.NewReader("other"), 5, nil)
+	is.Err(err)
+	_, err = item.(stow.ContextOpener).OpenContext(ctx)
+	is.Err(err)
+	_, err = c.Item("other")
+	is.Err(err)
+}
+
+func TestRateLimitedNoBurst(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	for _, burst := range []int{0, -1} {
+		loc := stow.RateLimited(&singleLocation{container: c}, 1000, burst)
+		cc, err := loc.Container("c")
+		is.NoErr(err)
+		_, err = cc.Put("item", strings.NewReader("item"), 4, nil)
+		is.NoErr(err)
+	}
+}
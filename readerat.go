@@ -0,0 +1,99 @@
+package stow
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ReaderAtOpener represents an Item that can be opened for
+// random access reading.
+type ReaderAtOpener interface {
+	// OpenReaderAt opens the Item for reading at any offset, and gets
+	// the size of its contents. If the io.ReaderAt is an io.Closer too,
+	// calling code must close it.
+	OpenReaderAt() (io.ReaderAt, int64, error)
+}
+
+// readAheadSize is the least number of bytes that the io.ReaderAt
+// made by OpenReaderAt gets with each ranged read.
+const readAheadSize = 64 << 10
+
+// OpenReaderAt opens the Item for reading at any offset, and gets the
+// size of its contents. ReaderAtOpeners are opened with OpenReaderAt,
+// and other RangeOpeners are read with a ranged read for each call to
+// ReadAt, which reads at least 64KiB ahead and caches the bytes read
+// for the next call. The cache makes the io.ReaderAt of a RangeOpener
+// serialize calls to ReadAt.
+// If the io.ReaderAt is an io.Closer too, calling code must close it.
+func OpenReaderAt(item Item) (io.ReaderAt, int64, error) {
+	if ro, ok := item.(ReaderAtOpener); ok {
+		return ro.OpenReaderAt()
+	}
+	ranger, ok := item.(RangeOpener)
+	if !ok {
+		return nil, 0, NotSupported("opening as an io.ReaderAt")
+	}
+	size, err := item.Size()
+	if err != nil {
+		return nil, 0, err
+	}
+	return &rangeReaderAt{ranger: ranger, size: size}, size, nil
+}
+
+// rangeReaderAt is an io.ReaderAt that reads ranges of an Item,
+// caching the last range it read.
+type rangeReaderAt struct {
+	ranger RangeOpener
+	size   int64
+
+	lock   sync.Mutex // protects the fields below
+	buf    []byte
+	bufOff int64
+}
+
+func (r *rangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var n int
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= r.size {
+			return n, io.EOF
+		}
+		if pos < r.bufOff || pos >= r.bufOff+int64(len(r.buf)) {
+			if err := r.fill(pos, len(p)-n); err != nil {
+				return n, err
+			}
+		}
+		n += copy(p[n:], r.buf[pos-r.bufOff:])
+	}
+	return n, nil
+}
+
+// fill reads at least want bytes, or readAheadSize bytes if that is
+// more, from pos into the cache, stopping at the end of the contents.
+func (r *rangeReaderAt) fill(pos int64, want int) error {
+	if want < readAheadSize {
+		want = readAheadSize
+	}
+	end := pos + int64(want)
+	if end > r.size {
+		end = r.size
+	}
+	rc, err := r.ranger.OpenRange(uint64(pos), uint64(end-1))
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	buf := make([]byte, end-pos)
+	if _, err := io.ReadFull(rc, buf); err != nil {
+		r.buf = nil
+		return err
+	}
+	r.buf, r.bufOff = buf, pos
+	return nil
+}
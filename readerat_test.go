@@ -0,0 +1,58 @@
+package stow_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// rangedItem is a testItem that can be read by ranges,
+// counting the ranged reads.
+type rangedItem struct {
+	*testItem
+	reads int
+}
+
+func (i *rangedItem) OpenRange(start, end uint64) (io.ReadCloser, error) {
+	i.reads++
+	return ioutil.NopCloser(bytes.NewReader(i.data[start : end+1])), nil
+}
+
+func TestOpenReaderAt(t *testing.T) {
+	is := is.New(t)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("inside.txt")
+	is.NoErr(err)
+	_, err = w.Write([]byte("zipped contents"))
+	is.NoErr(err)
+	is.NoErr(zw.Close())
+	item := &rangedItem{testItem: &testItem{name: "item.zip", data: buf.Bytes()}}
+
+	ra, size, err := stow.OpenReaderAt(item)
+	is.NoErr(err)
+	is.Equal(size, int64(buf.Len()))
+	zr, err := zip.NewReader(ra, size)
+	is.NoErr(err)
+	is.Equal(len(zr.File), 1)
+	rc, err := zr.File[0].Open()
+	is.NoErr(err)
+	b, err := ioutil.ReadAll(rc)
+	is.NoErr(err)
+	is.Equal(string(b), "zipped contents")
+	// the whole archive fits in the read ahead
+	is.Equal(item.reads, 1)
+
+	p := make([]byte, 4)
+	n, err := ra.ReadAt(p, size-2)
+	is.Equal(err, io.EOF)
+	is.Equal(n, 2)
+
+	_, _, err = stow.OpenReaderAt(&testItem{name: "plain"})
+	is.True(stow.IsNotSupported(err))
+}
@@ -0,0 +1,93 @@
+package stow
+
+import (
+	"io"
+	"net/url"
+)
+
+// ReadOnly wraps a Location so that operations that make changes to
+// it, or to the Containers and Items got from it, fail with
+// ErrReadOnly without reaching the Location.
+// The wrapped Containers and Items only implement the methods of the
+// Container and Item interfaces, and SetMetadata which always fails.
+func ReadOnly(loc Location) Location {
+	return &readOnlyLocation{Location: loc}
+}
+
+type readOnlyLocation struct {
+	Location
+}
+
+func (l *readOnlyLocation) CreateContainer(name string) (Container, error) {
+	return nil, ErrReadOnly
+}
+
+func (l *readOnlyLocation) Containers(prefix string, cursor string, count int) ([]Container, string, error) {
+	cs, cursor, err := l.Location.Containers(prefix, cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+	for i, c := range cs {
+		cs[i] = &readOnlyContainer{Container: c}
+	}
+	return cs, cursor, nil
+}
+
+func (l *readOnlyLocation) Container(id string) (Container, error) {
+	c, err := l.Location.Container(id)
+	if err != nil {
+		return nil, err
+	}
+	return &readOnlyContainer{Container: c}, nil
+}
+
+func (l *readOnlyLocation) RemoveContainer(id string) error {
+	return ErrReadOnly
+}
+
+func (l *readOnlyLocation) ItemByURL(u *url.URL) (Item, error) {
+	item, err := l.Location.ItemByURL(u)
+	if err != nil {
+		return nil, err
+	}
+	return &readOnlyItem{Item: item}, nil
+}
+
+type readOnlyContainer struct {
+	Container
+}
+
+func (c *readOnlyContainer) Item(id string) (Item, error) {
+	item, err := c.Container.Item(id)
+	if err != nil {
+		return nil, err
+	}
+	return &readOnlyItem{Item: item}, nil
+}
+
+func (c *readOnlyContainer) Items(prefix, cursor string, count int) ([]Item, string, error) {
+	items, cursor, err := c.Container.Items(prefix, cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+	for i, item := range items {
+		items[i] = &readOnlyItem{Item: item}
+	}
+	return items, cursor, nil
+}
+
+func (c *readOnlyContainer) RemoveItem(id string) error {
+	return ErrReadOnly
+}
+
+func (c *readOnlyContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error) {
+	return nil, ErrReadOnly
+}
+
+type readOnlyItem struct {
+	Item
+}
+
+func (i *readOnlyItem) SetMetadata(metadata map[string]interface{}) error {
+	return ErrReadOnly
+}
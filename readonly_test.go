@@ -0,0 +1,43 @@
+package stow_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestReadOnly(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	_, err := c.Put("item", strings.NewReader("item"), 4, nil)
+	is.NoErr(err)
+	loc := stow.ReadOnly(&singleLocation{container: c})
+
+	_, err = loc.CreateContainer("new")
+	is.Equal(err, stow.ErrReadOnly)
+	is.Equal(loc.RemoveContainer("c"), stow.ErrReadOnly)
+
+	rc, err := loc.Container("c")
+	is.NoErr(err)
+	items, _, err := rc.Items(stow.NoPrefix, stow.CursorStart, 10)
+	is.NoErr(err)
+	is.Equal(len(items), 1)
+	item, err := rc.Item("item")
+	is.NoErr(err)
+	size, err := item.Size()
+	is.NoErr(err)
+	is.Equal(size, int64(4))
+
+	_, err = rc.Put("other", strings.NewReader("other"), 5, nil)
+	is.Equal(err, stow.ErrReadOnly)
+	is.Equal(rc.RemoveItem("item"), stow.ErrReadOnly)
+	ms, ok := item.(stow.MetadataSetter)
+	is.True(ok)
+	is.Equal(ms.SetMetadata(map[string]interface{}{"a": "b"}), stow.ErrReadOnly)
+
+	// nothing reached the container
+	is.Equal(len(c.items), 1)
+	is.Equal(len(c.items["item"].metadata), 0)
+}
@@ -0,0 +1,79 @@
+package stow
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// BatchRemover represents a Container that can remove many
+// Items at once.
+type BatchRemover interface {
+	// RemoveItems removes the Items with the specified IDs.
+	// If some of the Items could not be removed, a *multierror.Error
+	// holding one error for each of them is returned.
+	RemoveItems(ids []string) error
+}
+
+// Deleter represents an Item that can remove itself from
+// its Container.
+type Deleter interface {
+	// Delete removes the Item from its Container.
+	Delete() error
+}
+
+// DeleteItem removes the Item from the Container.
+// The Item is used as a Deleter if possible, otherwise it is
+// removed through the Container by its ID.
+func DeleteItem(c Container, item Item) error {
+	if d, ok := item.(Deleter); ok {
+		return d.Delete()
+	}
+	return c.RemoveItem(item.ID())
+}
+
+// removePageSize is the number of Items listed per request
+// by RemovePrefix.
+const removePageSize = 1000
+
+// RemovePrefix removes all Items in the Container with the specified
+// prefix, returning the number of Items that were removed.
+// The Container is used as a BatchRemover if possible.
+// If some Items could not be removed, a *multierror.Error holding
+// one error for each of them is returned.
+func RemovePrefix(c Container, prefix string) (int, error) {
+	var ids []string
+	err := Walk(c, prefix, removePageSize, func(item Item, err error) error {
+		if err != nil {
+			return err
+		}
+		ids = append(ids, item.ID())
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if br, ok := c.(BatchRemover); ok {
+		err := br.RemoveItems(ids)
+		if err == nil {
+			return len(ids), nil
+		}
+		if merr, ok := err.(*multierror.Error); ok {
+			return len(ids) - len(merr.Errors), err
+		}
+		return 0, err
+	}
+	var errs *multierror.Error
+	for _, id := range ids {
+		if err := c.RemoveItem(id); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("removing %s: %w", id, err))
+		}
+	}
+	if errs == nil {
+		return len(ids), nil
+	}
+	return len(ids) - len(errs.Errors), errs
+}
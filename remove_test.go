@@ -0,0 +1,95 @@
+package stow_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+	"github.com/hashicorp/go-multierror"
+)
+
+func TestRemovePrefix(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	for i := 0; i < 5; i++ {
+		_, err := c.Put(fmt.Sprintf("logs/%d", i), strings.NewReader("log"), 3, nil)
+		is.NoErr(err)
+	}
+	_, err := c.Put("keep", strings.NewReader("keep"), 4, nil)
+	is.NoErr(err)
+
+	n, err := stow.RemovePrefix(c, "logs/")
+	is.NoErr(err)
+	is.Equal(n, 5)
+	is.Equal(len(c.items), 1)
+
+	n, err = stow.RemovePrefix(c, "logs/")
+	is.NoErr(err)
+	is.Equal(n, 0)
+}
+
+// batchContainer is a testContainer that fails to
+// remove some items in a batch.
+type batchContainer struct {
+	*testContainer
+	batches int
+}
+
+func (c *batchContainer) RemoveItems(ids []string) error {
+	c.batches++
+	var errs *multierror.Error
+	for _, id := range ids {
+		if strings.HasSuffix(id, "locked") {
+			errs = multierror.Append(errs, fmt.Errorf("removing %s: locked", id))
+			continue
+		}
+		delete(c.items, id)
+	}
+	return errs.ErrorOrNil()
+}
+
+func TestRemovePrefixBatch(t *testing.T) {
+	is := is.New(t)
+	c := &batchContainer{testContainer: newTestContainer("c")}
+	for _, name := range []string{"a", "b", "c-locked", "d-locked"} {
+		_, err := c.Put(name, strings.NewReader("x"), 1, nil)
+		is.NoErr(err)
+	}
+
+	n, err := stow.RemovePrefix(c, stow.NoPrefix)
+	is.Err(err)
+	is.Equal(n, 2)
+	is.Equal(c.batches, 1)
+	merr, ok := err.(*multierror.Error)
+	is.True(ok)
+	is.Equal(len(merr.Errors), 2)
+	is.Equal(len(c.items), 2)
+}
+
+// deleterItem is a testItem that records being deleted.
+type deleterItem struct {
+	*testItem
+	deleted bool
+}
+
+func (i *deleterItem) Delete() error {
+	i.deleted = true
+	return nil
+}
+
+func TestDeleteItem(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	item, err := c.Put("item", strings.NewReader("x"), 1, nil)
+	is.NoErr(err)
+
+	d := &deleterItem{testItem: item.(*testItem)}
+	is.NoErr(stow.DeleteItem(c, d))
+	is.True(d.deleted)
+	is.Equal(len(c.items), 1)
+
+	is.NoErr(stow.DeleteItem(c, item))
+	is.Equal(len(c.items), 0)
+}
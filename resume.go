@@ -0,0 +1,110 @@
+package stow
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// manifestEntry is a line of the manifest of a ResumableCopy, recording
+// an Item that was copied.
+type manifestEntry struct {
+	ID   string `json:"id"`
+	ETag string `json:"etag"`
+}
+
+// ResumableCopy copies all of the Items in the src Container into the
+// dst Container like CopyContainer, recording the IDs and ETags of the
+// Items it copied in the manifest file at manifestPath. When it is run
+// again with the same manifest, Items that are recorded with the same
+// ETag as they have in src are skipped without looking at dst, so a
+// copy that was interrupted resumes where it stopped, even between
+// backends whose ETags differ.
+// The manifest holds a JSON object per line. Each one is appended with
+// a single write once its Item was copied, and a partly written last
+// line, as left by a crash, is ignored.
+func ResumableCopy(dst, src Container, manifestPath string, workers int) error {
+	copied, err := readManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(manifestPath, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := endLine(f); err != nil {
+		return err
+	}
+	var lock sync.Mutex // serializes appends to the manifest
+	_, err = copyContainer(src, workers, func(item Item) (bool, error) {
+		etag, err := item.ETag()
+		if err != nil {
+			return false, err
+		}
+		if done, ok := copied[item.ID()]; ok && etag != "" && done == etag {
+			return false, nil
+		}
+		if _, err := CopyItem(dst, item.Name(), item); err != nil {
+			return false, err
+		}
+		line, err := json.Marshal(manifestEntry{ID: item.ID(), ETag: etag})
+		if err != nil {
+			return false, err
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// readManifest reads the ETags of the copied Items, by ID, from the
+// manifest at path, which may not exist yet. Lines that cannot be
+// decoded are ignored.
+func readManifest(path string) (map[string]string, error) {
+	copied := make(map[string]string)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return copied, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		var entry manifestEntry
+		if err := json.Unmarshal(s.Bytes(), &entry); err != nil {
+			continue
+		}
+		copied[entry.ID] = entry.ETag
+	}
+	return copied, s.Err()
+}
+
+// endLine ends a partly written last line of the manifest file, so
+// that the next line is appended after it.
+func endLine(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err = f.Write([]byte{'\n'})
+	return err
+}
@@ -0,0 +1,61 @@
+package stow_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// puttingContainer is a rejectingContainer that counts puts.
+type puttingContainer struct {
+	*rejectingContainer
+	puts int
+}
+
+func (c *puttingContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+	c.lock.Lock()
+	c.puts++
+	c.lock.Unlock()
+	return c.rejectingContainer.Put(name, r, size, metadata)
+}
+
+func TestResumableCopy(t *testing.T) {
+	is := is.New(t)
+	dir, err := ioutil.TempDir("", "stow-manifest")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+	manifest := filepath.Join(dir, "manifest")
+
+	src := newTestContainer("src")
+	for _, name := range []string{"a", "b", "c", "d"} {
+		_, err := src.Put(name, strings.NewReader(name), 1, nil)
+		is.NoErr(err)
+	}
+	dst := &puttingContainer{rejectingContainer: &rejectingContainer{testContainer: newTestContainer("dst"), rejected: "c"}}
+	is.Err(stow.ResumableCopy(dst, src, manifest, 2))
+	is.Equal(dst.puts, 4)
+
+	// a torn line from a crash is ignored
+	f, err := os.OpenFile(manifest, os.O_WRONLY|os.O_APPEND, 0644)
+	is.NoErr(err)
+	_, err = f.Write([]byte(`{"id":"c","et`))
+	is.NoErr(err)
+	is.NoErr(f.Close())
+
+	dst.rejected = ""
+	dst.puts = 0
+	is.NoErr(stow.ResumableCopy(dst, src, manifest, 2))
+	is.Equal(dst.puts, 1)
+	_, err = dst.Item("c")
+	is.NoErr(err)
+
+	dst.puts = 0
+	is.NoErr(stow.ResumableCopy(dst, src, manifest, 2))
+	is.Equal(dst.puts, 0)
+}
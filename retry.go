@@ -0,0 +1,274 @@
+package stow
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy describes how Retry retries failed operations.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times an operation is
+	// attempted. Values less than one mean a single attempt.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, which doubles
+	// for every retry after that.
+	BaseDelay time.Duration
+	// IsRetryable gets whether an operation that failed with err
+	// should be retried. If nil, all errors are retried except
+	// ErrNotFound, ErrBadCursor and errors satisfying IsNotSupported.
+	IsRetryable func(err error) bool
+}
+
+// retryable gets whether an operation that failed with err
+// should be retried.
+func (p RetryPolicy) retryable(err error) bool {
+	if p.IsRetryable != nil {
+		return p.IsRetryable(err)
+	}
+	return !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrBadCursor) && !IsNotSupported(err)
+}
+
+// do calls fn until it succeeds, fails with an error that is not
+// retryable, or has been attempted MaxAttempts times.
+// The before function, if not nil, is called before every retry
+// and stops the retries if it fails.
+func (p RetryPolicy) do(before func() error, fn func() error) error {
+	delay := p.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !p.retryable(err) {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+		if before != nil {
+			if berr := before(); berr != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Retry wraps a Location so that operations on it, and on the
+// Containers and Items got from it, are retried according to the
+// policy.
+// Put is only retried if the reader is an io.Seeker, which is used to
+// rewind the reader before each retry.
+// The wrapped Containers and Items only implement the methods of the
+// Container and Item interfaces.
+func Retry(loc Location, policy RetryPolicy) Location {
+	return &retryLocation{
+		Location: loc,
+		policy:   policy,
+	}
+}
+
+type retryLocation struct {
+	Location
+	policy RetryPolicy
+}
+
+func (l *retryLocation) CreateContainer(name string) (Container, error) {
+	var c Container
+	err := l.policy.do(nil, func() (err error) {
+		c, err = l.Location.CreateContainer(name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l.container(c), nil
+}
+
+func (l *retryLocation) Containers(prefix string, cursor string, count int) ([]Container, string, error) {
+	var (
+		cs   []Container
+		next string
+	)
+	err := l.policy.do(nil, func() (err error) {
+		cs, next, err = l.Location.Containers(prefix, cursor, count)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	for i, c := range cs {
+		cs[i] = l.container(c)
+	}
+	return cs, next, nil
+}
+
+func (l *retryLocation) Container(id string) (Container, error) {
+	var c Container
+	err := l.policy.do(nil, func() (err error) {
+		c, err = l.Location.Container(id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l.container(c), nil
+}
+
+func (l *retryLocation) RemoveContainer(id string) error {
+	return l.policy.do(nil, func() error {
+		return l.Location.RemoveContainer(id)
+	})
+}
+
+func (l *retryLocation) ItemByURL(u *url.URL) (Item, error) {
+	var item Item
+	err := l.policy.do(nil, func() (err error) {
+		item, err = l.Location.ItemByURL(u)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &retryItem{Item: item, policy: l.policy}, nil
+}
+
+func (l *retryLocation) container(c Container) Container {
+	return &retryContainer{Container: c, policy: l.policy}
+}
+
+type retryContainer struct {
+	Container
+	policy RetryPolicy
+}
+
+func (c *retryContainer) Item(id string) (Item, error) {
+	var item Item
+	err := c.policy.do(nil, func() (err error) {
+		item, err = c.Container.Item(id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &retryItem{Item: item, policy: c.policy}, nil
+}
+
+func (c *retryContainer) Items(prefix, cursor string, count int) ([]Item, string, error) {
+	var (
+		items []Item
+		next  string
+	)
+	err := c.policy.do(nil, func() (err error) {
+		items, next, err = c.Container.Items(prefix, cursor, count)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	for i, item := range items {
+		items[i] = &retryItem{Item: item, policy: c.policy}
+	}
+	return items, next, nil
+}
+
+func (c *retryContainer) RemoveItem(id string) error {
+	return c.policy.do(nil, func() error {
+		return c.Container.RemoveItem(id)
+	})
+}
+
+func (c *retryContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error) {
+	item, err := c.policy.put(c.Container, name, r, size, metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &retryItem{Item: item, policy: c.policy}, nil
+}
+
+// DefaultRetryPolicy is the RetryPolicy used by RetryablePut.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+}
+
+// RetryablePut puts an Item into the Container like Put, retrying
+// failed puts according to DefaultRetryPolicy. The reader is rewound
+// to where it started before each retry. If seeking in rs fails, such
+// as for an *os.File that is a pipe, the put is not retried.
+func RetryablePut(c Container, name string, rs io.ReadSeeker, size int64, metadata map[string]interface{}) (Item, error) {
+	return DefaultRetryPolicy.put(c, name, rs, size, metadata)
+}
+
+// put puts an Item into the Container, retrying failed puts if the
+// reader is an io.Seeker that can be rewound.
+func (p RetryPolicy) put(c Container, name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error) {
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return c.Put(name, r, size, metadata)
+	}
+	start, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return c.Put(name, r, size, metadata)
+	}
+	var item Item
+	put := func() (err error) {
+		item, err = c.Put(name, r, size, metadata)
+		return err
+	}
+	rewind := func() error {
+		_, err := s.Seek(start, io.SeekStart)
+		return err
+	}
+	if err := p.do(rewind, put); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+type retryItem struct {
+	Item
+	policy RetryPolicy
+}
+
+func (i *retryItem) Size() (int64, error) {
+	var size int64
+	err := i.policy.do(nil, func() (err error) {
+		size, err = i.Item.Size()
+		return err
+	})
+	return size, err
+}
+
+func (i *retryItem) Open() (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	err := i.policy.do(nil, func() (err error) {
+		rc, err = i.Item.Open()
+		return err
+	})
+	return rc, err
+}
+
+func (i *retryItem) ETag() (string, error) {
+	var etag string
+	err := i.policy.do(nil, func() (err error) {
+		etag, err = i.Item.ETag()
+		return err
+	})
+	return etag, err
+}
+
+func (i *retryItem) LastMod() (time.Time, error) {
+	var lastMod time.Time
+	err := i.policy.do(nil, func() (err error) {
+		lastMod, err = i.Item.LastMod()
+		return err
+	})
+	return lastMod, err
+}
+
+func (i *retryItem) Metadata() (map[string]interface{}, error) {
+	var md map[string]interface{}
+	err := i.policy.do(nil, func() (err error) {
+		md, err = i.Item.Metadata()
+		return err
+	})
+	return md, err
+}
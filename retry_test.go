@@ -0,0 +1,135 @@
+package stow_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// singleLocation is a Location holding a single Container.
+type singleLocation struct {
+	testLocation
+	container stow.Container
+}
+
+func (l *singleLocation) Container(id string) (stow.Container, error) {
+	return l.container, nil
+}
+
+var errTransient = errors.New("transient")
+
+// flakyContainer is a testContainer whose Item and Put
+// fail a number of times before working.
+type flakyContainer struct {
+	*testContainer
+	failures int
+	calls    int
+}
+
+func (c *flakyContainer) Item(id string) (stow.Item, error) {
+	c.calls++
+	if c.calls <= c.failures {
+		return nil, errTransient
+	}
+	return c.testContainer.Item(id)
+}
+
+func (c *flakyContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+	c.calls++
+	if c.calls <= c.failures {
+		// consume some of the reader before failing
+		io.CopyN(ioutil.Discard, r, 2)
+		return nil, errTransient
+	}
+	return c.testContainer.Put(name, r, size, metadata)
+}
+
+func TestRetry(t *testing.T) {
+	is := is.New(t)
+	flaky := &flakyContainer{testContainer: newTestContainer("c"), failures: 2}
+	loc := stow.Retry(&singleLocation{container: flaky}, stow.RetryPolicy{MaxAttempts: 3})
+	c, err := loc.Container("c")
+	is.NoErr(err)
+
+	// seekable readers are rewound
+	item, err := c.Put("item", strings.NewReader("contents"), 8, nil)
+	is.NoErr(err)
+	is.Equal(flaky.calls, 3)
+	rc, err := item.Open()
+	is.NoErr(err)
+	b, err := ioutil.ReadAll(rc)
+	is.NoErr(err)
+	is.Equal(string(b), "contents")
+
+	flaky.calls = 0
+	_, err = c.Item("item")
+	is.NoErr(err)
+	is.Equal(flaky.calls, 3)
+
+	// giving up after MaxAttempts
+	flaky.calls, flaky.failures = 0, 5
+	_, err = c.Item("item")
+	is.Equal(err, errTransient)
+	is.Equal(flaky.calls, 3)
+
+	// other readers are not retried
+	flaky.calls, flaky.failures = 0, 1
+	_, err = c.Put("item", ioutil.NopCloser(strings.NewReader("contents")), 8, nil)
+	is.Equal(err, errTransient)
+	is.Equal(flaky.calls, 1)
+
+	// not found is not retried
+	flaky.calls, flaky.failures = 0, 0
+	_, err = c.Item("nope")
+	is.Equal(err, stow.ErrNotFound)
+	is.Equal(flaky.calls, 1)
+
+	// custom predicate
+	loc = stow.Retry(&singleLocation{container: flaky}, stow.RetryPolicy{
+		MaxAttempts: 3,
+		IsRetryable: func(err error) bool { return false },
+	})
+	c, err = loc.Container("c")
+	is.NoErr(err)
+	flaky.calls, flaky.failures = 0, 1
+	_, err = c.Item("item")
+	is.Equal(err, errTransient)
+	is.Equal(flaky.calls, 1)
+}
+
+// unseekableReader is an io.ReadSeeker that cannot seek,
+// like a pipe.
+type unseekableReader struct {
+	io.Reader
+}
+
+func (unseekableReader) Seek(offset int64, whence int) (int64, error) {
+	return 0, errors.New("illegal seek")
+}
+
+func TestRetryablePut(t *testing.T) {
+	is := is.New(t)
+	defer func(p stow.RetryPolicy) { stow.DefaultRetryPolicy = p }(stow.DefaultRetryPolicy)
+	stow.DefaultRetryPolicy.BaseDelay = 0
+	flaky := &flakyContainer{testContainer: newTestContainer("c"), failures: 2}
+
+	item, err := stow.RetryablePut(flaky, "item", strings.NewReader("contents"), 8, nil)
+	is.NoErr(err)
+	is.Equal(flaky.calls, 3)
+	rc, err := item.Open()
+	is.NoErr(err)
+	b, err := ioutil.ReadAll(rc)
+	is.NoErr(err)
+	is.Equal(string(b), "contents")
+
+	// readers that cannot seek are not retried
+	flaky.calls, flaky.failures = 0, 1
+	_, err = stow.RetryablePut(flaky, "item", unseekableReader{strings.NewReader("contents")}, 8, nil)
+	is.Equal(err, errTransient)
+	is.Equal(flaky.calls, 1)
+}
@@ -120,6 +120,15 @@ func init() {
 	}
 
 	stow.Register(Kind, makefn, kindfn, validatefn)
+	stow.RegisterConfigKeys(Kind, nil, []string{
+		ConfigAuthType,
+		ConfigAccessKeyID,
+		ConfigSecretKey,
+		ConfigRegion,
+		ConfigEndpoint,
+		ConfigDisableSSL,
+		ConfigV2Signing,
+	})
 }
 
 // Attempts to create a session based on the information given.
@@ -1,7 +1,9 @@
 package s3
 
 import (
+	"context"
 	"io"
+	"net/url"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -9,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/graymeta/stow"
+	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 )
 
@@ -104,11 +107,66 @@ func (c *container) RemoveItem(id string) error {
 	return nil
 }
 
+// maxDeleteObjects is the maximum number of keys S3 deletes per request.
+const maxDeleteObjects = 1000
+
+// RemoveItems removes the items with the specified IDs using as few
+// DeleteObjects requests as possible.
+func (c *container) RemoveItems(ids []string) error {
+	var errs *multierror.Error
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > maxDeleteObjects {
+			n = maxDeleteObjects
+		}
+		objects := make([]*s3.ObjectIdentifier, 0, n)
+		for _, id := range ids[:n] {
+			objects = append(objects, &s3.ObjectIdentifier{Key: aws.String(id)})
+		}
+		res, err := c.client.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(c.name),
+			Delete: &s3.Delete{
+				Objects: objects,
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			for _, id := range ids[:n] {
+				errs = multierror.Append(errs, errors.Wrapf(err, "RemoveItems, deleting object %s", id))
+			}
+		} else {
+			for _, e := range res.Errors {
+				errs = multierror.Append(errs, errors.Errorf("RemoveItems, deleting object %s: %s", aws.StringValue(e.Key), aws.StringValue(e.Message)))
+			}
+		}
+		ids = ids[n:]
+	}
+	return errs.ErrorOrNil()
+}
+
 // Put sends a request to upload content to the container. The arguments
 // received are the name of the item (S3 Object), a reader representing the
 // content, and the size of the file. Many more attributes can be given to the
 // file, including metadata. Keeping it simple for now.
 func (c *container) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+	return c.PutContext(aws.BackgroundContext(), name, r, size, metadata)
+}
+
+// PutContext is like Put, but the requests are made with the given context.
+func (c *container) PutContext(ctx context.Context, name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+	// The content type is sent as a header rather than as metadata.
+	var contentType *string
+	if ct, ok := metadata[stow.MetadataContentType].(string); ok {
+		contentType = aws.String(ct)
+		md := make(map[string]interface{}, len(metadata))
+		for k, v := range metadata {
+			if k != stow.MetadataContentType {
+				md[k] = v
+			}
+		}
+		metadata = md
+	}
+
 	// Convert map[string]interface{} to map[string]*string
 	mdPrepped, err := prepMetadata(metadata)
 	if err != nil {
@@ -116,17 +174,18 @@ func (c *container) Put(name string, r io.Reader, size int64, metadata map[strin
 	}
 
 	uploader := s3manager.NewUploaderWithClient(c.client)
-	_, err = uploader.Upload(&s3manager.UploadInput{
-		Bucket:   aws.String(c.name), // Required
-		Key:      aws.String(name),   // Required
-		Body:     r,
-		Metadata: mdPrepped, // map[string]*string
+	_, err = uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:      aws.String(c.name), // Required
+		Key:         aws.String(name),   // Required
+		Body:        r,
+		ContentType: contentType,
+		Metadata:    mdPrepped, // map[string]*string
 	})
 
 	if err != nil {
 		return nil, errors.Wrap(err, "PutObject, putting object")
 	}
-	i, err := c.client.HeadObject(&s3.HeadObjectInput{
+	i, err := c.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
 		Key:    aws.String(name),
 		Bucket: aws.String(c.name),
 	})
@@ -134,6 +193,11 @@ func (c *container) Put(name string, r io.Reader, size int64, metadata map[strin
 	if i.ETag != nil && err == nil {
 		etag = cleanEtag(*i.ETag)
 	}
+	// The uploader streams readers of unknown size, so take the size
+	// from the uploaded object.
+	if size < 0 && err == nil && i.ContentLength != nil {
+		size = *i.ContentLength
+	}
 
 	// Some fields are empty because this information isn't included in the response.
 	// May have to involve sending a request if we want more specific information.
@@ -144,9 +208,10 @@ func (c *container) Put(name string, r io.Reader, size int64, metadata map[strin
 		container: c,
 		client:    c.client,
 		properties: properties{
-			ETag: &etag,
-			Key:  &name,
-			Size: &size,
+			ETag:        &etag,
+			Key:         &name,
+			Size:        &size,
+			ContentType: contentType,
 			//LastModified *time.Time
 			//Owner        *s3.Owner
 			//StorageClass *string
@@ -156,6 +221,25 @@ func (c *container) Put(name string, r io.Reader, size int64, metadata map[strin
 	return newItem, nil
 }
 
+// ServerSideCopy copies an item from any S3 bucket reachable with this
+// container's client into this container using CopyObject. The metadata of
+// the source item is copied along with its contents.
+func (c *container) ServerSideCopy(dstName string, src stow.Item) (stow.Item, error) {
+	srcItem, ok := src.(*item)
+	if !ok || srcItem.client != c.client {
+		return nil, stow.NotSupported("server side copy from another location")
+	}
+	_, err := c.client.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String(c.name),
+		Key:        aws.String(dstName),
+		CopySource: aws.String((&url.URL{Path: srcItem.container.name + "/" + srcItem.ID()}).EscapedPath()),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "ServerSideCopy, copying object")
+	}
+	return c.getItem(dstName)
+}
+
 // Region returns a string representing the region/availability zone of the container.
 func (c *container) Region() string {
 	return c.region
@@ -178,7 +262,7 @@ func (c *container) getItem(id string) (*item, error) {
 	if err != nil {
 		// stow needs ErrNotFound to pass the test but amazon returns an opaque error
 		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NotFound" {
-			return nil, stow.ErrNotFound
+			return nil, stow.NotFound(err)
 		}
 		return nil, errors.Wrap(err, "getItem, getting the object")
 	}
@@ -199,6 +283,7 @@ func (c *container) getItem(id string) (*item, error) {
 			Owner:        nil, // not returned in the response.
 			Size:         res.ContentLength,
 			StorageClass: res.StorageClass,
+			ContentType:  res.ContentType,
 			Metadata:     md,
 		},
 	}
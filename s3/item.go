@@ -1,14 +1,17 @@
 package s3
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/graymeta/stow"
 	"github.com/pkg/errors"
@@ -42,6 +45,7 @@ type properties struct {
 	Owner        *s3.Owner  `type:"structure"`
 	Size         *int64     `type:"integer"`
 	StorageClass *string    `type:"string" enum:"ObjectStorageClass"`
+	ContentType  *string    `type:"string"`
 	Metadata     map[string]interface{}
 }
 
@@ -83,18 +87,56 @@ func (i *item) URL() *url.URL {
 // and path of the file within the container. This response includes the body of
 // resource which is returned along with an error.
 func (i *item) Open() (io.ReadCloser, error) {
+	return i.OpenContext(aws.BackgroundContext())
+}
+
+// OpenContext is like Open, but the request is made with the given context.
+func (i *item) OpenContext(ctx context.Context) (io.ReadCloser, error) {
 	params := &s3.GetObjectInput{
 		Bucket: aws.String(i.container.Name()),
 		Key:    aws.String(i.ID()),
 	}
 
-	response, err := i.client.GetObject(params)
+	response, err := i.client.GetObjectWithContext(ctx, params)
 	if err != nil {
 		return nil, errors.Wrap(err, "Open, getting the object")
 	}
 	return response.Body, nil
 }
 
+// SignedURL gets a presigned URL for getting the object, which
+// expires after the specified duration.
+func (i *item) SignedURL(expiry time.Duration) (*url.URL, error) {
+	req, _ := i.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(i.container.Name()),
+		Key:    aws.String(i.ID()),
+	})
+	signed, err := req.Presign(expiry)
+	if err != nil {
+		return nil, errors.Wrap(err, "SignedURL, presigning the request")
+	}
+	return url.Parse(signed)
+}
+
+// OpenIfModifiedSince is like Open, but the object is only got if it
+// was modified after t. It gets whether the object was got.
+func (i *item) OpenIfModifiedSince(t time.Time) (io.ReadCloser, bool, error) {
+	params := &s3.GetObjectInput{
+		Bucket:          aws.String(i.container.Name()),
+		Key:             aws.String(i.ID()),
+		IfModifiedSince: aws.Time(t),
+	}
+
+	response, err := i.client.GetObject(params)
+	if aerr, ok := err.(awserr.RequestFailure); ok && aerr.StatusCode() == http.StatusNotModified {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "OpenIfModifiedSince, getting the object")
+	}
+	return response.Body, true, nil
+}
+
 // LastMod returns the last modified date of the item. The response of an item that is PUT
 // does not contain this field. Solution? Detect when the LastModified field (a *time.Time)
 // is nil, then do a manual request for it via the Item() method of the container which
@@ -108,6 +150,19 @@ func (i *item) LastMod() (time.Time, error) {
 	return *i.properties.LastModified, nil
 }
 
+// ContentType returns the content type of the object. Objects retrieved
+// by listing a bucket do not include it, so it is requested when missing.
+func (i *item) ContentType() (string, error) {
+	if i.properties.ContentType == nil {
+		itemInfo, err := i.container.getItem(i.ID())
+		if err != nil {
+			return "", errors.Wrap(err, "retrieving content type")
+		}
+		i.properties.ContentType = itemInfo.properties.ContentType
+	}
+	return aws.StringValue(i.properties.ContentType), nil
+}
+
 // ETag returns the ETag value from the properies field of an item.
 func (i *item) ETag() (string, error) {
 	return *(i.properties.ETag), nil
@@ -121,6 +176,52 @@ func (i *item) Metadata() (map[string]interface{}, error) {
 	return i.properties.Metadata, nil
 }
 
+// SetMetadata replaces the metadata of the object by copying
+// it onto itself. Replacing the metadata also replaces the headers
+// of the object, so its Content-Type, Cache-Control, Content-Encoding,
+// Content-Disposition, Content-Language and Expires headers are got
+// first and copied along.
+func (i *item) SetMetadata(metadata map[string]interface{}) error {
+	if metadata == nil {
+		return nil
+	}
+	mdPrepped, err := prepMetadata(metadata)
+	if err != nil {
+		return errors.Wrap(err, "unable to set metadata, preparing metadata")
+	}
+	head, err := i.client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(i.container.name),
+		Key:    aws.String(i.ID()),
+	})
+	if err != nil {
+		return errors.Wrap(err, "SetMetadata, getting object headers")
+	}
+	var expires *time.Time
+	if head.Expires != nil {
+		if t, err := http.ParseTime(*head.Expires); err == nil {
+			expires = &t
+		}
+	}
+	_, err = i.client.CopyObject(&s3.CopyObjectInput{
+		Bucket:             aws.String(i.container.name),
+		Key:                aws.String(i.ID()),
+		CopySource:         aws.String((&url.URL{Path: i.container.name + "/" + i.ID()}).EscapedPath()),
+		Metadata:           mdPrepped,
+		MetadataDirective:  aws.String(s3.MetadataDirectiveReplace),
+		ContentType:        head.ContentType,
+		CacheControl:       head.CacheControl,
+		ContentEncoding:    head.ContentEncoding,
+		ContentDisposition: head.ContentDisposition,
+		ContentLanguage:    head.ContentLanguage,
+		Expires:            expires,
+	})
+	if err != nil {
+		return errors.Wrap(err, "SetMetadata, copying object")
+	}
+	i.properties.Metadata, err = parseMetadata(mdPrepped)
+	return err
+}
+
 func (i *item) ensureInfo() error {
 	if i.properties.Metadata == nil || i.properties.LastModified == nil {
 		i.infoOnce.Do(func() {
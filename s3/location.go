@@ -181,7 +181,7 @@ func (l *location) Container(id string) (stow.Container, error) {
 	_, err := client.GetBucketLocation(params)
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NoSuchBucket" {
-			return nil, stow.ErrNotFound
+			return nil, stow.NotFound(err)
 		}
 
 		return nil, errors.Wrap(err, "GetBucketLocation")
@@ -205,3 +205,49 @@ func TestWillRequestRegionWhenConfigured(t *testing.T) {
 	// Make sure that this is an error
 	is.NoErr(err)
 }
+
+func TestSetMetadataKeepsHeaders(t *testing.T) {
+	is := is.New(t)
+
+	var copyHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("ETag", `"etag"`)
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Header().Set("Content-Disposition", "attachment")
+			w.WriteHeader(http.StatusOK)
+		case http.MethodPut:
+			copyHeaders = r.Header.Clone()
+			w.Write([]byte(`<CopyObjectResult><ETag>"etag"</ETag></CopyObjectResult>`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	config := stow.ConfigMap{
+		"access_key_id": "access-key",
+		"secret_key":    "secret-key",
+		"region":        "do-not-care",
+		"endpoint":      server.URL,
+	}
+	location, err := stow.Dial("s3", config)
+	is.NoErr(err)
+	container, err := location.Container("bucket")
+	is.NoErr(err)
+	item, err := container.Item("key")
+	is.NoErr(err)
+	is.NoErr(item.(stow.MetadataSetter).SetMetadata(map[string]interface{}{"owner": "me"}))
+
+	is.OK(copyHeaders)
+	is.Equal(copyHeaders.Get("X-Amz-Metadata-Directive"), "REPLACE")
+	is.Equal(copyHeaders.Get("X-Amz-Meta-Owner"), "me")
+	is.Equal(copyHeaders.Get("Content-Type"), "application/json")
+	is.Equal(copyHeaders.Get("Cache-Control"), "max-age=60")
+	is.Equal(copyHeaders.Get("Content-Encoding"), "gzip")
+	is.Equal(copyHeaders.Get("Content-Disposition"), "attachment")
+}
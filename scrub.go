@@ -0,0 +1,117 @@
+package stow
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"strings"
+	"sync"
+)
+
+// MetadataContentMD5 is the user metadata key holding the hex encoded
+// MD5 checksum of the contents of an Item, recorded when it was put,
+// which Scrub checks the contents against.
+const MetadataContentMD5 = "content_md5"
+
+// ScrubStatus is the outcome of checking an Item with Scrub.
+type ScrubStatus int
+
+const (
+	// ScrubOK means the contents match the recorded checksum.
+	ScrubOK ScrubStatus = iota
+	// ScrubMismatch means the contents do not match the recorded
+	// checksum, and may have been corrupted.
+	ScrubMismatch
+	// ScrubMissingHash means no checksum was recorded for the Item,
+	// so its contents were not read.
+	ScrubMissingHash
+	// ScrubReadError means the metadata or the contents of the Item
+	// could not be read.
+	ScrubReadError
+)
+
+// String gets the name of the status.
+func (s ScrubStatus) String() string {
+	switch s {
+	case ScrubOK:
+		return "ok"
+	case ScrubMismatch:
+		return "mismatch"
+	case ScrubMissingHash:
+		return "missing hash"
+	case ScrubReadError:
+		return "read error"
+	}
+	return "unknown"
+}
+
+// ScrubResult is the result of checking an Item with Scrub.
+type ScrubResult struct {
+	Item   Item
+	Status ScrubStatus
+	// Expected is the recorded checksum, if there is one.
+	Expected string
+	// Actual is the checksum of the contents, if they were read.
+	Actual string
+	// Err is the error reading the Item, for ScrubReadError.
+	Err error
+}
+
+// scrubPageSize is the number of Items Scrub gets per request.
+const scrubPageSize = 100
+
+// Scrub checks the contents of the Items of the Container whose names
+// start with the prefix against the MD5 checksums recorded in their
+// user metadata under MetadataContentMD5, to find Items whose contents
+// were corrupted. Items are checked by the specified number of
+// goroutines at once, and report is called with the result for each
+// of them, one call at a time.
+// Only errors listing the Items are returned; errors reading them are
+// reported.
+func Scrub(c Container, prefix string, workers int, report func(ScrubResult)) error {
+	var reportLock sync.Mutex
+	return WalkParallel(c, prefix, scrubPageSize, workers, func(item Item, err error) error {
+		if err != nil {
+			return err
+		}
+		res := scrubItem(item)
+		reportLock.Lock()
+		defer reportLock.Unlock()
+		report(res)
+		return nil
+	})
+}
+
+// scrubItem checks the contents of the Item against its
+// recorded checksum.
+func scrubItem(item Item) ScrubResult {
+	res := ScrubResult{Item: item}
+	md, err := item.Metadata()
+	if err != nil {
+		res.Status, res.Err = ScrubReadError, err
+		return res
+	}
+	res.Expected = metadataString(md, MetadataContentMD5)
+	if res.Expected == "" {
+		res.Status = ScrubMissingHash
+		return res
+	}
+	rc, err := item.Open()
+	if err != nil {
+		res.Status, res.Err = ScrubReadError, err
+		return res
+	}
+	defer rc.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		res.Status, res.Err = ScrubReadError, err
+		return res
+	}
+	res.Actual = hex.EncodeToString(h.Sum(nil))
+	if strings.EqualFold(res.Actual, res.Expected) {
+		res.Status = ScrubOK
+	} else {
+		res.Status = ScrubMismatch
+	}
+	return res
+}
@@ -0,0 +1,48 @@
+package stow_test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestScrub(t *testing.T) {
+	is := is.New(t)
+	c := &vanishingContainer{newTestContainer("c")}
+	sum := func(s string) string {
+		h := md5.Sum([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+	put := func(name, data string, md map[string]interface{}) {
+		_, err := c.Put(name, strings.NewReader(data), int64(len(data)), md)
+		is.NoErr(err)
+	}
+	put("a/ok", "ok", map[string]interface{}{stow.MetadataContentMD5: sum("ok")})
+	put("a/rotten", "rotten", map[string]interface{}{stow.MetadataContentMD5: sum("fresh")})
+	put("a/unhashed", "unhashed", nil)
+	put("a/gone", "gone", map[string]interface{}{stow.MetadataContentMD5: sum("gone")})
+	// hashes nested in the metadata are found too
+	put("a/nested", "nested", map[string]interface{}{"user_data": map[string]interface{}{stow.MetadataContentMD5: sum("nested")}})
+	put("b/skipped", "skipped", nil)
+
+	results := make(map[string]stow.ScrubResult)
+	err := stow.Scrub(c, "a/", 3, func(res stow.ScrubResult) {
+		results[res.Item.Name()] = res
+	})
+	is.NoErr(err)
+	is.Equal(len(results), 5)
+	is.Equal(results["a/ok"].Status, stow.ScrubOK)
+	is.Equal(results["a/nested"].Status, stow.ScrubOK)
+	is.Equal(results["a/rotten"].Status, stow.ScrubMismatch)
+	is.Equal(results["a/rotten"].Expected, sum("fresh"))
+	is.Equal(results["a/rotten"].Actual, sum("rotten"))
+	is.Equal(results["a/unhashed"].Status, stow.ScrubMissingHash)
+	is.Equal(results["a/gone"].Status, stow.ScrubReadError)
+	is.True(errors.Is(results["a/gone"].Err, stow.ErrNotFound))
+	is.Equal(stow.ScrubMismatch.String(), "mismatch")
+}
@@ -2,12 +2,12 @@ package sftp
 
 import (
 	"fmt"
+	"io"
 	"net/url"
 	"strconv"
 
 	"github.com/graymeta/stow"
 	"github.com/pkg/errors"
-	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -46,6 +46,18 @@ const (
 	ConfigBasePath = "base_path"
 )
 
+// configKeys are the configuration items that identify
+// a connection.
+var configKeys = []string{
+	ConfigHost,
+	ConfigPort,
+	ConfigUsername,
+	ConfigPassword,
+	ConfigPrivateKey,
+	ConfigPrivateKeyPassphrase,
+	ConfigHostPublicKey,
+}
+
 type conf struct {
 	host      string
 	port      int
@@ -146,23 +158,21 @@ func init() {
 			return nil, err
 		}
 
-		loc := &location{
-			config: c,
-		}
-
-		// Connect to the remote server and perform the SSH handshake.
-		loc.sshClient, err = ssh.Dial("tcp", c.Host(), &c.sshConfig)
+		// Locations dialed with the same configuration share a connection.
+		key := stow.ConfigKey(config, configKeys...)
+		client, err := clients.Acquire(key, func() (io.Closer, error) {
+			return dial(c)
+		})
 		if err != nil {
-			return nil, errors.Wrap(err, "ssh connection")
+			return nil, err
 		}
+		conn := client.(*connection)
 
-		// Open an SFTP session over an existing ssh connection.
-		loc.sftpClient, err = sftp.NewClient(loc.sshClient)
-		if err != nil {
-			// close the ssh connection if the sftp connection fails. This avoids leaking
-			// the ssh connection.
-			loc.Close()
-			return nil, errors.Wrap(err, "sftp connection")
+		loc := &location{
+			config:     c,
+			sshClient:  conn.sshClient,
+			sftpClient: conn.sftpClient,
+			clientKey:  key,
 		}
 
 		return loc, nil
@@ -173,4 +183,11 @@ func init() {
 	}
 
 	stow.Register(Kind, makefn, kindfn, validatefn)
+	stow.RegisterConfigKeys(Kind, []string{ConfigHost, ConfigPort, ConfigUsername}, []string{
+		ConfigPassword,
+		ConfigPrivateKey,
+		ConfigPrivateKeyPassphrase,
+		ConfigHostPublicKey,
+		ConfigBasePath,
+	})
 }
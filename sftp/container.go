@@ -32,7 +32,7 @@ func (c *container) Item(id string) (stow.Item, error) {
 	info, err := c.location.sftpClient.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, stow.ErrNotFound
+			return nil, stow.NotFound(err)
 		}
 		return nil, err
 	}
@@ -161,7 +161,11 @@ func (c *container) getFolderItems(entries []entry, prefix, relPath, id, cursor
 
 // RemoveItem removes a file from the remote server.
 func (c *container) RemoveItem(id string) error {
-	return c.location.sftpClient.Remove(filepath.Join(c.location.config.basePath, c.name, filepath.FromSlash(id)))
+	err := c.location.sftpClient.Remove(filepath.Join(c.location.config.basePath, c.name, filepath.FromSlash(id)))
+	if os.IsNotExist(err) {
+		return stow.NotFound(err)
+	}
+	return err
 }
 
 // Put sends a request to upload content to the container.
@@ -189,9 +193,10 @@ func (c *container) Put(name string, r io.Reader, size int64, metadata map[strin
 	if err != nil {
 		return nil, err
 	}
-	if n != size {
+	if size >= 0 && n != size {
 		return nil, errors.New("bad size")
 	}
+	item.size = n
 
 	info, err := c.location.sftpClient.Stat(path)
 	if err != nil {
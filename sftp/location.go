@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/graymeta/stow"
 	"github.com/hashicorp/go-multierror"
@@ -14,12 +15,69 @@ import (
 	"golang.org/x/crypto/ssh"
 )
 
+// clients holds the connections shared by locations dialed
+// with the same configuration.
+var clients stow.ClientRegistry
+
 type location struct {
 	// we keep config here so that we can access the server information (username/host)
 	// when constructing the item urls.
 	config     *conf
 	sshClient  *ssh.Client
 	sftpClient *sftp.Client
+	// clientKey is the key of the shared connection in clients.
+	clientKey string
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// connection is an sftp session over an ssh connection,
+// shared between locations.
+type connection struct {
+	sshClient  *ssh.Client
+	sftpClient *sftp.Client
+}
+
+// dial connects to the remote server and opens an sftp session.
+func dial(c *conf) (*connection, error) {
+	var (
+		conn connection
+		err  error
+	)
+	// Connect to the remote server and perform the SSH handshake.
+	conn.sshClient, err = ssh.Dial("tcp", c.Host(), &c.sshConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "ssh connection")
+	}
+
+	// Open an SFTP session over an existing ssh connection.
+	conn.sftpClient, err = sftp.NewClient(conn.sshClient)
+	if err != nil {
+		// close the ssh connection if the sftp connection fails. This avoids leaking
+		// the ssh connection.
+		conn.Close()
+		return nil, errors.Wrap(err, "sftp connection")
+	}
+	return &conn, nil
+}
+
+// Close closes the sftp/ssh connections.
+func (c *connection) Close() error {
+	var errs error
+
+	if c.sftpClient != nil {
+		if err := c.sftpClient.Close(); err != nil {
+			errs = multierror.Append(errs, errors.Wrap(err, "closing sftp conn"))
+		}
+	}
+
+	if c.sshClient != nil {
+		if err := c.sshClient.Close(); err != nil {
+			errs = multierror.Append(errs, errors.Wrap(err, "closing ssh conn"))
+		}
+	}
+
+	return errs
 }
 
 // CreateContainer creates a new container, in this case a directory on the remote server.
@@ -78,23 +136,14 @@ func (l *location) Containers(prefix, cursor string, count int) ([]stow.Containe
 	return cont, "", nil
 }
 
-// Close closes the underlying sftp/ssh connections.
+// Close releases the underlying sftp/ssh connections, which are
+// closed once no other location dialed with the same configuration
+// uses them.
 func (l *location) Close() error {
-	var errs error
-
-	if l.sftpClient != nil {
-		if err := l.sftpClient.Close(); err != nil {
-			errs = multierror.Append(errs, errors.Wrap(err, "closing sftp conn"))
-		}
-	}
-
-	if l.sshClient != nil {
-		if err := l.sshClient.Close(); err != nil {
-			errs = multierror.Append(errs, errors.Wrap(err, "closing ssh conn"))
-		}
-	}
-
-	return errs
+	l.closeOnce.Do(func() {
+		l.closeErr = clients.Release(l.clientKey)
+	})
+	return l.closeErr
 }
 
 // Container retrieves a stow.Container based on its name which must be exact.
@@ -102,7 +151,7 @@ func (l *location) Container(id string) (stow.Container, error) {
 	fi, err := l.sftpClient.Stat(filepath.Join(l.config.basePath, id))
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, stow.ErrNotFound
+			return nil, stow.NotFound(err)
 		}
 		return nil, err
 	}
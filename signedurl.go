@@ -0,0 +1,24 @@
+package stow
+
+import (
+	"net/url"
+	"time"
+)
+
+// URLSigner represents an Item that can be downloaded directly from
+// the backend with a signed URL, such as to hand it to a web client.
+type URLSigner interface {
+	// SignedURL gets a URL for getting the contents of the Item,
+	// which expires after the specified duration.
+	SignedURL(expiry time.Duration) (*url.URL, error)
+}
+
+// SignedURL gets a URL for getting the contents of the Item, which
+// expires after the specified duration. Items that are not URLSigners
+// fail with an error for which IsNotSupported is true.
+func SignedURL(item Item, expiry time.Duration) (*url.URL, error) {
+	if s, ok := item.(URLSigner); ok {
+		return s.SignedURL(expiry)
+	}
+	return nil, NotSupported("signed urls")
+}
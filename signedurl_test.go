@@ -0,0 +1,34 @@
+package stow_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// signingItem is a URLSigner with URLs holding their expiry.
+type signingItem struct {
+	*testItem
+}
+
+func (i *signingItem) SignedURL(expiry time.Duration) (*url.URL, error) {
+	return &url.URL{
+		Scheme:   "https",
+		Host:     "example.com",
+		Path:     "/" + i.Name(),
+		RawQuery: url.Values{"expires": {expiry.String()}}.Encode(),
+	}, nil
+}
+
+func TestSignedURL(t *testing.T) {
+	is := is.New(t)
+	u, err := stow.SignedURL(&signingItem{testItem: &testItem{name: "a"}}, time.Minute)
+	is.NoErr(err)
+	is.Equal(u.String(), "https://example.com/a?expires=1m0s")
+
+	_, err = stow.SignedURL(&testItem{name: "plain"}, time.Minute)
+	is.True(stow.IsNotSupported(err))
+}
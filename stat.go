@@ -0,0 +1,39 @@
+package stow
+
+// StatReporter represents a Container that can report how many
+// Items it holds and their total size without listing them.
+type StatReporter interface {
+	// Stat gets the number of Items in the Container and the
+	// sum of their sizes in bytes.
+	Stat() (objectCount int64, totalBytes int64, err error)
+}
+
+// statPageSize is the number of Items listed per request
+// by ContainerStat.
+const statPageSize = 1000
+
+// ContainerStat gets the number of Items in the Container and the
+// sum of their sizes in bytes.
+// The Container is used as a StatReporter if possible, otherwise
+// all Items are walked and their sizes summed.
+func ContainerStat(c Container) (objectCount int64, totalBytes int64, err error) {
+	if sr, ok := c.(StatReporter); ok {
+		return sr.Stat()
+	}
+	err = Walk(c, NoPrefix, statPageSize, func(item Item, err error) error {
+		if err != nil {
+			return err
+		}
+		size, err := item.Size()
+		if err != nil {
+			return err
+		}
+		objectCount++
+		totalBytes += size
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return objectCount, totalBytes, nil
+}
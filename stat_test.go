@@ -0,0 +1,45 @@
+package stow_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestContainerStat(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	count, total, err := stow.ContainerStat(c)
+	is.NoErr(err)
+	is.Equal(count, int64(0))
+	is.Equal(total, int64(0))
+
+	for _, s := range []string{"one", "three", "fifteen"} {
+		_, err := c.Put(s, strings.NewReader(s), int64(len(s)), nil)
+		is.NoErr(err)
+	}
+	count, total, err = stow.ContainerStat(c)
+	is.NoErr(err)
+	is.Equal(count, int64(3))
+	is.Equal(total, int64(15))
+}
+
+// statContainer is a testContainer that reports fixed stats.
+type statContainer struct {
+	*testContainer
+}
+
+func (c *statContainer) Stat() (int64, int64, error) {
+	return 42, 1024, nil
+}
+
+func TestContainerStatReporter(t *testing.T) {
+	is := is.New(t)
+	c := &statContainer{testContainer: newTestContainer("c")}
+	count, total, err := stow.ContainerStat(c)
+	is.NoErr(err)
+	is.Equal(count, int64(42))
+	is.Equal(total, int64(1024))
+}
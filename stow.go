@@ -1,15 +1,19 @@
 package stow
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/url"
+	"strconv"
 	"sync"
 	"time"
 )
 
 var (
-	lock sync.RWMutex // protects locations, kinds and kindmatches
+	lock sync.RWMutex // protects locations, kinds, kindmatches and configKeys
 	// kinds holds a list of location kinds.
 	kinds = []string{}
 	// locations is a map of installed location providers,
@@ -24,16 +28,52 @@ var (
 	// URL. Functions return an empty string if it does not
 	// match.
 	kindmatches []func(*url.URL) string
+	// configKeys is a map of the configuration items of location
+	// kinds, registered with RegisterConfigKeys.
+	configKeys = map[string]kindConfigKeys{}
 )
 
+// kindConfigKeys are the configuration items of a kind of Location.
+type kindConfigKeys struct {
+	required []string
+	optional []string
+}
+
 var (
 	// ErrNotFound is returned when something could not be found.
+	// Errors wrapping it are returned too, so callers should check
+	// for it with errors.Is.
 	ErrNotFound = errors.New("not found")
 	// ErrBadCursor is returned by paging methods when the specified
 	// cursor is invalid.
 	ErrBadCursor = errors.New("bad cursor")
+	// ErrNotSupported is returned when a backend does not support an
+	// operation, such as by the helpers of the optional interfaces
+	// when there is no fallback. The errors returned wrap it with the
+	// name of the operation, so callers should check for it with
+	// errors.Is.
+	ErrNotSupported = errors.New("not supported")
+	// ErrChecksumMismatch is returned when the contents of an Item
+	// do not match the expected checksum.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrReadOnly is returned by the Location wrapped with ReadOnly
+	// when asked to make a change.
+	ErrReadOnly = errors.New("read only")
+	// ErrAlreadyExists is returned by PutIfNotExists when there
+	// already is an Item with the specified name.
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrStopWalk is returned by a WalkFunc to stop Walk or
+	// WalkParallel early without an error.
+	ErrStopWalk = errors.New("stop walk")
+	// ErrQuotaExceeded is returned by the Container wrapped with
+	// QuotaContainer when a Put would exceed its quota.
+	ErrQuotaExceeded = errors.New("quota exceeded")
 )
 
+// MetadataContentType is the metadata key used to give Put
+// the MIME type of the contents of an Item.
+const MetadataContentType = "content_type"
+
 var (
 	// CursorStart is a string representing a cursor pointing
 	// to the first page of items or containers.
@@ -99,6 +139,9 @@ type Container interface {
 	RemoveItem(id string) error
 	// Put creates a new Item with the specified name, and contents
 	// read from the reader.
+	// A negative size means the size is unknown, and the reader is read
+	// until EOF. Implementations that need to know the size up front
+	// return an error satisfying IsNotSupported instead.
 	Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error)
 }
 
@@ -138,6 +181,120 @@ type ItemRanger interface {
 	OpenRange(start, end uint64) (io.ReadCloser, error)
 }
 
+// RangeOpener is an alias for ItemRanger.
+type RangeOpener = ItemRanger
+
+// Range is a range of bytes of an Item, from byte Start to
+// byte End, like the ranges read by OpenRange.
+type Range struct {
+	Start uint64
+	End   uint64
+}
+
+// MultiRangeOpener represents an Item that can read several
+// ranges of its contents at once.
+type MultiRangeOpener interface {
+	// OpenRanges opens the Item for reading the ranges one after
+	// another, in the order given. Ranges are read as they are, even
+	// if they overlap.
+	OpenRanges(ranges []Range) (io.ReadCloser, error)
+}
+
+// ContextOpener represents an Item that can be opened for
+// reading with a context.
+type ContextOpener interface {
+	// OpenContext opens the Item for reading. Reading stops with the
+	// context error once the context is done.
+	// Calling code must close the io.ReadCloser.
+	OpenContext(ctx context.Context) (io.ReadCloser, error)
+}
+
+// ConditionalOpener represents an Item that can be opened only if
+// it was modified after a time.
+type ConditionalOpener interface {
+	// OpenIfModifiedSince opens the Item for reading if it was modified
+	// after t, and gets whether it was opened. If it was not modified,
+	// the io.ReadCloser is nil. Otherwise calling code must close it.
+	OpenIfModifiedSince(t time.Time) (io.ReadCloser, bool, error)
+}
+
+// ContextPutter represents a Container that can put Items with a context.
+type ContextPutter interface {
+	// PutContext creates a new Item with the specified name, and contents
+	// read from the reader. The put is abandoned with the context
+	// error once the context is done.
+	PutContext(ctx context.Context, name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error)
+}
+
+// ConditionalPutter represents a Container that can put Items only
+// if they do not exist yet.
+type ConditionalPutter interface {
+	// PutIfNotExists creates a new Item like Put, but fails with
+	// ErrAlreadyExists if there already is an Item with the specified
+	// name. Only one of many concurrent calls for a name succeeds.
+	PutIfNotExists(name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error)
+}
+
+// PrefixLister represents a Container that can list a single level
+// of its Items, treating the names of Items as paths separated by a
+// delimiter, like the folders of a file browser.
+type PrefixLister interface {
+	// Prefixes gets a page of the Items whose names start with the
+	// prefix and do not contain the delimiter after it, along with
+	// the distinct prefixes, ending with the delimiter, of the names
+	// that do. Prefixes and Items together make up at most count
+	// entries, and the returned cursor is used to get the next page.
+	// The cursor returned with the last page is CursorStart.
+	Prefixes(prefix, delimiter, cursor string, count int) (prefixes []string, items []Item, next string, err error)
+}
+
+// MetadataSetter represents an Item whose metadata can be
+// changed without putting it again.
+type MetadataSetter interface {
+	// SetMetadata replaces the metadata of the Item.
+	// A nil map leaves the metadata unchanged, while an empty
+	// map removes all of it.
+	SetMetadata(metadata map[string]interface{}) error
+}
+
+// TypedMetadata represents an Item whose user metadata, the metadata
+// given to Put or SetMetadata, can be read as typed values.
+type TypedMetadata interface {
+	// StringMetadata gets the user metadata value for the key,
+	// if it is a string.
+	StringMetadata(key string) (string, bool)
+	// IntMetadata gets the user metadata value for the key, if it is
+	// an integer or a string holding one.
+	IntMetadata(key string) (int64, bool)
+	// UnmarshalUserMetadata decodes the user metadata into v, which
+	// is typically a pointer to a struct, like json.Unmarshal.
+	UnmarshalUserMetadata(v interface{}) error
+}
+
+// RawMetadata represents an Item whose user metadata is stored as
+// JSON, which can be read as it is stored.
+type RawMetadata interface {
+	// RawUserMetadata gets the JSON encoding of the user metadata, or
+	// nil if there is none, so that it can be decoded into other types
+	// than the map of Metadata, without numbers becoming float64s.
+	RawUserMetadata() (json.RawMessage, error)
+}
+
+// ContentTyper represents an Item that knows the MIME type
+// of its contents.
+type ContentTyper interface {
+	// ContentType gets the MIME type of the Item's contents.
+	ContentType() (string, error)
+}
+
+// ContainerLinker represents an Item that can get the Container
+// it belongs to.
+type ContainerLinker interface {
+	// Container gets the Container holding the Item, such as the
+	// Container of an Item got from Location.ItemByURL.
+	Container() (Container, error)
+}
+
 // Taggable represents a taggable Item
 type Taggable interface {
 	// Tags returns a list of tags that belong to a given Item
@@ -153,6 +310,13 @@ type Config interface {
 	Set(name, value string)
 }
 
+// ConfigValidator represents a Config that can check itself, beyond
+// the checks made by the kind of Location it is for.
+type ConfigValidator interface {
+	// Validate checks the configuration values.
+	Validate() error
+}
+
 // Register adds a Location implementation, with two helper functions.
 // makefn should make a Location with the given Config.
 // kindmatchfn should inspect a URL and return whether it represents a Location
@@ -179,20 +343,35 @@ func Register(kind string, makefn func(Config) (Location, error), kindmatchfn fu
 
 // Dial gets a new Location with the given kind and
 // configuration.
+// The configuration is first checked with Validate, so that
+// misconfigurations are reported before the Location is made.
 func Dial(kind string, config Config) (Location, error) {
 	fn, ok := locations[kind]
 	if !ok {
 		return nil, errUnknownKind(kind)
 	}
+	if err := Validate(kind, config); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", kind, err)
+	}
 	return fn(config)
 }
 
-// Validate validates the config for a location
+// Validate validates the config for a location.
+// If the config is a ConfigValidator, its own Validate method is
+// called first.
 func Validate(kind string, config Config) error {
 	fn, ok := configurations[kind]
 	if !ok {
 		return errUnknownKind(kind)
 	}
+	if v, ok := config.(ConfigValidator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+	if fn == nil {
+		return nil
+	}
 	return fn(config)
 }
 
@@ -200,7 +379,50 @@ func Validate(kind string, config Config) error {
 func Kinds() []string {
 	lock.RLock()
 	defer lock.RUnlock()
-	return kinds
+	return append([]string(nil), kinds...)
+}
+
+// RegisterConfigKeys records the configuration items that a kind of
+// Location supports: the required ones, which must always be set, and
+// the optional ones, which may be set, or are only required with some
+// values of other items.
+// RegisterConfigKeys is usually called in an implementation package's
+// init method, along with Register.
+func RegisterConfigKeys(kind string, required, optional []string) {
+	lock.Lock()
+	defer lock.Unlock()
+	configKeys[kind] = kindConfigKeys{
+		required: append([]string(nil), required...),
+		optional: append([]string(nil), optional...),
+	}
+}
+
+// ConfigKeys gets the configuration items that a kind of Location
+// supports, the required ones first, or nil if they were not
+// registered with RegisterConfigKeys.
+func ConfigKeys(kind string) []string {
+	lock.RLock()
+	defer lock.RUnlock()
+	keys, ok := configKeys[kind]
+	if !ok {
+		return nil
+	}
+	all := make([]string, 0, len(keys.required)+len(keys.optional))
+	all = append(all, keys.required...)
+	return append(all, keys.optional...)
+}
+
+// RequiredConfigKeys gets the configuration items that must be set
+// for a kind of Location, or nil if they were not registered with
+// RegisterConfigKeys.
+func RequiredConfigKeys(kind string) []string {
+	lock.RLock()
+	defer lock.RUnlock()
+	keys, ok := configKeys[kind]
+	if !ok {
+		return nil
+	}
+	return append([]string{}, keys.required...)
 }
 
 // KindByURL gets the kind represented by the given URL.
@@ -246,21 +468,84 @@ func (e errUnknownKind) Error() string {
 	return "stow: unknown kind"
 }
 
+// Error is an error of an operation of a backend, such as putting an
+// Item, with the error the backend got. Use errors.As to get it, and
+// errors.Is and errors.As to check the error the backend got.
+type Error struct {
+	// Op is the operation, such as "put", "open" or "remove".
+	Op string
+	// Kind is the kind of the Location.
+	Kind string
+	// Name is the name or ID of the Container or Item the
+	// operation was on, if any.
+	Name string
+	// Err is the error the backend got.
+	Err error
+}
+
+func (e *Error) Error() string {
+	s := "stow: " + e.Op
+	if e.Name != "" {
+		s += " " + strconv.Quote(e.Name)
+	}
+	return s + " (" + e.Kind + "): " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// errNotFound wraps the error a backend got for something
+// that could not be found.
+type errNotFound struct {
+	err error
+}
+
+func (e errNotFound) Error() string {
+	return ErrNotFound.Error() + ": " + e.err.Error()
+}
+
+// Is makes errors.Is(err, ErrNotFound) true.
+func (e errNotFound) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+func (e errNotFound) Unwrap() error {
+	return e.err
+}
+
+// NotFound gets an error for something that could not be found,
+// wrapping err, the error the backend got. The error matches
+// ErrNotFound with errors.Is, and err with errors.Is and errors.As.
+// If err is nil, ErrNotFound is returned.
+func NotFound(err error) error {
+	if err == nil {
+		return ErrNotFound
+	}
+	return errNotFound{err: err}
+}
+
 type errNotSupported string
 
 func (e errNotSupported) Error() string {
-	return "not supported: " + string(e)
+	return ErrNotSupported.Error() + ": " + string(e)
+}
+
+// Is makes errors.Is(err, ErrNotSupported) true.
+func (e errNotSupported) Is(target error) bool {
+	return target == ErrNotSupported
 }
 
 // IsNotSupported gets whether the error is due to
-// a feature not being supported by a specific implementation.
+// a feature not being supported by a specific implementation,
+// which is the same as errors.Is(err, ErrNotSupported).
 func IsNotSupported(err error) bool {
-	_, ok := err.(errNotSupported)
-	return ok
+	return errors.Is(err, ErrNotSupported)
 }
 
 // NotSupported gets an error describing the feature
-// as not supported by this implementation.
+// as not supported by this implementation. The error
+// matches ErrNotSupported with errors.Is.
 func NotSupported(feature string) error {
 	return errNotSupported(feature)
 }
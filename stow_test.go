@@ -2,7 +2,9 @@ package stow_test
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
+	"os"
 	"testing"
 
 	"github.com/cheekybits/is"
@@ -24,6 +26,15 @@ func TestKinds(t *testing.T) {
 	is.Equal(stow.Kinds(), []string{"test", "example"})
 }
 
+func TestConfigKeys(t *testing.T) {
+	is := is.New(t)
+	stow.RegisterConfigKeys("keyed", []string{"host"}, []string{"port", "tls"})
+	is.Equal(stow.ConfigKeys("keyed"), []string{"host", "port", "tls"})
+	is.Equal(stow.RequiredConfigKeys("keyed"), []string{"host"})
+	is.Nil(stow.ConfigKeys("unknown"))
+	is.Nil(stow.RequiredConfigKeys("unknown"))
+}
+
 func TestIsCursorEnd(t *testing.T) {
 	is := is.New(t)
 	is.True(stow.IsCursorEnd(""))
@@ -36,6 +47,21 @@ func TestErrNotSupported(t *testing.T) {
 	is.False(stow.IsNotSupported(err))
 	err = stow.NotSupported("feature")
 	is.True(stow.IsNotSupported(err))
+	is.True(errors.Is(err, stow.ErrNotSupported))
+	is.Equal(err.Error(), "not supported: feature")
+	is.True(stow.IsNotSupported(fmt.Errorf("wrapped: %w", stow.ErrNotSupported)))
+}
+
+func TestNotFound(t *testing.T) {
+	is := is.New(t)
+	is.Equal(stow.NotFound(nil), stow.ErrNotFound)
+	_, cause := os.Stat("does-not-exist")
+	err := stow.NotFound(cause)
+	is.True(errors.Is(err, stow.ErrNotFound))
+	is.True(errors.Is(err, os.ErrNotExist))
+	var perr *os.PathError
+	is.True(errors.As(err, &perr))
+	is.Equal(err.Error(), "not found: "+cause.Error())
 }
 
 func TestDuplicateKinds(t *testing.T) {
@@ -45,3 +71,43 @@ func TestDuplicateKinds(t *testing.T) {
 	stow.Register("example", nil, nil, nil)
 	is.Equal(stow.Kinds(), []string{"test", "example"})
 }
+
+// checkedConfig is a ConfigMap that validates itself.
+type checkedConfig struct {
+	stow.ConfigMap
+	err error
+}
+
+func (c checkedConfig) Validate() error {
+	return c.err
+}
+
+func TestDialValidates(t *testing.T) {
+	is := is.New(t)
+	_, err := stow.Dial(testKind, stow.ConfigMap{})
+	is.NoErr(err)
+	_, err = stow.Dial(testKind, stow.ConfigMap{"invalid": "missing key"})
+	is.Err(err)
+	is.Equal(err.Error(), "invalid test config: missing key")
+
+	errBad := errors.New("bad config")
+	cfg := checkedConfig{ConfigMap: stow.ConfigMap{}, err: errBad}
+	is.Equal(stow.Validate(testKind, cfg), errBad)
+	_, err = stow.Dial(testKind, cfg)
+	is.True(errors.Is(err, errBad))
+}
+
+func TestError(t *testing.T) {
+	is := is.New(t)
+	err := error(&stow.Error{Op: "put", Kind: "test", Name: "foo", Err: stow.NotFound(errDenied)})
+	is.Equal(err.Error(), `stow: put "foo" (test): not found: denied`)
+	is.True(errors.Is(err, stow.ErrNotFound))
+	is.True(errors.Is(err, errDenied))
+	var serr *stow.Error
+	is.True(errors.As(err, &serr))
+	is.Equal(serr.Op, "put")
+
+	err = &stow.Error{Op: "list containers", Kind: "test", Err: stow.NotSupported("listing")}
+	is.Equal(err.Error(), "stow: list containers (test): not supported: listing")
+	is.True(stow.IsNotSupported(err))
+}
@@ -71,6 +71,7 @@ func init() {
 		return u.Scheme == Kind
 	}
 	stow.Register(Kind, makefn, kindfn, validatefn)
+	stow.RegisterConfigKeys(Kind, []string{ConfigUsername, ConfigKey, ConfigTenantName, ConfigTenantAuthURL}, nil)
 }
 
 func newSwiftClient(cfg stow.Config) (*swift.Connection, error) {
@@ -2,7 +2,6 @@ package swift
 
 import (
 	"io"
-	"strings"
 
 	"github.com/pkg/errors"
 
@@ -59,6 +58,9 @@ func (c *container) Items(prefix, cursor string, count int) ([]stow.Item, string
 }
 
 func (c *container) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (stow.Item, error) {
+	if size < 0 {
+		return nil, stow.NotSupported("put with unknown size")
+	}
 	mdPrepped, err := prepMetadata(metadata)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to create or update Item, preparing metadata")
@@ -85,14 +87,18 @@ func (c *container) Put(name string, r io.Reader, size int64, metadata map[strin
 }
 
 func (c *container) RemoveItem(id string) error {
-	return c.client.ObjectDelete(c.id, id)
+	err := c.client.ObjectDelete(c.id, id)
+	if err == swift.ObjectNotFound {
+		return stow.NotFound(err)
+	}
+	return err
 }
 
 func (c *container) getItem(id string) (*item, error) {
 	info, headers, err := c.client.Object(c.id, id)
 	if err != nil {
-		if strings.Contains(err.Error(), "Object Not Found") {
-			return nil, stow.ErrNotFound
+		if err == swift.ObjectNotFound {
+			return nil, stow.NotFound(err)
 		}
 		return nil, errors.Wrap(err, "error retrieving item")
 	}
@@ -0,0 +1,34 @@
+package stow
+
+import "fmt"
+
+// Tagger represents an Item with tags, which are key/value pairs
+// kept apart from its metadata, such as for lifecycle rules.
+type Tagger interface {
+	// Tags gets the tags of the Item.
+	Tags() (map[string]string, error)
+	// SetTags replaces the tags of the Item.
+	SetTags(tags map[string]string) error
+}
+
+// ItemTags gets the tags of the Item.
+// The Item is used as a Tagger if possible, otherwise if it is
+// Taggable its tags are formatted as strings. Other Items fail
+// with an error for which IsNotSupported is true.
+func ItemTags(item Item) (map[string]string, error) {
+	switch t := item.(type) {
+	case Tagger:
+		return t.Tags()
+	case Taggable:
+		tags, err := t.Tags()
+		if err != nil {
+			return nil, err
+		}
+		strs := make(map[string]string, len(tags))
+		for k, v := range tags {
+			strs[k] = fmt.Sprint(v)
+		}
+		return strs, nil
+	}
+	return nil, NotSupported("tags")
+}
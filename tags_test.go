@@ -0,0 +1,32 @@
+package stow_test
+
+import (
+	"testing"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// taggableItem is a testItem with Taggable tags.
+type taggableItem struct {
+	*testItem
+	tags map[string]interface{}
+}
+
+func (i *taggableItem) Tags() (map[string]interface{}, error) {
+	return i.tags, nil
+}
+
+func TestItemTags(t *testing.T) {
+	is := is.New(t)
+	item := &taggableItem{
+		testItem: &testItem{name: "item"},
+		tags:     map[string]interface{}{"class": "archive", "days": 30},
+	}
+	tags, err := stow.ItemTags(item)
+	is.NoErr(err)
+	is.Equal(tags, map[string]string{"class": "archive", "days": "30"})
+
+	_, err = stow.ItemTags(&testItem{name: "item"})
+	is.True(stow.IsNotSupported(err))
+}
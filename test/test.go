@@ -14,6 +14,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -190,7 +191,7 @@ func All(t *testing.T, kind string, config stow.Config) {
 
 	// get container that doesn't exist
 	noContainer, err := location.Container(c1.ID() + "nope")
-	is.Equal(stow.ErrNotFound, err)
+	is.True(errors.Is(err, stow.ErrNotFound))
 	is.Nil(noContainer)
 
 	// get item by ID
@@ -208,7 +209,7 @@ func All(t *testing.T, kind string, config stow.Config) {
 
 	// get an item by ID that doesn't exist
 	noItem, err := c1copy.Item(item1.ID() + "nope")
-	is.Equal(stow.ErrNotFound, err)
+	is.True(errors.Is(err, stow.ErrNotFound))
 	is.Nil(noItem)
 
 	// get items by URL
@@ -273,6 +274,30 @@ func All(t *testing.T, kind string, config stow.Config) {
 	})
 	is.Equal(testErr, err)
 
+	// test walking in parallel
+	var walkedLock sync.Mutex
+	walkedItems = make([]stow.Item, 0)
+	err = stow.WalkParallel(c1, stow.NoPrefix, 1, 2, func(item stow.Item, err error) error {
+		if err != nil {
+			return err
+		}
+		walkedLock.Lock()
+		defer walkedLock.Unlock()
+		walkedItems = append(walkedItems, item)
+		return nil
+	})
+	is.NoErr(err)
+	is.Equal(len(walkedItems), 3)
+
+	// test walking in parallel stops at the first error
+	walked := 0
+	err = stow.WalkParallel(c1, stow.NoPrefix, 1, 1, func(item stow.Item, err error) error {
+		walked++
+		return testErr
+	})
+	is.Equal(testErr, err)
+	is.Equal(walked, 1)
+
 	// container walking
 	found := 0
 	err = stow.WalkContainers(location, stow.NoPrefix, 100, func(c stow.Container, err error) error {
@@ -0,0 +1,145 @@
+package stow
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// throttleChunkSize is the most bytes a throttled reader or writer
+// moves at once, so that transfers flow smoothly instead of stalling
+// for long between large reads or writes.
+const throttleChunkSize = 32 << 10
+
+// ThrottledReader wraps r so that reading from it is limited to
+// bytesPerSec bytes per second on average. Reads are split into
+// chunks of at most 32KB, or of bytesPerSec bytes if that is less,
+// which are each followed by a wait for the time they were due.
+// If bytesPerSec is not positive, reading is not limited.
+func ThrottledReader(r io.Reader, bytesPerSec int64) io.Reader {
+	if bytesPerSec <= 0 {
+		return r
+	}
+	return &throttledReader{r: r, limiter: newThrottleLimiter(bytesPerSec), ctx: context.Background()}
+}
+
+// ThrottledWriter wraps w so that writing to it is limited to
+// bytesPerSec bytes per second on average, in the same way as
+// ThrottledReader.
+// If bytesPerSec is not positive, writing is not limited.
+func ThrottledWriter(w io.Writer, bytesPerSec int64) io.Writer {
+	if bytesPerSec <= 0 {
+		return w
+	}
+	return &throttledWriter{w: w, limiter: newThrottleLimiter(bytesPerSec)}
+}
+
+// ThrottledOpen opens the Item for reading like Open, limiting the
+// reading of the contents to bytesPerSec bytes per second in the same
+// way as ThrottledReader. Closing the io.ReadCloser stops any read
+// waiting for its turn.
+// If bytesPerSec is not positive, reading is not limited.
+func ThrottledOpen(item Item, bytesPerSec int64) (io.ReadCloser, error) {
+	rc, err := item.Open()
+	if err != nil {
+		return nil, err
+	}
+	if bytesPerSec <= 0 {
+		return rc, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &throttledReadCloser{
+		throttledReader: throttledReader{r: rc, limiter: newThrottleLimiter(bytesPerSec), ctx: ctx},
+		c:               rc,
+		cancel:          cancel,
+	}, nil
+}
+
+// ThrottledPut puts an Item into the Container like Put, limiting the
+// reading of the contents from r to bytesPerSec bytes per second in the
+// same way as ThrottledReader.
+// If bytesPerSec is not positive, reading is not limited.
+func ThrottledPut(c Container, name string, r io.Reader, size int64, metadata map[string]interface{}, bytesPerSec int64) (Item, error) {
+	return c.Put(name, ThrottledReader(r, bytesPerSec), size, metadata)
+}
+
+// newThrottleLimiter makes a limiter of bytesPerSec tokens per second,
+// whose burst is the most bytes moved at once.
+func newThrottleLimiter(bytesPerSec int64) *rate.Limiter {
+	burst := int64(throttleChunkSize)
+	if bytesPerSec < burst {
+		burst = bytesPerSec
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSec), int(burst))
+}
+
+// throttledReader is an io.Reader limited by a token bucket
+// of one token per byte.
+type throttledReader struct {
+	r       io.Reader
+	limiter *rate.Limiter
+	ctx     context.Context // stops waiting when done
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > r.limiter.Burst() {
+		p = p[:r.limiter.Burst()]
+	}
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.limiter.WaitN(r.ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+// throttledReadCloser is a throttledReader that closes the
+// underlying reader, stopping any wait.
+type throttledReadCloser struct {
+	throttledReader
+	c         io.Closer
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func (r *throttledReadCloser) Close() error {
+	r.closeOnce.Do(func() {
+		r.cancel()
+		r.closeErr = r.c.Close()
+	})
+	return r.closeErr
+}
+
+// throttledWriter is an io.Writer limited by a token bucket
+// of one token per byte.
+type throttledWriter struct {
+	w       io.Writer
+	limiter *rate.Limiter
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > w.limiter.Burst() {
+			chunk = chunk[:w.limiter.Burst()]
+		}
+		if err := w.limiter.WaitN(context.Background(), len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,62 @@
+package stow_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestThrottled(t *testing.T) {
+	is := is.New(t)
+	// the first 32KB are the burst, the rest take half a second
+	contents := bytes.Repeat([]byte("x"), 32<<10+50000)
+
+	start := time.Now()
+	b, err := ioutil.ReadAll(stow.ThrottledReader(bytes.NewReader(contents), 100000))
+	is.NoErr(err)
+	is.True(bytes.Equal(b, contents))
+	is.True(time.Since(start) >= 400*time.Millisecond)
+
+	start = time.Now()
+	var buf bytes.Buffer
+	n, err := stow.ThrottledWriter(&buf, 100000).Write(contents)
+	is.NoErr(err)
+	is.Equal(n, len(contents))
+	is.True(bytes.Equal(buf.Bytes(), contents))
+	is.True(time.Since(start) >= 400*time.Millisecond)
+
+	c := newTestContainer("c")
+	start = time.Now()
+	item, err := stow.ThrottledPut(c, "a", bytes.NewReader(contents), int64(len(contents)), nil, 100000)
+	is.NoErr(err)
+	is.True(time.Since(start) >= 400*time.Millisecond)
+
+	// unlimited
+	rc, err := stow.ThrottledOpen(item, 0)
+	is.NoErr(err)
+	b, err = ioutil.ReadAll(rc)
+	is.NoErr(err)
+	is.True(bytes.Equal(b, contents))
+	is.NoErr(rc.Close())
+
+	// closing stops a waiting read
+	rc, err = stow.ThrottledOpen(item, 1)
+	is.NoErr(err)
+	p := make([]byte, 10)
+	n, err = rc.Read(p)
+	is.NoErr(err)
+	is.Equal(n, 1)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		rc.Close()
+	}()
+	start = time.Now()
+	_, err = rc.Read(p)
+	is.Err(err)
+	is.True(time.Since(start) < 500*time.Millisecond)
+	is.NoErr(rc.Close())
+}
@@ -0,0 +1,263 @@
+package stow
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"time"
+)
+
+// WithTimeout wraps a Location so that calls to it, and to the
+// Containers and Items got from it, fail with context.DeadlineExceeded
+// if they do not return within d, such as calls to a hung network
+// filesystem or backend.
+// Each call runs in its own goroutine, which is abandoned when the
+// call times out. As calls without a context cannot be cancelled,
+// abandoned calls may still complete in the background, for example
+// an abandoned Put may still create its Item, and may keep reading
+// its reader. The readers of Items opened by abandoned calls are
+// closed once they are opened.
+// Reading an opened Item is not limited.
+// The wrapped Containers and Items only implement the methods of the
+// Container and Item interfaces.
+func WithTimeout(loc Location, d time.Duration) Location {
+	return &timeoutLocation{
+		Location: loc,
+		timeout:  timeout(d),
+	}
+}
+
+// timeout is the time calls have to return.
+type timeout time.Duration
+
+// do calls fn in a new goroutine, and waits for it to return for up
+// to the timeout. If it does not, context.DeadlineExceeded is returned,
+// and the results of fn must not be used.
+func (t timeout) do(fn func()) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	timer := time.NewTimer(time.Duration(t))
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return context.DeadlineExceeded
+	}
+}
+
+type timeoutLocation struct {
+	Location
+	timeout timeout
+}
+
+func (l *timeoutLocation) Close() error {
+	var err error
+	if terr := l.timeout.do(func() { err = l.Location.Close() }); terr != nil {
+		return terr
+	}
+	return err
+}
+
+func (l *timeoutLocation) CreateContainer(name string) (Container, error) {
+	var (
+		c   Container
+		err error
+	)
+	if terr := l.timeout.do(func() { c, err = l.Location.CreateContainer(name) }); terr != nil {
+		return nil, terr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &timeoutContainer{Container: c, timeout: l.timeout}, nil
+}
+
+func (l *timeoutLocation) Containers(prefix string, cursor string, count int) ([]Container, string, error) {
+	var (
+		cs   []Container
+		next string
+		err  error
+	)
+	if terr := l.timeout.do(func() { cs, next, err = l.Location.Containers(prefix, cursor, count) }); terr != nil {
+		return nil, "", terr
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	for i, c := range cs {
+		cs[i] = &timeoutContainer{Container: c, timeout: l.timeout}
+	}
+	return cs, next, nil
+}
+
+func (l *timeoutLocation) Container(id string) (Container, error) {
+	var (
+		c   Container
+		err error
+	)
+	if terr := l.timeout.do(func() { c, err = l.Location.Container(id) }); terr != nil {
+		return nil, terr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &timeoutContainer{Container: c, timeout: l.timeout}, nil
+}
+
+func (l *timeoutLocation) RemoveContainer(id string) error {
+	var err error
+	if terr := l.timeout.do(func() { err = l.Location.RemoveContainer(id) }); terr != nil {
+		return terr
+	}
+	return err
+}
+
+func (l *timeoutLocation) ItemByURL(u *url.URL) (Item, error) {
+	var (
+		item Item
+		err  error
+	)
+	if terr := l.timeout.do(func() { item, err = l.Location.ItemByURL(u) }); terr != nil {
+		return nil, terr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &timeoutItem{Item: item, timeout: l.timeout}, nil
+}
+
+type timeoutContainer struct {
+	Container
+	timeout timeout
+}
+
+func (c *timeoutContainer) Item(id string) (Item, error) {
+	var (
+		item Item
+		err  error
+	)
+	if terr := c.timeout.do(func() { item, err = c.Container.Item(id) }); terr != nil {
+		return nil, terr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &timeoutItem{Item: item, timeout: c.timeout}, nil
+}
+
+func (c *timeoutContainer) Items(prefix, cursor string, count int) ([]Item, string, error) {
+	var (
+		items []Item
+		next  string
+		err   error
+	)
+	if terr := c.timeout.do(func() { items, next, err = c.Container.Items(prefix, cursor, count) }); terr != nil {
+		return nil, "", terr
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	for i, item := range items {
+		items[i] = &timeoutItem{Item: item, timeout: c.timeout}
+	}
+	return items, next, nil
+}
+
+func (c *timeoutContainer) RemoveItem(id string) error {
+	var err error
+	if terr := c.timeout.do(func() { err = c.Container.RemoveItem(id) }); terr != nil {
+		return terr
+	}
+	return err
+}
+
+func (c *timeoutContainer) Put(name string, r io.Reader, size int64, metadata map[string]interface{}) (Item, error) {
+	var (
+		item Item
+		err  error
+	)
+	if terr := c.timeout.do(func() { item, err = c.Container.Put(name, r, size, metadata) }); terr != nil {
+		return nil, terr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &timeoutItem{Item: item, timeout: c.timeout}, nil
+}
+
+type timeoutItem struct {
+	Item
+	timeout timeout
+}
+
+// Open opens the Item, closing the reader once it is opened if
+// the call timed out.
+func (i *timeoutItem) Open() (io.ReadCloser, error) {
+	type result struct {
+		rc  io.ReadCloser
+		err error
+	}
+	results := make(chan result, 1)
+	terr := i.timeout.do(func() {
+		rc, err := i.Item.Open()
+		results <- result{rc, err}
+	})
+	if terr != nil {
+		go func() {
+			if res := <-results; res.err == nil {
+				res.rc.Close()
+			}
+		}()
+		return nil, terr
+	}
+	res := <-results
+	return res.rc, res.err
+}
+
+func (i *timeoutItem) Size() (int64, error) {
+	var (
+		size int64
+		err  error
+	)
+	if terr := i.timeout.do(func() { size, err = i.Item.Size() }); terr != nil {
+		return 0, terr
+	}
+	return size, err
+}
+
+func (i *timeoutItem) ETag() (string, error) {
+	var (
+		etag string
+		err  error
+	)
+	if terr := i.timeout.do(func() { etag, err = i.Item.ETag() }); terr != nil {
+		return "", terr
+	}
+	return etag, err
+}
+
+func (i *timeoutItem) LastMod() (time.Time, error) {
+	var (
+		lastMod time.Time
+		err     error
+	)
+	if terr := i.timeout.do(func() { lastMod, err = i.Item.LastMod() }); terr != nil {
+		return time.Time{}, terr
+	}
+	return lastMod, err
+}
+
+func (i *timeoutItem) Metadata() (map[string]interface{}, error) {
+	var (
+		md  map[string]interface{}
+		err error
+	)
+	if terr := i.timeout.do(func() { md, err = i.Item.Metadata() }); terr != nil {
+		return nil, terr
+	}
+	return md, err
+}
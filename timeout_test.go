@@ -0,0 +1,64 @@
+package stow_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+// hangingContainer is a testContainer whose Items hang when
+// they are opened, until unblock is closed.
+type hangingContainer struct {
+	*testContainer
+	unblock chan struct{}
+}
+
+func (c *hangingContainer) Item(id string) (stow.Item, error) {
+	item, err := c.testContainer.Item(id)
+	if err != nil {
+		return nil, err
+	}
+	return &hangingItem{Item: item, unblock: c.unblock}, nil
+}
+
+type hangingItem struct {
+	stow.Item
+	unblock chan struct{}
+}
+
+func (i *hangingItem) Open() (io.ReadCloser, error) {
+	<-i.unblock
+	return i.Item.Open()
+}
+
+func TestWithTimeout(t *testing.T) {
+	is := is.New(t)
+	c := &hangingContainer{testContainer: newTestContainer("c"), unblock: make(chan struct{})}
+	defer close(c.unblock)
+	loc := stow.WithTimeout(&singleLocation{container: c}, 50*time.Millisecond)
+
+	// calls that return in time are passed through
+	cc, err := loc.Container("c")
+	is.NoErr(err)
+	_, err = cc.Put("item", strings.NewReader("item"), 4, nil)
+	is.NoErr(err)
+	item, err := cc.Item("item")
+	is.NoErr(err)
+	size, err := item.Size()
+	is.NoErr(err)
+	is.Equal(size, int64(4))
+	_, err = cc.Item("missing")
+	is.True(errors.Is(err, stow.ErrNotFound))
+
+	// hung calls time out
+	start := time.Now()
+	_, err = item.Open()
+	is.Equal(err, context.DeadlineExceeded)
+	is.True(time.Since(start) < time.Second)
+}
@@ -0,0 +1,123 @@
+package stow
+
+import (
+	"io"
+	"sync"
+)
+
+// UploadResult is the result of an upload submitted to an Uploader.
+type UploadResult struct {
+	// Name is the name the upload was submitted with.
+	Name string
+	// Item is the Item that was put, if Err is nil.
+	Item Item
+	// Err is the error putting the Item, if any.
+	Err error
+}
+
+// Uploader puts Items into a Container in the background, with a
+// pool of workers taking uploads from a queue.
+// The results of the uploads are sent on the Results channel, and are
+// kept until they are received, so they may be received while
+// uploading or only once Close has returned.
+type Uploader struct {
+	container Container
+	uploads   chan upload
+	finished  chan UploadResult // the results of the workers
+	results   chan UploadResult
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// upload is an upload waiting in the queue of an Uploader.
+type upload struct {
+	name     string
+	r        io.Reader
+	size     int64
+	metadata map[string]interface{}
+}
+
+// NewUploader makes an Uploader that puts Items into the Container with
+// the specified number of workers, queueing up to queueDepth uploads
+// that no worker has taken yet.
+func NewUploader(c Container, workers, queueDepth int) *Uploader {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueDepth < 0 {
+		queueDepth = 0
+	}
+	u := &Uploader{
+		container: c,
+		uploads:   make(chan upload, queueDepth),
+		finished:  make(chan UploadResult),
+		results:   make(chan UploadResult),
+	}
+	for i := 0; i < workers; i++ {
+		u.wg.Add(1)
+		go u.work()
+	}
+	go u.forward()
+	return u
+}
+
+func (u *Uploader) work() {
+	defer u.wg.Done()
+	for up := range u.uploads {
+		item, err := u.container.Put(up.name, up.r, up.size, up.metadata)
+		u.finished <- UploadResult{Name: up.name, Item: item, Err: err}
+	}
+}
+
+// forward sends the results of the workers on the Results channel,
+// keeping those that are not received yet, so that the workers never
+// wait for them to be received. It closes the Results channel once
+// the workers are done and all of the results are received.
+func (u *Uploader) forward() {
+	finished := u.finished
+	var pending []UploadResult
+	for finished != nil || len(pending) > 0 {
+		var results chan UploadResult
+		var next UploadResult
+		if len(pending) > 0 {
+			results, next = u.results, pending[0]
+		}
+		select {
+		case result, ok := <-finished:
+			if !ok {
+				finished = nil
+				continue
+			}
+			pending = append(pending, result)
+		case results <- next:
+			pending[0] = UploadResult{}
+			pending = pending[1:]
+		}
+	}
+	close(u.results)
+}
+
+// Submit queues an upload of an Item with the specified name, and
+// contents read from r, blocking while the queue is full.
+// The reader must stay readable until the result of the upload has
+// been received. Submit must not be called once Close was called.
+func (u *Uploader) Submit(name string, r io.Reader, size int64, metadata map[string]interface{}) {
+	u.uploads <- upload{name: name, r: r, size: size, metadata: metadata}
+}
+
+// Results gets the channel the results of the uploads are sent on, in
+// the order they finish. It is closed once Close has been called and
+// all of the results have been received.
+func (u *Uploader) Results() <-chan UploadResult {
+	return u.results
+}
+
+// Close stops the Uploader taking new uploads, and waits for the
+// queued ones to finish.
+func (u *Uploader) Close() {
+	u.closeOnce.Do(func() {
+		close(u.uploads)
+		u.wg.Wait()
+		close(u.finished)
+	})
+}
@@ -0,0 +1,103 @@
+package stow_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestUploader(t *testing.T) {
+	is := is.New(t)
+	c := &rejectingContainer{testContainer: newTestContainer("c"), rejected: "item07"}
+	u := stow.NewUploader(c, 4, 2)
+
+	results := make(map[string]stow.UploadResult)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range u.Results() {
+			results[result.Name] = result
+		}
+	}()
+	for n := 0; n < 20; n++ {
+		name := fmt.Sprintf("item%02d", n)
+		u.Submit(name, strings.NewReader(name), int64(len(name)), nil)
+	}
+	u.Close()
+	<-done
+
+	is.Equal(len(results), 20)
+	for name, result := range results {
+		if name == "item07" {
+			is.Equal(result.Err, errDenied)
+			continue
+		}
+		is.NoErr(result.Err)
+		is.Equal(result.Item.Name(), name)
+	}
+	_, err := c.Item("item19")
+	is.NoErr(err)
+}
+
+func TestUploaderDrainedWhileClosing(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	// no queue and a single worker, so each upload is only taken once
+	// the previous one finished
+	u := stow.NewUploader(c, 1, 0)
+	var names []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range u.Results() {
+			is.NoErr(result.Err)
+			names = append(names, result.Name)
+		}
+	}()
+	for n := 0; n < 50; n++ {
+		name := fmt.Sprintf("item%02d", n)
+		u.Submit(name, strings.NewReader(name), int64(len(name)), nil)
+	}
+	closed := make(chan struct{})
+	go func() {
+		u.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked while the results were received")
+	}
+	<-done
+	is.Equal(len(names), 50)
+}
+
+func TestUploaderResultsAfterClose(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	u := stow.NewUploader(c, 2, 1)
+	submitted := make(chan struct{})
+	go func() {
+		defer close(submitted)
+		for n := 0; n < 50; n++ {
+			name := fmt.Sprintf("item%02d", n)
+			u.Submit(name, strings.NewReader(name), int64(len(name)), nil)
+		}
+		u.Close()
+	}()
+	select {
+	case <-submitted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Submit and Close blocked on the results not received")
+	}
+	names := make(map[string]bool)
+	for result := range u.Results() {
+		is.NoErr(result.Err)
+		names[result.Name] = true
+	}
+	is.Equal(len(names), 50)
+}
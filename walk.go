@@ -1,5 +1,10 @@
 package stow
 
+import (
+	"sync"
+	"time"
+)
+
 // DEV NOTE: tests for this are in test/test.go
 
 // WalkFunc is a function called for each Item visited
@@ -7,12 +12,13 @@ package stow
 // If there was a problem, the incoming error will describe
 // the problem and the function can decide how to handle
 // that error.
-// If an error is returned, processing stops.
+// If an error is returned, processing stops. Returning
+// ErrStopWalk stops without an error.
 type WalkFunc func(item Item, err error) error
 
 // Walk walks all Items in the Container.
 // Returns the first error returned by the WalkFunc or
-// nil if no errors were returned.
+// nil if no errors, or only ErrStopWalk, were returned.
 // The pageSize is the number of Items to get per request.
 func Walk(container Container, prefix string, pageSize int, fn WalkFunc) error {
 	var (
@@ -25,13 +31,13 @@ func Walk(container Container, prefix string, pageSize int, fn WalkFunc) error {
 		if err != nil {
 			err = fn(nil, err)
 			if err != nil {
-				return err
+				return stopWalkErr(err)
 			}
 		}
 		for _, item := range items {
 			err = fn(item, nil)
 			if err != nil {
-				return err
+				return stopWalkErr(err)
 			}
 		}
 		if IsCursorEnd(cursor) {
@@ -41,6 +47,244 @@ func Walk(container Container, prefix string, pageSize int, fn WalkFunc) error {
 	return nil
 }
 
+// WalkParallel walks all Items in the Container like Walk, calling
+// the WalkFunc from the specified number of goroutines at once.
+// The WalkFunc must therefore be safe for concurrent use, and
+// Items are not visited in any particular order.
+// Pages of Items are still listed one after another.
+// Once any call to the WalkFunc returns an error no more calls are
+// made, and the first error returned by the WalkFunc, or nil if it
+// was ErrStopWalk, is returned when all calls in progress have
+// finished.
+func WalkParallel(container Container, prefix string, pageSize int, workers int, fn WalkFunc) error {
+	if workers < 1 {
+		workers = 1
+	}
+	type walkJob struct {
+		item Item
+		err  error
+	}
+	var (
+		jobs     = make(chan walkJob)
+		stop     = make(chan struct{})
+		stopOnce sync.Once
+		// calls are made holding a read lock, and stopped is set
+		// holding the write lock, so that no call starts once it is
+		stopLock sync.RWMutex
+		stopped  bool
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	call := func(job walkJob) error {
+		stopLock.RLock()
+		defer stopLock.RUnlock()
+		if stopped {
+			return nil
+		}
+		return fn(job.item, job.err)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if err := call(job); err != nil {
+					stopOnce.Do(func() {
+						stopLock.Lock()
+						stopped = true
+						firstErr = err
+						stopLock.Unlock()
+						close(stop)
+					})
+				}
+			}
+		}()
+	}
+	var (
+		err    error
+		items  []Item
+		cursor = CursorStart
+	)
+list:
+	for {
+		select {
+		case <-stop:
+			break list
+		default:
+		}
+		items, cursor, err = container.Items(prefix, cursor, pageSize)
+		if err != nil {
+			select {
+			case jobs <- walkJob{err: err}:
+			case <-stop:
+				break list
+			}
+		}
+		for _, item := range items {
+			select {
+			case jobs <- walkJob{item: item}:
+			case <-stop:
+				break list
+			}
+		}
+		if IsCursorEnd(cursor) {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return stopWalkErr(firstErr)
+}
+
+// stopWalkErr gets the error a walk returns when its WalkFunc
+// returned err.
+func stopWalkErr(err error) error {
+	if err == ErrStopWalk {
+		return nil
+	}
+	return err
+}
+
+// FilterWalk makes a WalkFunc that only calls fn for the Items that
+// the predicate is true for. Errors are always passed on to fn, and
+// errors returned by the predicate stop the walk.
+func FilterWalk(fn WalkFunc, pred func(item Item) (bool, error)) WalkFunc {
+	return func(item Item, err error) error {
+		if err != nil {
+			return fn(nil, err)
+		}
+		ok, err := pred(item)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		return fn(item, nil)
+	}
+}
+
+// LimitWalk makes a WalkFunc that stops the walk with ErrStopWalk
+// once fn has been called for n Items. To limit the number of
+// matching Items, wrap the LimitWalk with FilterWalk rather than
+// the other way round.
+// The WalkFunc is not safe for concurrent use.
+func LimitWalk(fn WalkFunc, n int) WalkFunc {
+	var count int
+	return func(item Item, err error) error {
+		if err != nil {
+			return fn(nil, err)
+		}
+		if count >= n {
+			return ErrStopWalk
+		}
+		count++
+		if err := fn(item, nil); err != nil {
+			return err
+		}
+		if count >= n {
+			return ErrStopWalk
+		}
+		return nil
+	}
+}
+
+// CollectWalk makes a WalkFunc that appends the Items it is called
+// for to the returned slice, and stops the walk on the first error.
+// The WalkFunc is not safe for concurrent use.
+func CollectWalk() (WalkFunc, *[]Item) {
+	items := new([]Item)
+	return func(item Item, err error) error {
+		if err != nil {
+			return err
+		}
+		*items = append(*items, item)
+		return nil
+	}, items
+}
+
+// ListingMetadataer represents an Item whose metadata may have been
+// got with the listing that returned it.
+type ListingMetadataer interface {
+	// ListingMetadata gets the metadata of the Item, and whether it was
+	// got with the listing, without another request. If it was not,
+	// the metadata is nil.
+	ListingMetadata() (map[string]interface{}, bool)
+}
+
+// WalkWithMetadata walks all Items in the Container like Walk, calling
+// fn with each Item and its metadata.
+// The metadata got with the listing is used for Items that are
+// ListingMetadataers, otherwise it is got with Metadata for each Item.
+// Errors listing the Items or getting their metadata stop the walk,
+// and are returned, as is the first error returned by fn, unless it
+// is ErrStopWalk.
+func WalkWithMetadata(container Container, prefix string, pageSize int, fn func(Item, map[string]interface{}) error) error {
+	return Walk(container, prefix, pageSize, func(item Item, err error) error {
+		if err != nil {
+			return err
+		}
+		if lm, ok := item.(ListingMetadataer); ok {
+			if md, ok := lm.ListingMetadata(); ok {
+				return fn(item, md)
+			}
+		}
+		md, err := item.Metadata()
+		if err != nil {
+			return err
+		}
+		return fn(item, md)
+	})
+}
+
+// SinceLister represents a Container that can list only the Items
+// modified after a time, without enumerating the others.
+type SinceLister interface {
+	// ItemsSince gets a page of the Items whose names start with the
+	// prefix and whose LastMod is after since, like Items.
+	ItemsSince(prefix string, since time.Time, cursor string, count int) ([]Item, string, error)
+}
+
+// walkSincePageSize is the number of Items WalkSince gets
+// per request.
+const walkSincePageSize = 1000
+
+// WalkSince walks the Items in the Container like Walk, only calling
+// fn for the Items whose LastMod is after since, such as those changed
+// since the last run of an incremental backup.
+// Containers that are SinceListers are listed with ItemsSince,
+// otherwise all of the Items are walked and filtered by LastMod.
+func WalkSince(container Container, prefix string, since time.Time, fn WalkFunc) error {
+	sl, ok := container.(SinceLister)
+	if !ok {
+		return Walk(container, prefix, walkSincePageSize, FilterWalk(fn, func(item Item) (bool, error) {
+			lastMod, err := item.LastMod()
+			if err != nil {
+				return false, err
+			}
+			return lastMod.After(since), nil
+		}))
+	}
+	cursor := CursorStart
+	for {
+		items, next, err := sl.ItemsSince(prefix, since, cursor, walkSincePageSize)
+		if err != nil {
+			if err := fn(nil, err); err != nil {
+				return stopWalkErr(err)
+			}
+		}
+		for _, item := range items {
+			if err := fn(item, nil); err != nil {
+				return stopWalkErr(err)
+			}
+		}
+		if IsCursorEnd(next) {
+			return nil
+		}
+		cursor = next
+	}
+}
+
 // WalkContainersFunc is a function called for each Container visited
 // by WalkContainers.
 // If there was a problem, the incoming error will describe
@@ -79,3 +323,32 @@ func WalkContainers(location Location, prefix string, pageSize int, fn WalkConta
 	}
 	return nil
 }
+
+// findContainersPageSize is the number of Containers FindContainers
+// lists per request.
+const findContainersPageSize = 100
+
+// FindContainers gets all of the Containers in the Location for which
+// match returns true, listing them page by page.
+// If match returns an error, or listing the Containers fails, no more
+// Containers are matched and the error is returned.
+func FindContainers(location Location, match func(Container) (bool, error)) ([]Container, error) {
+	var found []Container
+	err := WalkContainers(location, NoPrefix, findContainersPageSize, func(container Container, err error) error {
+		if err != nil {
+			return err
+		}
+		ok, err := match(container)
+		if err != nil {
+			return err
+		}
+		if ok {
+			found = append(found, container)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
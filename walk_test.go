@@ -0,0 +1,233 @@
+package stow_test
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+	"github.com/graymeta/stow"
+)
+
+func TestWalkFuncs(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	for _, name := range []string{"a.log", "b.txt", "c.log", "d.log", "e.txt"} {
+		_, err := c.Put(name, strings.NewReader(name), int64(len(name)), nil)
+		is.NoErr(err)
+	}
+	bySuffix := func(suffix string) func(stow.Item) (bool, error) {
+		return func(item stow.Item) (bool, error) {
+			return strings.HasSuffix(item.Name(), suffix), nil
+		}
+	}
+	names := func(items []stow.Item) []string {
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name())
+		}
+		return names
+	}
+
+	collect, items := stow.CollectWalk()
+	is.NoErr(stow.Walk(c, stow.NoPrefix, 2, stow.FilterWalk(collect, bySuffix(".log"))))
+	is.Equal(names(*items), []string{"a.log", "c.log", "d.log"})
+
+	// stopping early is not an error
+	collect, items = stow.CollectWalk()
+	is.NoErr(stow.Walk(c, stow.NoPrefix, 2, stow.FilterWalk(stow.LimitWalk(collect, 2), bySuffix(".log"))))
+	is.Equal(names(*items), []string{"a.log", "c.log"})
+
+	collect, items = stow.CollectWalk()
+	is.NoErr(stow.Walk(c, stow.NoPrefix, 2, stow.LimitWalk(stow.FilterWalk(collect, bySuffix(".log")), 2)))
+	is.Equal(names(*items), []string{"a.log"})
+
+	collect, items = stow.CollectWalk()
+	is.NoErr(stow.WalkParallel(c, stow.NoPrefix, 2, 1, stow.LimitWalk(collect, 3)))
+	is.Equal(len(*items), 3)
+
+	// predicate errors stop the walk
+	collect, _ = stow.CollectWalk()
+	err := stow.Walk(c, stow.NoPrefix, 2, stow.FilterWalk(collect, func(stow.Item) (bool, error) {
+		return false, errDenied
+	}))
+	is.Equal(err, errDenied)
+}
+
+// pagedLocation is a testLocation that lists its containers
+// in pages, with the name of the next container as the cursor.
+type pagedLocation struct {
+	testLocation
+	containers []stow.Container
+}
+
+func (l *pagedLocation) Containers(prefix string, cursor string, count int) ([]stow.Container, string, error) {
+	var page []stow.Container
+	for _, c := range l.containers {
+		if !strings.HasPrefix(c.Name(), prefix) || c.Name() < cursor {
+			continue
+		}
+		if len(page) == count {
+			return page, c.Name(), nil
+		}
+		page = append(page, c)
+	}
+	return page, "", nil
+}
+
+func TestFindContainers(t *testing.T) {
+	is := is.New(t)
+	loc := &pagedLocation{}
+	for n := 0; n < 250; n++ {
+		loc.containers = append(loc.containers, newTestContainer(fmt.Sprintf("c%03d", n)))
+	}
+
+	found, err := stow.FindContainers(loc, func(c stow.Container) (bool, error) {
+		return strings.HasSuffix(c.Name(), "0"), nil
+	})
+	is.NoErr(err)
+	is.Equal(len(found), 25)
+	for n, c := range found {
+		is.Equal(c.Name(), fmt.Sprintf("c%03d", n*10))
+	}
+
+	var calls int
+	_, err = stow.FindContainers(loc, func(c stow.Container) (bool, error) {
+		calls++
+		if c.Name() == "c150" {
+			return false, errDenied
+		}
+		return true, nil
+	})
+	is.Equal(err, errDenied)
+	is.Equal(calls, 151)
+}
+
+// listedContainer is a testContainer whose Items are listed
+// with the metadata of every other Item.
+type listedContainer struct {
+	*testContainer
+}
+
+func (c *listedContainer) Items(prefix, cursor string, count int) ([]stow.Item, string, error) {
+	items, cursor, err := c.testContainer.Items(prefix, cursor, count)
+	for i, item := range items {
+		items[i] = &listedItem{Item: item, listed: i%2 == 0}
+	}
+	return items, cursor, err
+}
+
+type listedItem struct {
+	stow.Item
+	listed bool
+}
+
+func (i *listedItem) ListingMetadata() (map[string]interface{}, bool) {
+	if !i.listed {
+		return nil, false
+	}
+	return map[string]interface{}{"listed": true}, true
+}
+
+func TestWalkWithMetadata(t *testing.T) {
+	is := is.New(t)
+	c := &listedContainer{testContainer: newTestContainer("c")}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		_, err := c.Put(name, strings.NewReader(name), 1, map[string]interface{}{"name": name})
+		is.NoErr(err)
+	}
+
+	mds := make(map[string]map[string]interface{})
+	err := stow.WalkWithMetadata(c, "", 3, func(item stow.Item, md map[string]interface{}) error {
+		mds[item.Name()] = md
+		return nil
+	})
+	is.NoErr(err)
+	is.Equal(mds, map[string]map[string]interface{}{
+		"a": {"listed": true},
+		"b": {"name": "b"},
+		"c": {"listed": true},
+		"d": {"listed": true},
+	})
+
+	err = stow.WalkWithMetadata(c, "", 3, func(item stow.Item, md map[string]interface{}) error {
+		return stow.ErrStopWalk
+	})
+	is.NoErr(err)
+}
+
+// sinceContainer is a SinceLister that only lists the Items
+// changed since a time.
+type sinceContainer struct {
+	*testContainer
+	calls int
+}
+
+func (c *sinceContainer) ItemsSince(prefix string, since time.Time, cursor string, count int) ([]stow.Item, string, error) {
+	c.calls++
+	items, cursor, err := c.testContainer.Items(prefix, cursor, count)
+	var recent []stow.Item
+	for _, item := range items {
+		if lastMod, _ := item.LastMod(); lastMod.After(since) {
+			recent = append(recent, item)
+		}
+	}
+	return recent, cursor, err
+}
+
+func TestWalkSince(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	for _, name := range []string{"a", "b", "c", "d"} {
+		_, err := c.Put(name, strings.NewReader(name), 1, nil)
+		is.NoErr(err)
+	}
+	since := time.Now().Add(-time.Hour)
+	c.items["b"].lastMod = since.Add(-time.Minute)
+	c.items["d"].lastMod = since
+
+	for _, container := range []stow.Container{c, &sinceContainer{testContainer: c}} {
+		var names []string
+		err := stow.WalkSince(container, stow.NoPrefix, since, func(item stow.Item, err error) error {
+			if err != nil {
+				return err
+			}
+			names = append(names, item.Name())
+			return nil
+		})
+		is.NoErr(err)
+		is.Equal(names, []string{"a", "c"})
+	}
+	sc := &sinceContainer{testContainer: c}
+	err := stow.WalkSince(sc, stow.NoPrefix, since, func(stow.Item, error) error {
+		return stow.ErrStopWalk
+	})
+	is.NoErr(err)
+	is.Equal(sc.calls, 1)
+}
+
+func TestWalkParallelStops(t *testing.T) {
+	is := is.New(t)
+	c := newTestContainer("c")
+	for i := 0; i < 1000; i++ {
+		name := fmt.Sprintf("item%04d", i)
+		_, err := c.Put(name, strings.NewReader(name), int64(len(name)), nil)
+		is.NoErr(err)
+	}
+	var calls int32
+	failed := fmt.Errorf("failed")
+	err := stow.WalkParallel(c, stow.NoPrefix, 10, 8, func(item stow.Item, err error) error {
+		if atomic.AddInt32(&calls, 1) == 20 {
+			return failed
+		}
+		return nil
+	})
+	is.Equal(err, failed)
+	n := atomic.LoadInt32(&calls)
+	is.True(n < 1000)
+	// no calls are made once the walk returned
+	time.Sleep(10 * time.Millisecond)
+	is.Equal(atomic.LoadInt32(&calls), n)
+}
@@ -0,0 +1,42 @@
+package stow
+
+// EventOp is the kind of change an Event describes.
+type EventOp int
+
+const (
+	// EventCreate means that an Item was created.
+	EventCreate EventOp = iota + 1
+	// EventModify means that the contents of an Item changed.
+	EventModify
+	// EventDelete means that an Item was removed.
+	EventDelete
+)
+
+func (op EventOp) String() string {
+	switch op {
+	case EventCreate:
+		return "create"
+	case EventModify:
+		return "modify"
+	case EventDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// Event describes a change to an Item in a Container.
+type Event struct {
+	// ID is the ID of the Item that changed.
+	ID string
+	// Op is the kind of change.
+	Op EventOp
+}
+
+// Watcher represents a Container that can report changes to
+// its Items as they happen.
+type Watcher interface {
+	// Watch starts watching the Container, sending an Event on the
+	// returned channel for every change to its Items. The returned
+	// cancel function stops watching and closes the channel.
+	Watch() (events <-chan Event, cancel func(), err error)
+}